@@ -0,0 +1,95 @@
+// Package admin serves operational endpoints (pprof, expvar, log level,
+// recent logs, debug capture, build info, version) on a port separate from
+// the public API.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"time"
+
+	"github.com/upendravikram5/upendra/buildinfo"
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Config holds the admin server configuration
+type Config struct {
+	Addr string // Listen address (default "localhost:6060")
+}
+
+// Server is the admin HTTP server; it implements lifecycle.Component
+type Server struct {
+	mux    *http.ServeMux
+	server *http.Server
+}
+
+// NewServer creates the admin server with the standard endpoints mounted
+func NewServer(cfg Config) *Server {
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:6060"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/loglevel", logger.LevelHandler())
+	mux.Handle("/debug/logs", logger.FlightRecorderHandler())
+	mux.Handle("/debug/capture", logger.CaptureHandler())
+	mux.HandleFunc("/buildinfo", buildInfoHandler)
+	mux.Handle("/version", buildinfo.Handler())
+
+	return &Server{
+		mux: mux,
+		server: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// Handle mounts an additional admin endpoint; call before Start
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// Start listens on the configured address and serves in the background
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		log.Printf("Starting admin server on %s", ln.Addr())
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Admin server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the server down gracefully
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func buildInfoHandler(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		http.Error(w, "build info not available", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,82 @@
+// Package buildinfo reports the version of the running binary. Release builds
+// stamp it with ldflags:
+//
+//	go build -ldflags "-X github.com/upendravikram5/upendra/buildinfo.Version=v1.2.3 \
+//	  -X github.com/upendravikram5/upendra/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/upendravikram5/upendra/buildinfo.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Unstamped builds fall back to the module version and VCS data recorded by
+// the Go toolchain.
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set via -ldflags -X
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build info, filling unstamped values from the Go build info
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, BuildDate: BuildDate, GoVersion: runtime.Version()}
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Version == "" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	})
+	return info
+}
+
+// ShortCommit returns the first 12 characters of the commit
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
+
+// Fields returns the info as logger key-value pairs
+func (i Info) Fields() []interface{} {
+	return []interface{}{"version", i.Version, "commit", i.ShortCommit(), "build_date", i.BuildDate, "go_version", i.GoVersion}
+}
+
+// Handler serves the build info as JSON, e.g. on /version
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Get())
+	})
+}
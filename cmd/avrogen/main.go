@@ -0,0 +1,205 @@
+// Command avrogen generates Go types for Avro event contracts stored in
+// Schema Registry, plus glue that turns them into kafka.Message values
+// tagged with their schema ID and back. Run it from go:generate so the
+// types follow the registry:
+//
+//	//go:generate go run github.com/upendravikram5/upendra/cmd/avrogen -subject orders-value,payments-value -package events -out events_gen.go
+//
+// The registry URL and credentials come from -registry or
+// SCHEMA_REGISTRY_URL, SCHEMA_REGISTRY_USER and SCHEMA_REGISTRY_PASSWORD.
+// Two files are written: the structs to -out and the codec glue next to it
+// with a _codec suffix.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ettle/strcase"
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/gen"
+)
+
+func main() {
+	registry := flag.String("registry", os.Getenv("SCHEMA_REGISTRY_URL"), "Schema Registry URL")
+	subjects := flag.String("subject", "", "Comma-separated subjects to generate")
+	version := flag.String("version", "latest", "Subject version to generate from")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "Package of the generated files")
+	out := flag.String("out", "", "Output file for the structs")
+	flag.Parse()
+
+	if *registry == "" || *subjects == "" || *pkg == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	client := &registryClient{
+		url:      strings.TrimSuffix(*registry, "/"),
+		user:     os.Getenv("SCHEMA_REGISTRY_USER"),
+		password: os.Getenv("SCHEMA_REGISTRY_PASSWORD"),
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+	if err := run(client, strings.Split(*subjects, ","), *version, *pkg, *out); err != nil {
+		log.Fatalf("avrogen: %v", err)
+	}
+}
+
+// contract is one generated event type
+type contract struct {
+	Subject  string
+	Version  int
+	SchemaID int
+	Type     string
+}
+
+func run(client *registryClient, subjects []string, version, pkg, out string) error {
+	g := gen.NewGenerator(pkg, map[string]gen.TagStyle{"json": gen.Snake}, gen.WithEncoders(true))
+	var contracts []contract
+	for _, subject := range subjects {
+		subject = strings.TrimSpace(subject)
+		s, err := client.schema(subject, version)
+		if err != nil {
+			return err
+		}
+		schema, err := avro.Parse(s.Schema)
+		if err != nil {
+			return fmt.Errorf("subject %s: %w", subject, err)
+		}
+		rec, ok := schema.(*avro.RecordSchema)
+		if !ok {
+			return fmt.Errorf("subject %s: only record schemas can be generated, got %s", subject, schema.Type())
+		}
+		g.Parse(rec)
+		contracts = append(contracts, contract{
+			Subject:  subject,
+			Version:  s.Version,
+			SchemaID: s.ID,
+			Type:     strcase.ToGoPascal(rec.Name()),
+		})
+	}
+
+	var structs bytes.Buffer
+	if err := g.Write(&structs); err != nil {
+		return err
+	}
+	if err := writeSource(out, structs.Bytes()); err != nil {
+		return err
+	}
+
+	var glue bytes.Buffer
+	if err := codecTemplate.Execute(&glue, map[string]interface{}{"Package": pkg, "Contracts": contracts}); err != nil {
+		return err
+	}
+	return writeSource(strings.TrimSuffix(out, ".go")+"_codec.go", glue.Bytes())
+}
+
+func writeSource(path string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("generated code for %s does not compile: %w", path, err)
+	}
+	return os.WriteFile(path, formatted, 0o644)
+}
+
+// registryClient reads schemas over the Schema Registry REST API
+type registryClient struct {
+	url            string
+	user, password string
+	http           *http.Client
+}
+
+type registrySchema struct {
+	ID         int    `json:"id"`
+	Version    int    `json:"version"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+	References []struct {
+		Name string `json:"name"`
+	} `json:"references"`
+}
+
+func (c *registryClient) schema(subject, version string) (*registrySchema, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/subjects/%s/versions/%s", c.url, url.PathEscape(subject), version), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("subject %s: %w", subject, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("subject %s: registry returned %s: %s", subject, resp.Status, bytes.TrimSpace(body))
+	}
+
+	var s registrySchema
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("subject %s: %w", subject, err)
+	}
+	if s.SchemaType != "" && s.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("subject %s: schema type %s is not Avro", subject, s.SchemaType)
+	}
+	if len(s.References) > 0 {
+		return nil, fmt.Errorf("subject %s: schema references are not supported", subject)
+	}
+	return &s, nil
+}
+
+var codecTemplate = template.Must(template.New("codec").Parse(`// Code generated by avrogen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+
+	"github.com/upendravikram5/upendra/kafka"
+)
+{{range .Contracts}}
+// Registry coordinates of the schema {{.Type}} was generated from
+const (
+	{{.Type}}Subject  = "{{.Subject}}"
+	{{.Type}}Version  = {{.Version}}
+	{{.Type}}SchemaID = {{.SchemaID}}
+)
+
+// Message encodes o as a record for topic, tagged with its schema ID
+func (o *{{.Type}}) Message(topic string, key []byte) (*kafka.Message, error) {
+	value, err := o.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode {{.Type}}: %w", err)
+	}
+	msg := &kafka.Message{Topic: topic, Key: key, Value: value}
+	msg.SetContentType("avro/binary")
+	msg.SetSchemaID({{.Type}}SchemaID)
+	return msg, nil
+}
+
+// Decode{{.Type}} decodes a record written with the generated schema.
+// Records tagged with another schema ID are rejected, since decoding
+// them needs the writer's schema; regenerate when the subject changes.
+func Decode{{.Type}}(msg *kafka.Message) (*{{.Type}}, error) {
+	if id, ok := msg.SchemaID(); ok && id != {{.Type}}SchemaID {
+		return nil, fmt.Errorf("{{.Type}}: record has schema ID %d, generated from %d", id, {{.Type}}SchemaID)
+	}
+	var o {{.Type}}
+	if err := o.Unmarshal(msg.Value); err != nil {
+		return nil, fmt.Errorf("failed to decode {{.Type}}: %w", err)
+	}
+	return &o, nil
+}
+{{end}}`))
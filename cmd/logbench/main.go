@@ -0,0 +1,138 @@
+// Command logbench compares `go test -bench` output with a baseline and
+// fails when a benchmark got significantly slower or allocates more. It is
+// what make bench-check runs:
+//
+//	go test -run '^$' -bench . -benchmem -count 5 ./logger > bench_output.txt
+//	go run ./cmd/logbench -baseline logger/testdata/bench-baseline.txt bench_output.txt
+//
+// With -count above 1 the median ns/op of each benchmark is compared.
+// Results vary between machines, so record the baseline (make
+// bench-baseline) on the machine class that runs the comparison.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// result collects the runs of one benchmark
+type result struct {
+	nsPerOp     []float64
+	allocsPerOp int64 // The lowest seen, as allocations don't vary by noise
+}
+
+func main() {
+	baseline := flag.String("baseline", "", "Benchmark output to compare against")
+	maxRegression := flag.Float64("max-regression", 0.15, "Allowed slowdown in ns/op as a fraction of the baseline")
+	flag.Parse()
+	if *baseline == "" || flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: logbench -baseline FILE [-max-regression F] FILE")
+		os.Exit(2)
+	}
+
+	base, err := parse(*baseline)
+	if err != nil {
+		log.Fatalf("logbench: %v", err)
+	}
+	cur, err := parse(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("logbench: %v", err)
+	}
+
+	names := make([]string, 0, len(cur))
+	for name := range cur {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := false
+	for _, name := range names {
+		now := cur[name]
+		was, ok := base[name]
+		if !ok {
+			fmt.Printf("%-28s %10.1f ns/op %4d allocs/op (new)\n", name, median(now.nsPerOp), now.allocsPerOp)
+			continue // Nothing to compare with yet
+		}
+		nowNs, wasNs := median(now.nsPerOp), median(was.nsPerOp)
+		fmt.Printf("%-28s %10.1f ns/op (baseline %.1f, %+.0f%%) %4d allocs/op (baseline %d)\n",
+			name, nowNs, wasNs, (nowNs/wasNs-1)*100, now.allocsPerOp, was.allocsPerOp)
+		if wasNs > 0 && nowNs > wasNs*(1+*maxRegression) {
+			fmt.Printf("REGRESSION %s: %.1f ns/op, baseline %.1f\n", name, nowNs, wasNs)
+			failed = true
+		}
+		if now.allocsPerOp > was.allocsPerOp {
+			fmt.Printf("REGRESSION %s: %d allocs/op, baseline %d\n", name, now.allocsPerOp, was.allocsPerOp)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// parse reads the benchmark lines of `go test -bench -benchmem` output, e.g.
+// "BenchmarkInfo-8  338154  3912 ns/op  432 B/op  2 allocs/op"
+func parse(path string) (map[string]*result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := make(map[string]*result)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		name := fields[0]
+		if i := strings.LastIndexByte(name, '-'); i > 0 {
+			name = name[:i] // Drop the GOMAXPROCS suffix
+		}
+		r, ok := results[name]
+		if !ok {
+			r = &result{allocsPerOp: -1}
+			results[name] = r
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				r.nsPerOp = append(r.nsPerOp, v)
+			case "allocs/op":
+				if r.allocsPerOp < 0 || int64(v) < r.allocsPerOp {
+					r.allocsPerOp = int64(v)
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no benchmark results in %s", path)
+	}
+	return results, nil
+}
+
+func median(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), vs...)
+	sort.Float64s(s)
+	if len(s)%2 == 1 {
+		return s[len(s)/2]
+	}
+	return (s[len(s)/2-1] + s[len(s)/2]) / 2
+}
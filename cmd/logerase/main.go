@@ -0,0 +1,67 @@
+// Command logerase removes a data subject from retained log files and
+// appends the audit record of the erasure to -audit:
+//
+//	logerase -request DSR-1234 -subject user-42 -keys user_id,customer_id -audit /var/log/app/erasures.jsonl /var/log/app/app-*.log.gz
+//
+// With -mode hash, matching entries are kept with the subject's values
+// replaced by their hash under LOG_REDACTION_SALT; set it to the service's
+// RedactionConfig.Salt so the hashes match those already logged.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/upendravikram5/upendra/logger/erasure"
+)
+
+func main() {
+	request := flag.String("request", "", "Reference of the erasure request")
+	subject := flag.String("subject", "", "Identifier to erase")
+	keys := flag.String("keys", "", "Comma-separated keys holding the identifier; empty matches any string value")
+	mode := flag.String("mode", erasure.ModeRemove, "remove drops matching entries, hash rewrites them")
+	audit := flag.String("audit", "", "File the audit record is appended to (default stdout)")
+	flag.Parse()
+
+	if *request == "" || *subject == "" || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: logerase -request ID -subject VALUE [-keys a,b] [-mode remove|hash] [-audit FILE] FILE...")
+		os.Exit(2)
+	}
+	req := erasure.Request{
+		ID:      *request,
+		Subject: *subject,
+		Mode:    *mode,
+		Salt:    os.Getenv("LOG_REDACTION_SALT"),
+	}
+	if *keys != "" {
+		req.Keys = strings.Split(*keys, ",")
+	}
+
+	rec, err := erasure.Erase(req, flag.Args()...)
+	if rec != nil {
+		if werr := writeRecord(*audit, rec); werr != nil {
+			log.Fatalf("logerase: failed to write audit record: %v", werr)
+		}
+	}
+	if err != nil {
+		log.Fatalf("logerase: %v", err)
+	}
+}
+
+func writeRecord(path string, rec *erasure.Record) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return json.NewEncoder(w).Encode(rec)
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// loadAWSConfig uses the default credential chain (env, shared config, IRSA, instance role)
+func loadAWSConfig(ctx context.Context, region string) (aws.Config, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return cfg, nil
+}
+
+// SecretsManagerProvider resolves secret://aws-sm/<secret-id>#<json-key>.
+// Without a key the whole secret string is returned.
+type SecretsManagerProvider struct {
+	client *secretsmanager.Client
+}
+
+// NewSecretsManagerProvider creates the provider; an empty region uses the default chain
+func NewSecretsManagerProvider(ctx context.Context, region string) (*SecretsManagerProvider, error) {
+	cfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretsManagerProvider{client: secretsmanager.NewFromConfig(cfg)}, nil
+}
+
+// Resolve reads the secret and optionally one key of its JSON payload
+func (p *SecretsManagerProvider) Resolve(ctx context.Context, path, field string) (string, error) {
+	out, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(path)})
+	if err != nil {
+		return "", fmt.Errorf("secrets manager get %s: %w", path, err)
+	}
+	value := aws.ToString(out.SecretString)
+	if field == "" {
+		return value, nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", path, err)
+	}
+	v, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %q", path, field)
+	}
+	return fmt.Sprint(v), nil
+}
+
+// ParameterStoreProvider resolves secret://ssm/<parameter-name>. The name is
+// used verbatim, so hierarchical names keep their leading slash:
+// secret://ssm//prod/kafka/sasl_password
+type ParameterStoreProvider struct {
+	client *ssm.Client
+}
+
+// NewParameterStoreProvider creates the provider; an empty region uses the default chain
+func NewParameterStoreProvider(ctx context.Context, region string) (*ParameterStoreProvider, error) {
+	cfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return &ParameterStoreProvider{client: ssm.NewFromConfig(cfg)}, nil
+}
+
+// Resolve reads the parameter, decrypting SecureString values
+func (p *ParameterStoreProvider) Resolve(ctx context.Context, path, field string) (string, error) {
+	out, err := p.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(path),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("parameter store get %s: %w", path, err)
+	}
+	if out.Parameter == nil {
+		return "", fmt.Errorf("parameter %s not found", path)
+	}
+	return aws.ToString(out.Parameter.Value), nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// CachedProvider caches another provider's values and refreshes them in the
+// background, so repeated lookups don't hit the secret store and rotated
+// values are picked up without a restart. Refreshing only updates the cache:
+// a struct filled by SecretResolver.Resolve keeps the values it got, and
+// only later Resolve calls see new ones. Register an OnChange callback to
+// re-resolve or apply a rotated value:
+//
+//	cached.OnChange(func(path, field, value string) {
+//		if field == "sasl_password" {
+//			rotator.SetCredentials(username, value) // e.g. a kafka.Rotator
+//		}
+//	})
+type CachedProvider struct {
+	provider SecretProvider
+	interval time.Duration
+
+	mu        sync.RWMutex
+	cache     map[secretKey]string
+	callbacks []func(path, field, value string)
+}
+
+type secretKey struct {
+	path, field string
+}
+
+// NewCachedProvider wraps provider; cached values are refreshed every interval by Run
+func NewCachedProvider(provider SecretProvider, interval time.Duration) *CachedProvider {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &CachedProvider{provider: provider, interval: interval, cache: make(map[secretKey]string)}
+}
+
+// Resolve returns the cached value, fetching it on first use
+func (c *CachedProvider) Resolve(ctx context.Context, path, field string) (string, error) {
+	key := secretKey{path, field}
+	c.mu.RLock()
+	v, ok := c.cache[key]
+	c.mu.RUnlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err := c.provider.Resolve(ctx, path, field)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.cache[key] = v
+	c.mu.Unlock()
+	return v, nil
+}
+
+// OnChange calls fn with the new value whenever a refresh finds a cached
+// secret changed. fn runs on the goroutine calling Run and should not block.
+func (c *CachedProvider) OnChange(fn func(path, field, value string)) {
+	c.mu.Lock()
+	c.callbacks = append(c.callbacks, fn)
+	c.mu.Unlock()
+}
+
+// Run refreshes every cached value on the interval until ctx is done.
+// A failed refresh keeps the previous value.
+func (c *CachedProvider) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.refresh(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *CachedProvider) refresh(ctx context.Context) {
+	c.mu.RLock()
+	keys := make([]secretKey, 0, len(c.cache))
+	for k := range c.cache {
+		keys = append(keys, k)
+	}
+	c.mu.RUnlock()
+
+	for _, k := range keys {
+		v, err := c.provider.Resolve(ctx, k.path, k.field)
+		if err != nil {
+			log.Printf("Failed to refresh secret %s: %v", k.path, err)
+			continue
+		}
+		c.mu.Lock()
+		changed := c.cache[k] != v
+		c.cache[k] = v
+		callbacks := c.callbacks
+		c.mu.Unlock()
+		if !changed {
+			continue
+		}
+		for _, fn := range callbacks {
+			fn(k.path, k.field, v)
+		}
+	}
+}
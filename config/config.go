@@ -0,0 +1,82 @@
+// Package config loads the service configuration shared by main.go and the
+// infrastructure packages.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/upendravikram5/upendra/config/env"
+	"github.com/upendravikram5/upendra/config/flags"
+	"github.com/upendravikram5/upendra/kafka"
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/tracing"
+)
+
+// Config holds the service configuration
+type Config struct {
+	ServiceName     string        `env:"SERVICE_NAME" flag:"service.name"`
+	HTTPAddress     string        `env:"HTTP_ADDRESS,default=:8080" flag:"http.address"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT,default=30s" flag:"shutdown.timeout"`
+	Logging         logger.Config
+	Kafka           kafka.Config `env:"-"` // Bound only when brokers are configured
+	Tracing         tracing.Config
+}
+
+// LoadConfig loads the configuration and validates it. When CONFIG_DIR is
+// set the profile for APP_ENV is read from it first (see LoadProfile); the
+// environment variables named in the env tags override the file values.
+func LoadConfig() (*Config, error) {
+	return load(nil)
+}
+
+// LoadConfigFlags is LoadConfig with command-line flags, e.g. --log.level,
+// taking precedence over everything else. The flags named in the flag tags
+// are registered on fs, which may hold the program's own flags, and args
+// are parsed with it.
+func LoadConfigFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	fl := flags.Register(fs, &Config{})
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return load(fl)
+}
+
+func load(fl *flags.Flags) (*Config, error) {
+	cfg := &Config{}
+	if dir := os.Getenv("CONFIG_DIR"); dir != "" {
+		var err error
+		if cfg, err = LoadProfile(dir, os.Getenv("APP_ENV")); err != nil {
+			return nil, err
+		}
+	}
+	if err := env.Bind(cfg); err != nil {
+		return nil, err
+	}
+
+	// Kafka is optional; only services that set brokers get a Kafka config
+	kafkaFlag := fl != nil && fl.Given("kafka.brokers")
+	if os.Getenv("KAFKA_BOOTSTRAP_SERVERS") != "" || kafkaFlag || len(cfg.Kafka.Brokers()) > 0 {
+		if err := env.Bind(&cfg.Kafka); err != nil {
+			return nil, fmt.Errorf("failed to load kafka config: %w", err)
+		}
+	}
+	if fl != nil {
+		if err := fl.Apply(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = cfg.ServiceName
+	}
+	if cfg.Kafka.OriginService == "" && len(cfg.Kafka.Brokers()) > 0 {
+		cfg.Kafka.OriginService = cfg.ServiceName
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,221 @@
+// Package env binds environment variables to config structs through struct
+// tags, replacing hand-written os.Getenv parsing:
+//
+//	type Config struct {
+//		Level    string        `env:"LOG_LEVEL,default=info"`
+//		Outputs  []string      `env:"LOG_OUTPUT"`       // Comma-separated
+//		Timeout  time.Duration `env:"SHUTDOWN_TIMEOUT"` // time.ParseDuration syntax
+//		Database DBConfig      // Untagged structs are searched for tags
+//		Ignored  string        `env:"-"`
+//	}
+//
+// A set variable overwrites the field. A default is only applied to a field
+// that is still zero, so values loaded from files before Bind are kept.
+package env
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind sets the tagged fields of the struct pointed to by cfg. Supported
+// field types are strings, bools, numbers, time.Duration, types
+// implementing encoding.TextUnmarshaler, slices of those (comma-separated)
+// and maps and structs, which are read as JSON. Errors for every invalid
+// variable are returned together.
+func Bind(cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env: Bind needs a pointer to a struct, got %T", cfg)
+	}
+	var errs []error
+	bindStruct(v.Elem(), &errs)
+	return errors.Join(errs...)
+}
+
+type tag struct {
+	name       string
+	def        string
+	hasDefault bool
+}
+
+func parseTag(s string) (tag, bool) {
+	if s == "" || s == "-" {
+		return tag{}, false
+	}
+	parts := strings.Split(s, ",")
+	t := tag{name: parts[0]}
+	for i := 1; i < len(parts); i++ {
+		if d, ok := strings.CutPrefix(parts[i], "default="); ok {
+			// The default runs to the end of the tag so it may contain commas
+			t.def, t.hasDefault = strings.Join(append([]string{d}, parts[i+1:]...), ","), true
+			break
+		}
+	}
+	return t, t.name != ""
+}
+
+// bindStruct reports whether any variable was set inside v
+func bindStruct(v reflect.Value, errs *[]error) bool {
+	set := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		raw, hasTag := sf.Tag.Lookup("env")
+		if hasTag && raw == "-" {
+			continue
+		}
+		f := v.Field(i)
+		if tg, ok := parseTag(raw); ok {
+			if s, found := os.LookupEnv(tg.name); found && s != "" {
+				if err := setValue(f, s); err != nil {
+					*errs = append(*errs, fmt.Errorf("invalid %s: %w", tg.name, err))
+				}
+				set = true
+			} else if tg.hasDefault && f.IsZero() {
+				if err := setValue(f, tg.def); err != nil {
+					*errs = append(*errs, fmt.Errorf("invalid default for %s: %w", tg.name, err))
+				}
+			}
+			continue
+		}
+		if !tagged(f.Type(), map[reflect.Type]bool{}) {
+			continue
+		}
+		switch {
+		case f.Kind() == reflect.Struct:
+			set = bindStruct(f, errs) || set
+		case f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.Struct:
+			if !f.IsNil() {
+				set = bindStruct(f.Elem(), errs) || set
+				continue
+			}
+			// Optional sections are only created when one of their variables is set
+			n := reflect.New(f.Type().Elem())
+			if bindStruct(n.Elem(), errs) {
+				f.Set(n)
+				set = true
+			}
+		}
+	}
+	return set
+}
+
+// tagged reports whether t holds env-tagged fields, so bindStruct skips
+// structs without any, self-referential ones included
+func tagged(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if raw, ok := sf.Tag.Lookup("env"); ok {
+			if raw == "-" {
+				continue
+			}
+			return true
+		}
+		if tagged(sf.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// FieldVar returns the variable named in the env tag of field and its default
+func FieldVar(field reflect.StructField) (name, def string, ok bool) {
+	tg, ok := parseTag(field.Tag.Get("env"))
+	return tg.name, tg.def, ok
+}
+
+// SetField parses s into v the way Bind parses a variable
+func SetField(v reflect.Value, s string) error {
+	return setValue(v, s)
+}
+
+func setValue(f reflect.Value, s string) error {
+	if f.CanAddr() && f.Addr().Type().Implements(textUnmarshalerType) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	case reflect.Slice:
+		parts := strings.Split(s, ",")
+		slice := reflect.MakeSlice(f.Type(), 0, len(parts))
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			e := reflect.New(f.Type().Elem()).Elem()
+			if err := setValue(e, p); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, e)
+		}
+		f.Set(slice)
+	case reflect.Map, reflect.Struct, reflect.Ptr:
+		p := reflect.New(f.Type())
+		if err := json.Unmarshal([]byte(s), p.Interface()); err != nil {
+			return err
+		}
+		f.Set(p.Elem())
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package flags generates command-line flags from config structs. Fields
+// tagged with flag:"log.level" get a --log.level flag; untagged structs are
+// searched for tags as with env tags. Flags are recorded when parsed and
+// applied to a loaded config with Apply, so they win over every other
+// source. Secrets have no flags since command lines are visible to other
+// processes.
+package flags
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+
+	"github.com/upendravikram5/upendra/config/env"
+)
+
+// Flags holds the values of the generated flags given on the command line
+type Flags struct {
+	values map[string]string
+}
+
+// Register defines a flag on fs for every flag-tagged field of the struct
+// type cfg points to. The flags carry no defaults of their own: one that is
+// not given leaves the field as loaded. The usage text names the field's
+// environment variable and default.
+func Register(fs *flag.FlagSet, cfg interface{}) *Flags {
+	f := &Flags{values: make(map[string]string)}
+	walk(reflect.TypeOf(cfg), func(name string, sf reflect.StructField) {
+		usage := sf.Name
+		if v, def, ok := env.FieldVar(sf); ok {
+			usage = "Overrides " + v
+			if def != "" {
+				usage += " (default " + def + ")"
+			}
+		}
+		base := sf.Type
+		for base.Kind() == reflect.Ptr {
+			base = base.Elem()
+		}
+		fs.Var(&value{name: name, flags: f, isBool: base.Kind() == reflect.Bool}, name, usage)
+	})
+	return f
+}
+
+// Apply sets the fields of the struct pointed to by cfg whose flags were
+// given. Errors for every invalid flag are returned together.
+func (f *Flags) Apply(cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("flags: Apply needs a pointer to a struct, got %T", cfg)
+	}
+	var errs []error
+	apply(v.Elem(), f.values, &errs)
+	return errors.Join(errs...)
+}
+
+// Given reports whether the flag name was set on the command line
+func (f *Flags) Given(name string) bool {
+	_, ok := f.values[name]
+	return ok
+}
+
+// value records a flag until Apply
+type value struct {
+	name   string
+	flags  *Flags
+	isBool bool
+}
+
+func (v *value) String() string {
+	if v == nil || v.flags == nil {
+		return ""
+	}
+	return v.flags.values[v.name]
+}
+
+func (v *value) Set(s string) error {
+	v.flags.values[v.name] = s
+	return nil
+}
+
+func (v *value) IsBoolFlag() bool { return v.isBool }
+
+func walk(t reflect.Type, fn func(name string, sf reflect.StructField)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if name := sf.Tag.Get("flag"); name != "" && name != "-" {
+			fn(name, sf)
+			continue
+		}
+		if tagged(sf.Type, map[reflect.Type]bool{}) {
+			walk(sf.Type, fn)
+		}
+	}
+}
+
+// tagged reports whether t holds flag-tagged fields, so apply and walk skip
+// structs without any, self-referential ones included
+func tagged(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if name := sf.Tag.Get("flag"); name != "" && name != "-" {
+			return true
+		}
+		if tagged(sf.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply reports whether any flag was applied inside v
+func apply(v reflect.Value, values map[string]string, errs *[]error) bool {
+	set := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := v.Field(i)
+		if name := sf.Tag.Get("flag"); name != "" && name != "-" {
+			s, ok := values[name]
+			if !ok {
+				continue
+			}
+			if err := env.SetField(f, s); err != nil {
+				*errs = append(*errs, fmt.Errorf("invalid --%s: %w", name, err))
+			}
+			set = true
+			continue
+		}
+		if !tagged(f.Type(), map[reflect.Type]bool{}) {
+			continue
+		}
+		switch {
+		case f.Kind() == reflect.Struct:
+			set = apply(f, values, errs) || set
+		case f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.Struct:
+			if !f.IsNil() {
+				set = apply(f.Elem(), values, errs) || set
+				continue
+			}
+			n := reflect.New(f.Type().Elem())
+			if apply(n.Elem(), values, errs) {
+				f.Set(n)
+				set = true
+			}
+		}
+	}
+	return set
+}
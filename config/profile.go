@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile files are named base.<ext> and <APP_ENV>.<ext> in the profile
+// directory, with ext one of these; YAML files may be written as JSON too
+var profileExtensions = []string{".yaml", ".yml", ".json"}
+
+// LoadProfile reads the base profile in dir and deep merges the profile for
+// env over it: objects are merged key by key, any other value (lists
+// included) replaces the base one, and null removes it. Keys are the Config
+// field names in any case, e.g. "logging: {level: debug}"; durations are
+// written as strings like "30s". An empty env loads the base alone; a set env
+// without its file is an error so a typo doesn't silently run on base values.
+// The result is not validated.
+func LoadProfile(dir, env string) (*Config, error) {
+	merged, found, err := readProfile(dir, "base")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		merged = map[string]interface{}{}
+	}
+	if env != "" {
+		overlay, found, err := readProfile(dir, env)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("config: no profile for APP_ENV %q in %s", env, dir)
+		}
+		merged = mergeProfiles(merged, overlay)
+	}
+
+	cfg := &Config{}
+	if err := decodeProfile(merged, cfg); err != nil {
+		return nil, fmt.Errorf("config: profile %s: %w", env, err)
+	}
+	return cfg, nil
+}
+
+// readProfile parses the first existing file for name
+func readProfile(dir, name string) (map[string]interface{}, bool, error) {
+	for _, ext := range profileExtensions {
+		path := filepath.Join(dir, name+ext)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("config: %w", err)
+		}
+		var m map[string]interface{}
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, false, fmt.Errorf("config: failed to parse %s: %w", path, err)
+		}
+		if m == nil {
+			m = map[string]interface{}{}
+		}
+		return m, true, nil
+	}
+	return nil, false, nil
+}
+
+// mergeProfiles returns base with overlay merged over it; neither is modified
+func mergeProfiles(base, overlay map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(base)+len(overlay))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range overlay {
+		// Keys match fields case-insensitively, so "Logging" overrides "logging"
+		for bk := range out {
+			if bk != k && strings.EqualFold(bk, k) {
+				out[k] = out[bk]
+				delete(out, bk)
+			}
+		}
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		bm, baseIsMap := out[k].(map[string]interface{})
+		om, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			out[k] = mergeProfiles(bm, om)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// decodeProfile stores a merged profile in cfg through encoding/json, after
+// turning duration strings into the nanoseconds time.Duration decodes from
+func decodeProfile(m map[string]interface{}, cfg *Config) error {
+	normalized, err := normalizeDurations(m, reflect.TypeOf(cfg).Elem(), "")
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, cfg)
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// normalizeDurations walks v alongside the type it decodes into
+func normalizeDurations(v interface{}, t reflect.Type, path string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return v, nil // Decodes its own durations, e.g. kafka.TopicConfig
+	}
+	switch x := v.(type) {
+	case string:
+		if t != durationType {
+			return v, nil
+		}
+		d, err := time.ParseDuration(x)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return int64(d), nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			var et reflect.Type
+			switch t.Kind() {
+			case reflect.Struct:
+				if f, ok := fieldByFold(t, k); ok {
+					et = f.Type
+				}
+			case reflect.Map:
+				et = t.Elem()
+			}
+			if et == nil {
+				out[k] = e // Unknown keys are left to encoding/json, which ignores them
+				continue
+			}
+			n, err := normalizeDurations(e, et, joinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = n
+		}
+		return out, nil
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return v, nil
+		}
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			n, err := normalizeDurations(e, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = n
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// fieldByFold finds the exported field of t named key in any case, as encoding/json matches it
+func fieldByFold(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && strings.EqualFold(f.Name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
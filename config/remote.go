@@ -0,0 +1,190 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/upendravikram5/upendra/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+// RemoteSource reads settings kept under a prefix in a remote store such as
+// etcd or Consul. Keys are relative to the prefix; naming them after the
+// flag tags, e.g. "log.level", keeps them recognisable.
+type RemoteSource interface {
+	// Load returns every value under the prefix
+	Load(ctx context.Context) (map[string]string, error)
+	// Watch calls fn with the current values and again with the new values on
+	// every change, until ctx is done or the watch fails
+	Watch(ctx context.Context, fn func(map[string]string)) error
+}
+
+// Change describes one key that changed in the remote store
+type Change struct {
+	Key      string
+	Value    string
+	Previous string
+	Deleted  bool
+}
+
+// RemoteConfig keeps the values of a RemoteSource and notifies subscribers
+// when they change, so running components pick up new settings without a
+// restart:
+//
+//	rc := config.NewRemoteConfig(src)
+//	rc.BindLogLevel("log.level")
+//	rc.BindFloat("kafka.max-rate", func(v float64) { limiter.SetLimit(rate.Limit(v)) })
+//	go rc.Run(ctx)
+type RemoteConfig struct {
+	source RemoteSource
+
+	mu     sync.RWMutex
+	values map[string]string
+	subs   map[string][]func(Change)
+}
+
+// NewRemoteConfig creates a RemoteConfig reading from source; call Load or
+// Run to fetch the values
+func NewRemoteConfig(source RemoteSource) *RemoteConfig {
+	return &RemoteConfig{source: source, values: make(map[string]string), subs: make(map[string][]func(Change))}
+}
+
+// Load fetches the current values, notifying subscribers of the differences
+func (r *RemoteConfig) Load(ctx context.Context) error {
+	values, err := r.source.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load remote config: %w", err)
+	}
+	r.update(values)
+	return nil
+}
+
+// Get returns the current value of key
+func (r *RemoteConfig) Get(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.values[key]
+	return v, ok
+}
+
+// Subscribe calls fn whenever key changes; an empty key subscribes to every
+// key. If key already has a value fn is called with it right away. Later
+// callbacks run on the goroutine that saw the change and should not block.
+func (r *RemoteConfig) Subscribe(key string, fn func(Change)) {
+	r.mu.Lock()
+	r.subs[key] = append(r.subs[key], fn)
+	var current []Change
+	for k, v := range r.values {
+		if key == "" || k == key {
+			current = append(current, Change{Key: k, Value: v})
+		}
+	}
+	r.mu.Unlock()
+
+	for _, c := range current {
+		fn(c)
+	}
+}
+
+// Bind calls set with the value of key parsed by parse, now if key has a
+// value and on every change. Values parse rejects are logged and ignored;
+// deleting the key leaves the setting as it is.
+func Bind[T any](r *RemoteConfig, key string, parse func(string) (T, error), set func(T)) {
+	r.Subscribe(key, func(c Change) {
+		if c.Deleted {
+			return
+		}
+		v, err := parse(c.Value)
+		if err != nil {
+			log.Printf("Ignoring remote %s %q: %v", c.Key, c.Value, err)
+			return
+		}
+		set(v)
+	})
+}
+
+// BindLogLevel sets the shared logger's level from key
+func (r *RemoteConfig) BindLogLevel(key string) {
+	Bind(r, key, zapcore.ParseLevel, logger.SetLevel)
+}
+
+// BindInt binds key to set as a base 10 integer
+func (r *RemoteConfig) BindInt(key string, set func(int)) {
+	Bind(r, key, strconv.Atoi, set)
+}
+
+// BindFloat binds key to set as a float, e.g. a sampling ratio or a rate limit
+func (r *RemoteConfig) BindFloat(key string, set func(float64)) {
+	Bind(r, key, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }, set)
+}
+
+// BindBool binds key to set as a boolean in strconv.ParseBool's syntax
+func (r *RemoteConfig) BindBool(key string, set func(bool)) {
+	Bind(r, key, strconv.ParseBool, set)
+}
+
+// BindDuration binds key to set as a duration such as "250ms"
+func (r *RemoteConfig) BindDuration(key string, set func(time.Duration)) {
+	Bind(r, key, time.ParseDuration, set)
+}
+
+// Run watches the source until ctx is done, restarting the watch with
+// backoff when it fails. Changes made while it was down are applied once it
+// is back, since every watch starts from the current values.
+func (r *RemoteConfig) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := r.source.Watch(ctx, r.update)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("Remote config watch failed, retrying in %s: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// update replaces the values and notifies subscribers of what changed
+func (r *RemoteConfig) update(values map[string]string) {
+	r.mu.Lock()
+	var changes []Change
+	for k, v := range values {
+		if prev, ok := r.values[k]; !ok || prev != v {
+			changes = append(changes, Change{Key: k, Value: v, Previous: prev})
+		}
+	}
+	for k, prev := range r.values {
+		if _, ok := values[k]; !ok {
+			changes = append(changes, Change{Key: k, Previous: prev, Deleted: true})
+		}
+	}
+	r.values = make(map[string]string, len(values))
+	for k, v := range values {
+		r.values[k] = v
+	}
+	notify := make([][]func(Change), len(changes))
+	for i, c := range changes {
+		notify[i] = append(append([]func(Change){}, r.subs[c.Key]...), r.subs[""]...)
+	}
+	r.mu.Unlock()
+
+	for i, c := range changes {
+		for _, fn := range notify[i] {
+			fn(c)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+// ConsulConfig holds the Consul connection settings for remote config
+type ConsulConfig struct {
+	Address    string // Defaults to CONSUL_HTTP_ADDR
+	Token      string // Defaults to CONSUL_HTTP_TOKEN
+	Datacenter string
+	Prefix     string        // KV prefix, e.g. "config/orders/"
+	WaitTime   time.Duration // Longest a blocking query waits for a change (default 5m)
+}
+
+// ConsulSource is a RemoteSource reading the keys under a prefix in the
+// Consul KV store, watched with blocking queries
+type ConsulSource struct {
+	kv  *consul.KV
+	cfg ConsulConfig
+}
+
+// NewConsulSource creates the Consul client
+func NewConsulSource(cfg ConsulConfig) (*ConsulSource, error) {
+	ccfg := consul.DefaultConfig()
+	if cfg.Address != "" {
+		ccfg.Address = cfg.Address
+	}
+	if cfg.Token != "" {
+		ccfg.Token = cfg.Token
+	}
+	if cfg.Datacenter != "" {
+		ccfg.Datacenter = cfg.Datacenter
+	}
+	if cfg.WaitTime <= 0 {
+		cfg.WaitTime = 5 * time.Minute
+	}
+	client, err := consul.NewClient(ccfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consul client: %w", err)
+	}
+	return &ConsulSource{kv: client.KV(), cfg: cfg}, nil
+}
+
+// Load reads every key under the prefix
+func (s *ConsulSource) Load(ctx context.Context) (map[string]string, error) {
+	values, _, err := s.list(ctx, 0)
+	return values, err
+}
+
+func (s *ConsulSource) list(ctx context.Context, index uint64) (map[string]string, uint64, error) {
+	q := (&consul.QueryOptions{WaitIndex: index, WaitTime: s.cfg.WaitTime}).WithContext(ctx)
+	pairs, meta, err := s.kv.List(s.cfg.Prefix, q)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul list %s: %w", s.cfg.Prefix, err)
+	}
+	values := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		key := strings.TrimPrefix(p.Key, s.cfg.Prefix)
+		if key == "" || strings.HasSuffix(key, "/") { // Folder entries hold no value
+			continue
+		}
+		values[key] = string(p.Value)
+	}
+	return values, meta.LastIndex, nil
+}
+
+// Watch polls with blocking queries, which Consul answers as soon as a key
+// under the prefix changes or WaitTime passes
+func (s *ConsulSource) Watch(ctx context.Context, fn func(map[string]string)) error {
+	var index uint64
+	for {
+		values, next, err := s.list(ctx, index)
+		if err != nil {
+			return err
+		}
+		if next < index {
+			next = 0 // The index went backwards, e.g. after a restore; start over
+		}
+		if index == 0 || next != index {
+			fn(values)
+		}
+		index = next
+	}
+}
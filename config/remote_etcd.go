@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdConfig holds the etcd connection settings for remote config
+type EtcdConfig struct {
+	Endpoints   []string
+	Username    string
+	Password    string
+	Prefix      string        // Key prefix, e.g. "/config/orders/"
+	DialTimeout time.Duration // Defaults to 5s
+}
+
+// EtcdSource is a RemoteSource reading the keys under a prefix in etcd v3
+type EtcdSource struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewEtcdSource connects to etcd
+func NewEtcdSource(cfg EtcdConfig) (*EtcdSource, error) {
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		DialTimeout: cfg.DialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd client: %w", err)
+	}
+	return &EtcdSource{client: client, prefix: cfg.Prefix}, nil
+}
+
+// Load reads every key under the prefix
+func (s *EtcdSource) Load(ctx context.Context) (map[string]string, error) {
+	values, _, err := s.load(ctx)
+	return values, err
+}
+
+func (s *EtcdSource) load(ctx context.Context) (map[string]string, int64, error) {
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, fmt.Errorf("etcd get %s: %w", s.prefix, err)
+	}
+	values := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		values[strings.TrimPrefix(string(kv.Key), s.prefix)] = string(kv.Value)
+	}
+	return values, resp.Header.Revision, nil
+}
+
+// Watch reads the keys and follows their changes from that revision on, so
+// none are missed in between
+func (s *EtcdSource) Watch(ctx context.Context, fn func(map[string]string)) error {
+	values, rev, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	fn(values)
+
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	for resp := range s.client.Watch(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1)) {
+		if err := resp.Err(); err != nil {
+			return fmt.Errorf("etcd watch %s: %w", s.prefix, err)
+		}
+		if len(resp.Events) == 0 {
+			continue
+		}
+		next := make(map[string]string, len(values))
+		for k, v := range values {
+			next[k] = v
+		}
+		for _, ev := range resp.Events {
+			key := strings.TrimPrefix(string(ev.Kv.Key), s.prefix)
+			if ev.Type == clientv3.EventTypeDelete {
+				delete(next, key)
+			} else {
+				next[key] = string(ev.Kv.Value)
+			}
+		}
+		values = next
+		fn(values)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("etcd watch %s closed", s.prefix)
+}
+
+// Close closes the etcd client
+func (s *EtcdSource) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SecretScheme prefixes config values that must be resolved from a secret
+// store, e.g. secret://vault/secret/data/kafka#sasl_password
+const SecretScheme = "secret://"
+
+// SecretProvider reads one field of a secret stored at path
+type SecretProvider interface {
+	Resolve(ctx context.Context, path, field string) (string, error)
+}
+
+// SecretResolver replaces secret:// references in a config with their values
+type SecretResolver struct {
+	providers map[string]SecretProvider
+}
+
+// NewSecretResolver creates a resolver with no providers
+func NewSecretResolver() *SecretResolver {
+	return &SecretResolver{providers: make(map[string]SecretProvider)}
+}
+
+// Register makes provider available under name, the host part of the reference
+func (r *SecretResolver) Register(name string, provider SecretProvider) {
+	r.providers[name] = provider
+}
+
+// Resolve walks the struct pointed to by cfg and resolves every string (and
+// []string element) holding a secret:// reference in place. All failures are
+// returned together, keyed by field path.
+func (r *SecretResolver) Resolve(ctx context.Context, cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Resolve needs a pointer to a struct, got %T", cfg)
+	}
+	var errs []error
+	r.walk(ctx, v.Elem(), "", &errs)
+	return errors.Join(errs...)
+}
+
+func (r *SecretResolver) walk(ctx context.Context, v reflect.Value, path string, errs *[]error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			r.walk(ctx, v.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				r.walk(ctx, v.Field(i), joinPath(path, t.Field(i).Name), errs)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			r.walk(ctx, v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.String:
+		ref := v.String()
+		if !strings.HasPrefix(ref, SecretScheme) || !v.CanSet() {
+			return
+		}
+		secret, err := r.lookup(ctx, ref)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		v.SetString(secret)
+	}
+}
+
+// lookup parses secret://<provider>/<path>#<field> and asks the provider
+func (r *SecretResolver) lookup(ctx context.Context, ref string) (string, error) {
+	rest := strings.TrimPrefix(ref, SecretScheme)
+	name, pathField, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", fmt.Errorf("malformed secret reference %q", ref)
+	}
+	path, field, _ := strings.Cut(pathField, "#")
+	provider, ok := r.providers[name]
+	if !ok {
+		return "", fmt.Errorf("no secret provider registered for %q", name)
+	}
+	return provider.Resolve(ctx, path, field)
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
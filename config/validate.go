@@ -0,0 +1,316 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/upendravikram5/upendra/ids"
+	"github.com/upendravikram5/upendra/kafka"
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/tracing"
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldError is one validation problem, located by its field path
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every problem found by Validate
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		lines[i] = fe.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d problems):\n  %s", len(e.Errors), strings.Join(lines, "\n  "))
+}
+
+// validator collects problems so they can be reported at once
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the loaded configuration and returns a *ValidationError
+// describing every problem, or nil when the configuration is usable
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.ShutdownTimeout <= 0 {
+		v.add("ShutdownTimeout", "must be positive, got %s", c.ShutdownTimeout)
+	}
+
+	c.validateLogging(v)
+	c.validateKafka(v)
+
+	if _, err := tracing.NewPropagator(c.Tracing.Propagators); err != nil {
+		v.add("Tracing.Propagators", "%v", err)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+func (c *Config) validateLogging(v *validator) {
+	l := c.Logging
+	if l.Level != "" {
+		if _, err := zapcore.ParseLevel(l.Level); err != nil {
+			v.add("Logging.Level", "unknown level %q", l.Level)
+		}
+	}
+	validateEncoding(v, "Logging.Encoding", l.Encoding)
+	switch l.Framing {
+	case "", logger.FramingNewline, logger.FramingStrict, logger.FramingJSONSeq:
+	default:
+		v.add("Logging.Framing", "must be newline, strict or json-seq, got %q", l.Framing)
+	}
+	validateOutputPaths(v, "Logging.OutputPaths", l.OutputPaths)
+	for i, sink := range l.Sinks {
+		prefix := fmt.Sprintf("Logging.Sinks[%d]", i)
+		validateEncoding(v, prefix+".Encoding", sink.Encoding)
+		validateOutputPaths(v, prefix+".OutputPaths", sink.OutputPaths)
+		if sink.WriteTimeout < 0 {
+			v.add(prefix+".WriteTimeout", "must not be negative, got %s", sink.WriteTimeout)
+		}
+		if sink.Fsync != nil {
+			validateFsync(v, prefix+".Fsync", *sink.Fsync)
+		}
+		if sink.Level != "" {
+			if _, err := zapcore.ParseLevel(sink.Level); err != nil {
+				v.add(prefix+".Level", "unknown level %q", sink.Level)
+			}
+		}
+	}
+	if sc := l.Schema; sc != nil {
+		switch sc.Mode {
+		case "", logger.SchemaModeReport, logger.SchemaModeDrop:
+		default:
+			v.add("Logging.Schema.Mode", "must be report or drop, got %q", sc.Mode)
+		}
+		for field, typ := range sc.Types {
+			switch typ {
+			case "string", "int", "float", "bool", "time", "duration", "object", "array":
+			default:
+				v.add("Logging.Schema.Types["+field+"]", "unknown type %q", typ)
+			}
+		}
+	}
+	if r := l.Redaction; r != nil {
+		if r.MaxDepth < 0 {
+			v.add("Logging.Redaction.MaxDepth", "must not be negative, got %d", r.MaxDepth)
+		}
+		if len(r.HashKeys) > 0 && r.Salt == "" {
+			v.add("Logging.Redaction.Salt", "must be set with HashKeys")
+		}
+	}
+	if d := l.CrashDump; d != nil && d.Entries < 0 {
+		v.add("Logging.CrashDump.Entries", "must not be negative, got %d", d.Entries)
+	}
+	if l.FlightRecorder < 0 {
+		v.add("Logging.FlightRecorder", "must not be negative, got %d", l.FlightRecorder)
+	}
+	if l.ErrorRateInterval < 0 {
+		v.add("Logging.ErrorRateInterval", "must not be negative, got %s", l.ErrorRateInterval)
+	}
+	if l.WriteTimeout < 0 {
+		v.add("Logging.WriteTimeout", "must not be negative, got %s", l.WriteTimeout)
+	}
+	validateFsync(v, "Logging.Fsync", l.Fsync)
+	if n := l.Notify; n != nil {
+		if n.URL == "" {
+			v.add("Logging.Notify.URL", "must be set")
+		}
+		switch n.Format {
+		case "", logger.NotifyFormatSlack, logger.NotifyFormatGeneric:
+		default:
+			v.add("Logging.Notify.Format", "must be slack or generic, got %q", n.Format)
+		}
+	}
+	if e := l.Email; e != nil {
+		if _, _, err := net.SplitHostPort(e.Addr); err != nil {
+			v.add("Logging.Email.Addr", "must be host:port, got %q", e.Addr)
+		}
+		if e.From == "" {
+			v.add("Logging.Email.From", "must be set")
+		}
+		if len(e.To) == 0 {
+			v.add("Logging.Email.To", "must list at least one recipient")
+		}
+		if !e.Immediate && e.DigestInterval <= 0 {
+			v.add("Logging.Email", "enable Immediate or set DigestInterval")
+		}
+	}
+	r := l.Rotation
+	if r.MaxSizeMB < 0 {
+		v.add("Logging.Rotation.MaxSizeMB", "must not be negative")
+	}
+	if r.MaxBackups < 0 {
+		v.add("Logging.Rotation.MaxBackups", "must not be negative")
+	}
+	if r.MaxAgeDays < 0 {
+		v.add("Logging.Rotation.MaxAgeDays", "must not be negative")
+	}
+	if r.MaxSizeMB == 0 && (r.MaxBackups > 0 || r.MaxAgeDays > 0 || r.Compress) {
+		v.add("Logging.Rotation.MaxSizeMB", "must be set when other rotation settings are used")
+	}
+}
+
+func validateEncoding(v *validator, field, encoding string) {
+	switch encoding {
+	case "", "json", "console", "msgpack", "otel", "logfmt", "gelf":
+	default:
+		v.add(field, "must be json, console, msgpack, otel, logfmt or gelf, got %q", encoding)
+	}
+}
+
+func validateFsync(v *validator, field string, p logger.FsyncPolicy) {
+	if p.Entries < 0 {
+		v.add(field+".Entries", "must not be negative, got %d", p.Entries)
+	}
+	if p.Interval < 0 {
+		v.add(field+".Interval", "must not be negative, got %s", p.Interval)
+	}
+}
+
+func validateOutputPaths(v *validator, field string, paths []string) {
+	for i, p := range paths {
+		field := fmt.Sprintf("%s[%d]", field, i)
+		switch {
+		case p == "":
+			v.add(field, "must not be empty")
+		case p == "stdout" || p == "stderr":
+		case strings.Contains(p, "://"):
+			u, err := url.Parse(p)
+			if err != nil || u.Scheme != "file" {
+				v.add(field, "unsupported output scheme in %q (use stdout, stderr, a path or file://)", p)
+			}
+		}
+	}
+}
+
+func (c *Config) validateKafka(v *validator) {
+	k := c.Kafka
+	if reflect.ValueOf(k).IsZero() { // Kafka is optional
+		return
+	}
+	if len(k.Brokers()) == 0 {
+		v.add("Kafka.BootstrapServers", "must be set")
+	}
+	if k.Backend != "" && !contains(kafka.Backends(), k.Backend) {
+		v.add("Kafka.Backend", "backend %q not compiled in (have %s)", k.Backend, strings.Join(kafka.Backends(), ", "))
+	}
+
+	protocol := strings.ToUpper(k.SecurityProtocol)
+	switch protocol {
+	case "", "PLAINTEXT", "SSL", "SASL_PLAINTEXT", "SASL_SSL":
+	default:
+		v.add("Kafka.SecurityProtocol", "unknown protocol %q", k.SecurityProtocol)
+	}
+	if strings.HasPrefix(protocol, "SASL") {
+		switch strings.ToUpper(k.SASLMechanism) {
+		case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		default:
+			v.add("Kafka.SASLMechanism", "must be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512 with %s, got %q", protocol, k.SASLMechanism)
+		}
+		if k.SASLUsername == "" {
+			v.add("Kafka.SASLUsername", "must be set with %s", protocol)
+		}
+		if k.SASLPassword == "" && k.SASLPasswordFile == "" {
+			v.add("Kafka.SASLPassword", "must be set with %s, or SASLPasswordFile", protocol)
+		}
+	}
+	if (k.SSLCertLocation == "") != (k.SSLKeyLocation == "") {
+		v.add("Kafka.SSLKeyLocation", "must be set together with SSLCertLocation")
+	}
+	switch k.AutoOffsetReset {
+	case "", "earliest", "latest":
+	default:
+		v.add("Kafka.AutoOffsetReset", "must be earliest or latest, got %q", k.AutoOffsetReset)
+	}
+	if k.HandlerTimeout < 0 {
+		v.add("Kafka.HandlerTimeout", "must not be negative, got %s", k.HandlerTimeout)
+	}
+	switch k.Concurrency {
+	case "", kafka.ConcurrencySequential, kafka.ConcurrencyPartition, kafka.ConcurrencyPool, kafka.ConcurrencyPriority:
+	default:
+		v.add("Kafka.Concurrency", "must be sequential, partition, pool or priority, got %q", k.Concurrency)
+	}
+	switch k.Partitioner {
+	case "", kafka.PartitionerMurmur2, kafka.PartitionerRoundRobin, kafka.PartitionerSticky:
+	default:
+		v.add("Kafka.Partitioner", "must be murmur2, roundrobin or sticky, got %q", k.Partitioner)
+	}
+	switch strings.ToLower(k.Compression) {
+	case "", kafka.CompressionNone, kafka.CompressionGzip, kafka.CompressionSnappy, kafka.CompressionLZ4, kafka.CompressionZstd:
+	default:
+		v.add("Kafka.Compression", "must be none, gzip, snappy, lz4 or zstd, got %q", k.Compression)
+	}
+	if k.Linger < 0 {
+		v.add("Kafka.Linger", "must not be negative, got %s", k.Linger)
+	}
+	if k.BatchBytes < 0 {
+		v.add("Kafka.BatchBytes", "must not be negative, got %d", k.BatchBytes)
+	}
+	if k.Workers < 0 {
+		v.add("Kafka.Workers", "must not be negative, got %d", k.Workers)
+	}
+	if k.DrainTimeout < 0 {
+		v.add("Kafka.DrainTimeout", "must not be negative, got %s", k.DrainTimeout)
+	}
+	for topic, tc := range k.Topics {
+		if tc.MaxRetries != nil && *tc.MaxRetries < 0 {
+			v.add("Kafka.Topics["+topic+"].MaxRetries", "must not be negative, got %d", *tc.MaxRetries)
+		}
+		if tc.HandlerTimeout < 0 {
+			v.add("Kafka.Topics["+topic+"].HandlerTimeout", "must not be negative, got %s", tc.HandlerTimeout)
+		}
+		if tc.Weight < 0 {
+			v.add("Kafka.Topics["+topic+"].Weight", "must not be negative, got %d", tc.Weight)
+		}
+	}
+	if err := kafka.CheckStartFrom(k.StartFrom); err != nil {
+		v.add("Kafka.StartFrom", "%v", err)
+	}
+	if _, err := ids.New(k.IDFormat); err != nil {
+		v.add("Kafka.IDFormat", "%v", err)
+	}
+	if sc := k.SchemaCheck; sc.RegistryURL != "" {
+		if u, err := url.Parse(sc.RegistryURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("Kafka.SchemaCheck.RegistryURL", "must be an http or https URL, got %q", sc.RegistryURL)
+		}
+		for _, level := range sc.Compatibility {
+			if err := kafka.CheckCompatibilityLevel(level); err != nil {
+				v.add("Kafka.SchemaCheck.Compatibility", "%v", err)
+			}
+		}
+	}
+	if k.Debug.MaxBytes < 0 {
+		v.add("Kafka.Debug.MaxBytes", "must not be negative, got %d", k.Debug.MaxBytes)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
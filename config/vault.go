@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// VaultConfig holds the Vault connection and authentication settings.
+// Either Token or RoleID/SecretID (AppRole) must be set.
+type VaultConfig struct {
+	Address      string // Defaults to VAULT_ADDR
+	Namespace    string
+	Token        string
+	RoleID       string
+	SecretID     string
+	AppRoleMount string // Defaults to "approle"
+}
+
+// VaultProvider resolves secrets from Vault KV (v1 or v2) and keeps its token renewed
+type VaultProvider struct {
+	client *vault.Client
+	cfg    VaultConfig
+
+	mu        sync.Mutex
+	watcher   *vault.LifetimeWatcher
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewVaultProvider authenticates against Vault; register it under "vault"
+func NewVaultProvider(ctx context.Context, cfg VaultConfig) (*VaultProvider, error) {
+	vcfg := vault.DefaultConfig()
+	if cfg.Address != "" {
+		vcfg.Address = cfg.Address
+	}
+	client, err := vault.NewClient(vcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault client: %w", err)
+	}
+	if cfg.Namespace != "" {
+		client.SetNamespace(cfg.Namespace)
+	}
+	if cfg.AppRoleMount == "" {
+		cfg.AppRoleMount = "approle"
+	}
+
+	p := &VaultProvider{client: client, cfg: cfg, stop: make(chan struct{})}
+	if cfg.Token != "" {
+		client.SetToken(cfg.Token)
+		return p, nil
+	}
+	if err := p.login(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// login performs the AppRole login and starts renewing the resulting token
+func (p *VaultProvider) login(ctx context.Context) error {
+	secret, err := p.client.Logical().WriteWithContext(ctx, "auth/"+p.cfg.AppRoleMount+"/login", map[string]interface{}{
+		"role_id":   p.cfg.RoleID,
+		"secret_id": p.cfg.SecretID,
+	})
+	if err != nil {
+		return fmt.Errorf("vault approle login failed: %w", err)
+	}
+	if secret == nil || secret.Auth == nil {
+		return fmt.Errorf("vault approle login returned no auth data")
+	}
+	p.client.SetToken(secret.Auth.ClientToken)
+
+	if secret.Auth.Renewable {
+		watcher, err := p.client.NewLifetimeWatcher(&vault.LifetimeWatcherInput{Secret: secret})
+		if err != nil {
+			return fmt.Errorf("failed to create vault token watcher: %w", err)
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		select {
+		case <-p.stop:
+			return nil // Closed during the login; Close has stopped the previous watcher only
+		default:
+		}
+		p.watcher = watcher
+		go watcher.Start()
+		go p.renew(watcher)
+	}
+	return nil
+}
+
+// renew watches the token lease and logs in again once it can no longer be renewed
+func (p *VaultProvider) renew(watcher *vault.LifetimeWatcher) {
+	for {
+		select {
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				log.Printf("Vault token renewal stopped: %v", err)
+			}
+			p.relogin()
+			return
+		case <-watcher.RenewCh():
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// relogin logs in again until it succeeds or the provider is closed,
+// doubling the wait between attempts up to 30s
+func (p *VaultProvider) relogin() {
+	backoff := time.Second
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+		err := p.login(context.Background())
+		if err == nil {
+			return
+		}
+		log.Printf("Vault re-login failed, retrying in %s: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-p.stop:
+			return
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// Resolve reads field from the secret at path
+func (p *VaultProvider) Resolve(ctx context.Context, path, field string) (string, error) {
+	secret, err := p.client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("vault read %s: %w", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("vault secret %s not found", path)
+	}
+
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok { // KV v2 wraps the payload
+		data = nested
+	}
+	v, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %q", path, field)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s field %q is not a string", path, field)
+	}
+	return s, nil
+}
+
+// Close stops token renewal; later calls do nothing
+func (p *VaultProvider) Close() error {
+	p.closeOnce.Do(func() { close(p.stop) })
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.watcher != nil {
+		p.watcher.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package errcode gives errors a stable code, a category and a retryable
+// flag, so alerts and dashboards can match on what failed instead of on
+// message text. The logger adds them to entries logging such an error:
+//
+//	err := errcode.Wrap(err, "payment.declined", errcode.CategoryInvalid)
+//	log.Errorw("charge failed", "error", err)
+//	// ... "error": "...", "error.code": "payment.declined",
+//	//     "error.category": "invalid", "error.retryable": false
+package errcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Category groups codes by how callers should react
+type Category string
+
+// Categories
+const (
+	CategoryInvalid      Category = "invalid"      // Bad input; retrying won't help
+	CategoryNotFound     Category = "not_found"    // The target doesn't exist
+	CategoryConflict     Category = "conflict"     // The state changed underneath, e.g. a duplicate
+	CategoryUnauthorized Category = "unauthorized" // Missing or invalid credentials
+	CategoryForbidden    Category = "forbidden"    // Credentials lack the permission
+	CategoryRateLimited  Category = "rate_limited" // Throttled, retry later
+	CategoryTimeout      Category = "timeout"      // A deadline passed
+	CategoryUnavailable  Category = "unavailable"  // A dependency is down, retry later
+	CategoryInternal     Category = "internal"     // A bug or unexpected state
+)
+
+// Retryable reports whether errors in the category are worth retrying by default
+func (c Category) Retryable() bool {
+	switch c {
+	case CategoryRateLimited, CategoryTimeout, CategoryUnavailable:
+		return true
+	}
+	return false
+}
+
+// Error is an error with a code. Code is a stable dotted identifier owned by
+// the service, such as "order.not_found"; Category and Retryable describe it
+// for callers that don't know the code.
+type Error struct {
+	Code      string
+	Category  Category
+	Retryable bool
+	Err       error // The underlying error, if any
+}
+
+// New creates an error with a message, retryable as its category is
+func New(code string, cat Category, msg string) *Error {
+	return &Error{Code: code, Category: cat, Retryable: cat.Retryable(), Err: errors.New(msg)}
+}
+
+// Errorf is New with a formatted message; %w wraps as with fmt.Errorf
+func Errorf(code string, cat Category, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Category: cat, Retryable: cat.Retryable(), Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap gives err a code, retryable as its category is; a nil err returns nil
+func Wrap(err error, code string, cat Category) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Category: cat, Retryable: cat.Retryable(), Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Code
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches another *Error with the same code, so codes can serve as sentinels:
+//
+//	var ErrNotFound = errcode.New("order.not_found", errcode.CategoryNotFound, "order not found")
+//	errors.Is(errcode.Wrap(err, "order.not_found", errcode.CategoryNotFound), ErrNotFound) // true
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// From returns the outermost *Error in err's chain
+func From(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// Code returns err's code, or "" when it has none
+func Code(err error) string {
+	if e, ok := From(err); ok {
+		return e.Code
+	}
+	return ""
+}
+
+// CategoryOf returns err's category, CategoryInternal when it has no code
+func CategoryOf(err error) Category {
+	if e, ok := From(err); ok {
+		return e.Category
+	}
+	return CategoryInternal
+}
+
+// IsRetryable reports whether err is marked retryable
+func IsRetryable(err error) bool {
+	e, ok := From(err)
+	return ok && e.Retryable
+}
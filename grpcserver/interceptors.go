@@ -0,0 +1,211 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/upendravikram5/upendra/ids"
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/logger/fields"
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+// RequestIDHeader is the metadata key carrying the request ID in both directions
+const RequestIDHeader = "x-request-id"
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the call handled under ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID keeps the caller's request ID or makes one, and sends it back in the response header
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDHeader); len(v) > 0 && v[0] != "" {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = ids.NewID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// UnaryRequestID puts the request ID in the context, see RequestID
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// StreamRequestID puts the request ID in the stream's context, see RequestID
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+	}
+}
+
+// contextStream replaces the context of a stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+// serverError reports whether code is the server's fault rather than the caller's
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable, codes.Unimplemented, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// logCall writes one entry per call; server errors are logged at error level
+func logCall(ctx context.Context, l logger.FieldLogger, method string, start time.Time, err error) {
+	if zl, ok := l.(logger.Logger); ok {
+		l = zl.Ctx(ctx)
+	}
+	code := status.Code(err)
+	kv := []interface{}{
+		"grpc.method", method,
+		"grpc.code", code.String(),
+		fields.DurationMS("grpc.duration", time.Since(start)),
+	}
+	if id := RequestID(ctx); id != "" {
+		kv = append(kv, "request_id", id)
+	}
+	kv = append(kv, logger.RequestFields(ctx)...)
+	if serverError(code) {
+		l.Errorw("grpc request", append(kv, "error", err)...)
+		return
+	}
+	l.Infow("grpc request", kv...)
+}
+
+// UnaryLogging logs every call with its method, status code and duration,
+// plus the fields the handler added with logger.AddRequestFields
+func UnaryLogging(l logger.FieldLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = logger.WithRequestFields(ctx)
+		resp, err := handler(ctx, req)
+		logCall(ctx, l, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLogging logs every stream with its method, status code and duration,
+// plus the fields the handler added with logger.AddRequestFields
+func StreamLogging(l logger.FieldLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ss = &contextStream{ServerStream: ss, ctx: logger.WithRequestFields(ss.Context())}
+		err := handler(srv, ss)
+		logCall(ss.Context(), l, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// Server metrics, registered with the shared registry on first use
+var (
+	metricsOnce sync.Once
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "grpc_server",
+		Name:      "requests_total",
+		Help:      "Handled calls, by method and status code.",
+	}, []string{"method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "grpc_server",
+		Name:      "duration_seconds",
+		Help:      "Time to handle a call, by method.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14), // 1ms .. ~8s
+	}, []string{"method"})
+)
+
+func registerMetrics() {
+	metricsOnce.Do(func() { metrics.MustRegister(requestsTotal, requestDuration) })
+}
+
+func observe(ctx context.Context, method string, start time.Time, err error) {
+	requestsTotal.WithLabelValues(method, status.Code(err).String()).Inc()
+	metrics.ObserveWithExemplar(ctx, requestDuration.WithLabelValues(method), time.Since(start).Seconds())
+}
+
+// UnaryMetrics counts calls and records their duration
+func UnaryMetrics() grpc.UnaryServerInterceptor {
+	registerMetrics()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamMetrics counts streams and records their duration
+func StreamMetrics() grpc.StreamServerInterceptor {
+	registerMetrics()
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+// recovered logs a panic with its stack and turns it into an Internal error
+func recovered(ctx context.Context, l logger.FieldLogger, method string, p interface{}) error {
+	if zl, ok := l.(logger.Logger); ok {
+		l = zl.Ctx(ctx)
+	}
+	l.Errorw("gRPC handler panicked",
+		"panic", fmt.Sprint(p),
+		"stack", string(debug.Stack()),
+		"grpc.method", method,
+	)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// UnaryRecovery returns Internal to the caller when the handler panics
+func UnaryRecovery(l logger.FieldLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ctx, l, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery returns Internal to the caller when the handler panics
+func StreamRecovery(l logger.FieldLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), l, info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,94 @@
+// Package health serves liveness/readiness endpoints backed by named checks
+// that components such as the Kafka consumer register.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Checker reports the health of one component; a nil error means healthy
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// Handler runs the registered checks and reports the aggregated status
+type Handler struct {
+	mu      sync.RWMutex
+	checks  map[string]Checker
+	timeout time.Duration
+}
+
+// NewHandler creates a health handler; each request runs all checks within timeout
+func NewHandler(timeout time.Duration) *Handler {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Handler{checks: make(map[string]Checker), timeout: timeout}
+}
+
+// Register adds or replaces the check called name
+func (h *Handler) Register(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = c
+}
+
+// Response is the JSON body returned by the handler
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Run executes every check and reports whether all of them passed
+func (h *Handler) Run(ctx context.Context) (Response, bool) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	checks := make(map[string]Checker, len(h.checks))
+	for name, c := range h.checks {
+		checks[name] = c
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	resp := Response{Status: "ok", Checks: make(map[string]string, len(names))}
+	healthy := true
+	for _, name := range names {
+		if err := checks[name].Check(ctx); err != nil {
+			resp.Checks[name] = err.Error()
+			healthy = false
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	if !healthy {
+		resp.Status = "fail"
+	}
+	return resp, healthy
+}
+
+// ServeHTTP responds 200 when all checks pass and 503 otherwise
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, healthy := h.Run(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,150 @@
+// Package ids generates correlation IDs for requests and messages.
+package ids
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// Supported ID formats
+const (
+	FormatUUIDv7    = "uuidv7"    // Time-ordered RFC 9562 UUID (default)
+	FormatULID      = "ulid"      // 26 character Crockford base32, lexically sortable
+	FormatSnowflake = "snowflake" // 64-bit time/node/sequence integer, printed in decimal
+)
+
+// Generator produces unique IDs
+type Generator interface {
+	NewID() string
+}
+
+// GeneratorFunc adapts a function to the Generator interface
+type GeneratorFunc func() string
+
+// NewID calls f
+func (f GeneratorFunc) NewID() string { return f() }
+
+// NodeEnv names the environment variable holding the snowflake node number,
+// 0 to 1023, which must differ between all processes generating IDs at once,
+// e.g. a StatefulSet ordinal
+const NodeEnv = "SNOWFLAKE_NODE"
+
+// New returns the generator for format; an empty format selects UUIDv7.
+// The snowflake format takes its node from NodeEnv and fails without it.
+func New(format string) (Generator, error) {
+	switch strings.ToLower(format) {
+	case "", FormatUUIDv7:
+		return GeneratorFunc(newUUIDv7), nil
+	case FormatULID:
+		return GeneratorFunc(func() string { return ulid.Make().String() }), nil
+	case FormatSnowflake:
+		node, err := envNode()
+		if err != nil {
+			return nil, err
+		}
+		return NewSnowflake(node), nil
+	}
+	return nil, fmt.Errorf("unknown id format %q", format)
+}
+
+var defaultGenerator atomic.Value // Generator
+
+func init() {
+	defaultGenerator.Store(Generator(GeneratorFunc(newUUIDv7)))
+}
+
+// SetDefault replaces the generator used by NewID
+func SetDefault(g Generator) {
+	defaultGenerator.Store(g)
+}
+
+// NewID returns an ID from the default generator
+func NewID() string {
+	return defaultGenerator.Load().(Generator).NewID()
+}
+
+func newUUIDv7() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString() // Random source failed for v7, fall back to v4
+	}
+	return id.String()
+}
+
+// Snowflake layout: 41 bits of milliseconds since epoch, 10 bits of node and
+// 12 bits of per-millisecond sequence
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	maxNode      = 1<<nodeBits - 1
+	maxSequence  = 1<<sequenceBits - 1
+)
+
+// snowflakeEpoch is 2024-01-01T00:00:00Z, which leaves room until ~2093
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates 64-bit time-ordered IDs unique per node
+type Snowflake struct {
+	mu       sync.Mutex
+	node     int64
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake creates a snowflake generator; node is masked to 10 bits
+func NewSnowflake(node int64) *Snowflake {
+	return &Snowflake{node: node & maxNode}
+}
+
+// NewID returns the next ID as a decimal string
+func (s *Snowflake) NewID() string {
+	return fmt.Sprintf("%d", s.Next())
+}
+
+// Next returns the next ID, waiting for the next millisecond when the
+// sequence is exhausted
+func (s *Snowflake) Next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Since(snowflakeEpoch).Milliseconds()
+	if now < s.lastMs {
+		now = s.lastMs // Clock went backwards; stay monotonic
+	}
+	if now == s.lastMs {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			for now <= s.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.lastMs = now
+	return now<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence
+}
+
+// envNode reads the snowflake node from NodeEnv. There is no fallback: a
+// node derived from the hostname collides within a few dozen pods, and
+// colliding nodes generate duplicate IDs.
+func envNode() (int64, error) {
+	v := os.Getenv(NodeEnv)
+	if v == "" {
+		return 0, fmt.Errorf("snowflake ids need a node number unique per process in %s", NodeEnv)
+	}
+	node, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || node < 0 || node > maxNode {
+		return 0, fmt.Errorf("%s must be a number from 0 to %d, got %q", NodeEnv, maxNode, v)
+	}
+	return node, nil
+}
@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// AuditedConsumer wraps a Consumer and logs every commit: the partition,
+// the range of offsets it covers, how long the handler held the committed
+// message and which delivery attempt it was. Log to a dedicated sink, e.g.
+// logger.New(logger.WithSink(auditFile)), to keep a record of what was
+// processed when. Commits made by auto commit are not seen.
+type AuditedConsumer struct {
+	Consumer
+	log   logger.FieldLogger
+	group string
+
+	mu    sync.Mutex
+	reads map[TopicPartition]map[int64]time.Time // Uncommitted offsets and when they were read
+}
+
+// NewAuditedConsumer wraps c; pass the returned value to Consume in place of c
+func NewAuditedConsumer(c Consumer, log logger.FieldLogger, group string) *AuditedConsumer {
+	return &AuditedConsumer{Consumer: c, log: log, group: group, reads: make(map[TopicPartition]map[int64]time.Time)}
+}
+
+// ReadMessage reads from the wrapped consumer and records when msg was read
+func (a *AuditedConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	msg, err := a.Consumer.ReadMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tp := TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+	a.mu.Lock()
+	offsets, ok := a.reads[tp]
+	if !ok {
+		offsets = make(map[int64]time.Time)
+		a.reads[tp] = offsets
+	}
+	offsets[msg.Offset] = time.Now()
+	a.mu.Unlock()
+	return msg, nil
+}
+
+// CommitMessage commits msg and logs the audit record
+func (a *AuditedConsumer) CommitMessage(ctx context.Context, msg *Message) error {
+	err := a.Consumer.CommitMessage(ctx, msg)
+	now := time.Now()
+
+	// The commit covers every offset up to msg read since the last commit
+	tp := TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+	from, latency := msg.Offset, time.Duration(0)
+	a.mu.Lock()
+	if offsets := a.reads[tp]; offsets != nil {
+		if readAt, ok := offsets[msg.Offset]; ok {
+			latency = now.Sub(readAt)
+		}
+		if err == nil {
+			for o := range offsets {
+				if o <= msg.Offset {
+					from = min(from, o)
+					delete(offsets, o)
+				}
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	kv := []interface{}{
+		"group", a.group,
+		"topic", msg.Topic,
+		"partition", msg.Partition,
+		"offset_from", from,
+		"offset_to", msg.Offset,
+		"handler_latency_ms", latency.Milliseconds(),
+		"attempt", msg.RetryCount() + 1,
+	}
+	if err != nil {
+		a.log.Warnw("kafka commit failed", append(kv, "error", err)...)
+		return err
+	}
+	a.log.Infow("kafka commit", kv...)
+	return nil
+}
+
+// Unwrap returns the wrapped consumer
+func (a *AuditedConsumer) Unwrap() Consumer { return a.Consumer }
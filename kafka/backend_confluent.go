@@ -0,0 +1,409 @@
+//go:build cgo && !kafka_noconfluent
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func init() {
+	RegisterBackend("confluent", confluentBackend{})
+}
+
+// confluentBackend uses confluent-kafka-go (librdkafka, requires CGO)
+type confluentBackend struct{}
+
+// configMap translates the Config into librdkafka properties
+func (c *Config) configMap() *ckafka.ConfigMap {
+	configMap := &ckafka.ConfigMap{
+		"bootstrap.servers": c.BootstrapServers,
+		"security.protocol": c.SecurityProtocol,
+	}
+	if c.SASLMechanism != "" {
+		configMap.SetKey("sasl.mechanism", c.SASLMechanism)
+		configMap.SetKey("sasl.username", c.SASLUsername)
+		configMap.SetKey("sasl.password", c.SASLPassword)
+	}
+	if c.SSLTruststoreLocation != "" {
+		configMap.SetKey("ssl.ca.location", c.SSLTruststoreLocation)
+	}
+	if c.SSLCertLocation != "" {
+		configMap.SetKey("ssl.certificate.location", c.SSLCertLocation)
+		configMap.SetKey("ssl.key.location", c.SSLKeyLocation)
+	}
+	return configMap
+}
+
+func (confluentBackend) NewProducer(cfg *Config) (Producer, error) {
+	partition, err := cfg.partitioner()
+	if err != nil {
+		return nil, err
+	}
+	codec, err := cfg.compression()
+	if err != nil {
+		return nil, err
+	}
+	configMap := cfg.configMap()
+	configMap.SetKey("compression.type", codec)
+	configMap.SetKey("linger.ms", int(cfg.linger().Milliseconds()))
+	configMap.SetKey("batch.size", cfg.batchBytes())
+	if cfg.DisableIdempotence {
+		configMap.SetKey("enable.idempotence", false)
+	} else {
+		// Idempotence needs acks=all and at most 5 in-flight requests; retries stay unlimited within message.timeout.ms
+		configMap.SetKey("enable.idempotence", true)
+		configMap.SetKey("acks", "all")
+		configMap.SetKey("max.in.flight.requests.per.connection", 5)
+	}
+	if cfg.Partitioner == PartitionerMurmur2 && cfg.PartitionFunc == nil {
+		configMap.SetKey("partitioner", "murmur2_random") // Native Java-compatible hashing
+		partition = nil
+	}
+
+	p, err := ckafka.NewProducer(configMap)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delivery reports go to per-message channels; anything left on the
+	// events channel is a client-level error worth logging.
+	go func() {
+		for e := range p.Events() {
+			if kerr, ok := e.(ckafka.Error); ok {
+				log.Printf("Producer error: %v\n", kerr)
+			}
+		}
+	}()
+	return &confluentProducer{producer: p, partition: partition, partitions: make(map[string]int)}, nil
+}
+
+func (confluentBackend) NewConsumer(cfg *Config, topics []string) (Consumer, error) {
+	configMap := cfg.configMap()
+	configMap.SetKey("group.id", cfg.GroupID)
+	configMap.SetKey("enable.auto.commit", cfg.EnableAutoCommit)
+	if cfg.AutoOffsetReset != "" {
+		configMap.SetKey("auto.offset.reset", cfg.AutoOffsetReset)
+	}
+
+	start, err := cfg.startFrom()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := ckafka.NewConsumer(configMap)
+	if err != nil {
+		return nil, err
+	}
+	cc := &confluentConsumer{consumer: c, start: start}
+	if err := c.SubscribeTopics(topics, cc.onRebalance); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to subscribe to topics %s: %w", strings.Join(topics, ","), err)
+	}
+	return cc, nil
+}
+
+type confluentProducer struct {
+	producer  *ckafka.Producer
+	partition PartitionerFunc // Partitions in Go when librdkafka has no equivalent
+
+	mu         sync.Mutex
+	partitions map[string]int // Partition count per topic
+}
+
+// partitionCount returns the cached partition count of topic
+func (p *confluentProducer) partitionCount(topic string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n, ok := p.partitions[topic]; ok {
+		return n, nil
+	}
+	md, err := p.producer.GetMetadata(&topic, false, metadataTimeoutMs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metadata for %s: %w", topic, err)
+	}
+	n := len(md.Topics[topic].Partitions)
+	if n == 0 {
+		return 0, fmt.Errorf("topic %s has no partitions", topic)
+	}
+	p.partitions[topic] = n
+	return n, nil
+}
+
+func (p *confluentProducer) Produce(ctx context.Context, msg *Message) error {
+	// Buffered so a late delivery report after ctx cancellation never blocks librdkafka
+	deliveryChan := make(chan ckafka.Event, 1)
+
+	km := &ckafka.Message{
+		TopicPartition: ckafka.TopicPartition{Topic: &msg.Topic, Partition: ckafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+	}
+	if !msg.Timestamp.IsZero() {
+		km.Timestamp = msg.Timestamp
+	}
+	if p.partition != nil {
+		n, err := p.partitionCount(msg.Topic)
+		if err != nil {
+			return err
+		}
+		km.TopicPartition.Partition = p.partition(msg, n)
+	}
+	for _, h := range msg.Headers {
+		km.Headers = append(km.Headers, ckafka.Header{Key: h.Key, Value: h.Value})
+	}
+
+	if err := p.producer.Produce(km, deliveryChan); err != nil {
+		return newProduceError(msg.Topic, err)
+	}
+
+	select {
+	case e := <-deliveryChan:
+		m, ok := e.(*ckafka.Message)
+		if !ok {
+			return fmt.Errorf("unexpected delivery event: %v", e)
+		}
+		if m.TopicPartition.Error != nil {
+			return newProduceError(msg.Topic, m.TopicPartition.Error)
+		}
+		msg.Partition = m.TopicPartition.Partition
+		msg.Offset = int64(m.TopicPartition.Offset)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err() // Propagate context cancellation
+	}
+}
+
+func (p *confluentProducer) Close() error {
+	// Wait for outstanding deliveries before shutting down
+	p.producer.Flush(int((5 * time.Second).Milliseconds()))
+	p.producer.Close()
+	return nil
+}
+
+type confluentConsumer struct {
+	consumer *ckafka.Consumer
+	start    startPosition
+	started  bool // First assignment done; rebalance callbacks run on the polling goroutine
+
+	mu        sync.Mutex
+	revokeFns []func([]TopicPartition)
+}
+
+// OnRevoke registers fn to run on the reading goroutine before partitions
+// are unassigned
+func (c *confluentConsumer) OnRevoke(fn func(revoked []TopicPartition)) {
+	c.mu.Lock()
+	c.revokeFns = append(c.revokeFns, fn)
+	c.mu.Unlock()
+}
+
+// onRebalance reports revocations and positions the partitions of the first
+// assignment at the configured start; later assignments keep the committed
+// offsets
+func (c *confluentConsumer) onRebalance(kc *ckafka.Consumer, ev ckafka.Event) error {
+	if revoked, ok := ev.(ckafka.RevokedPartitions); ok {
+		tps := make([]TopicPartition, 0, len(revoked.Partitions))
+		for _, tp := range revoked.Partitions {
+			tps = append(tps, TopicPartition{Topic: *tp.Topic, Partition: tp.Partition})
+		}
+		c.mu.Lock()
+		fns := c.revokeFns
+		c.mu.Unlock()
+		for _, fn := range fns {
+			fn(tps)
+		}
+		return nil // The client applies the revocation itself
+	}
+	assigned, ok := ev.(ckafka.AssignedPartitions)
+	if !ok || c.started || c.start.committed() {
+		return nil // The client applies the assignment itself
+	}
+	c.started = true
+
+	parts := assigned.Partitions
+	switch c.start.mode {
+	case StartFromEarliest:
+		for i := range parts {
+			parts[i].Offset = ckafka.OffsetBeginning
+		}
+	case StartFromLatest:
+		for i := range parts {
+			parts[i].Offset = ckafka.OffsetEnd
+		}
+	case StartFromTimestamp:
+		for i := range parts {
+			parts[i].Offset = ckafka.Offset(c.start.at.UnixMilli())
+		}
+		resolved, err := kc.OffsetsForTimes(parts, metadataTimeoutMs)
+		if err != nil {
+			// Errors returned from the callback are dropped by the client
+			log.Printf("Failed to look up offsets for %s, using committed offsets: %v", c.start.at.Format(time.RFC3339), err)
+			return nil
+		}
+		parts = resolved // Partitions without later messages resolve to the end
+	}
+	log.Printf("Starting %d partitions from %s", len(parts), c.start.mode)
+	if kc.GetRebalanceProtocol() == "COOPERATIVE" {
+		return kc.IncrementalAssign(parts)
+	}
+	return kc.Assign(parts)
+}
+
+// pollInterval bounds each blocking read so context cancellation is noticed
+const pollInterval = 100 * time.Millisecond
+
+func (c *confluentConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		km, err := c.consumer.ReadMessage(pollInterval)
+		if err != nil {
+			if kerr, ok := err.(ckafka.Error); ok && kerr.Code() == ckafka.ErrTimedOut {
+				continue
+			}
+			return nil, err
+		}
+
+		msg := &Message{
+			Topic:     *km.TopicPartition.Topic,
+			Partition: km.TopicPartition.Partition,
+			Offset:    int64(km.TopicPartition.Offset),
+			Key:       km.Key,
+			Value:     km.Value,
+			Timestamp: km.Timestamp,
+			raw:       km,
+		}
+		for _, h := range km.Headers {
+			msg.Headers = append(msg.Headers, Header{Key: h.Key, Value: h.Value})
+		}
+		return msg, nil
+	}
+}
+
+func (c *confluentConsumer) CommitMessage(ctx context.Context, msg *Message) error {
+	topic := msg.Topic
+	_, err := c.consumer.CommitOffsets([]ckafka.TopicPartition{{
+		Topic:     &topic,
+		Partition: msg.Partition,
+		Offset:    ckafka.Offset(msg.Offset + 1),
+	}})
+	return err
+}
+
+func (c *confluentConsumer) Seek(ctx context.Context, tp TopicPartition, offset int64) error {
+	topic := tp.Topic
+	return c.consumer.Seek(ckafka.TopicPartition{Topic: &topic, Partition: tp.Partition, Offset: ckafka.Offset(offset)}, 0)
+}
+
+func (c *confluentConsumer) Pause(tps ...TopicPartition) error {
+	return c.consumer.Pause(confluentPartitions(tps))
+}
+
+func (c *confluentConsumer) Resume(tps ...TopicPartition) error {
+	return c.consumer.Resume(confluentPartitions(tps))
+}
+
+func confluentPartitions(tps []TopicPartition) []ckafka.TopicPartition {
+	parts := make([]ckafka.TopicPartition, len(tps))
+	for i, tp := range tps {
+		topic := tp.Topic
+		parts[i] = ckafka.TopicPartition{Topic: &topic, Partition: tp.Partition}
+	}
+	return parts
+}
+
+// GroupLag sums the lag of every partition of the subscribed topics from
+// the group's committed offsets; partitions without a commit count from the
+// low watermark
+func (c *confluentConsumer) GroupLag(ctx context.Context) (int64, error) {
+	topics, err := c.consumer.Subscription()
+	if err != nil {
+		return 0, err
+	}
+	var parts []ckafka.TopicPartition
+	for _, topic := range topics {
+		md, err := c.consumer.GetMetadata(&topic, false, metadataTimeoutMs)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get metadata for %s: %w", topic, err)
+		}
+		for _, p := range md.Topics[topic].Partitions {
+			t := topic
+			parts = append(parts, ckafka.TopicPartition{Topic: &t, Partition: p.ID})
+		}
+	}
+	committed, err := c.consumer.Committed(parts, metadataTimeoutMs)
+	if err != nil {
+		return 0, err
+	}
+	var lag int64
+	for _, tp := range committed {
+		low, high, err := c.consumer.QueryWatermarkOffsets(*tp.Topic, tp.Partition, metadataTimeoutMs)
+		if err != nil {
+			return 0, err
+		}
+		from := int64(tp.Offset)
+		if from < 0 {
+			from = low
+		}
+		if high > from {
+			lag += high - from
+		}
+	}
+	return lag, nil
+}
+
+func (c *confluentConsumer) Close() error {
+	return c.consumer.Close()
+}
+
+// metadataTimeoutMs bounds the admin calls made for health checks
+const metadataTimeoutMs = 5000
+
+func (c *confluentConsumer) Ping(ctx context.Context) error {
+	_, err := c.consumer.GetMetadata(nil, false, metadataTimeoutMs)
+	return err
+}
+
+func (c *confluentConsumer) Assigned() []TopicPartition {
+	tps, err := c.consumer.Assignment()
+	if err != nil {
+		return nil
+	}
+	assigned := make([]TopicPartition, 0, len(tps))
+	for _, tp := range tps {
+		assigned = append(assigned, TopicPartition{Topic: *tp.Topic, Partition: tp.Partition})
+	}
+	return assigned
+}
+
+func (c *confluentConsumer) Lag(ctx context.Context) ([]PartitionLag, error) {
+	tps, err := c.consumer.Assignment()
+	if err != nil {
+		return nil, err
+	}
+	positions, err := c.consumer.Position(tps)
+	if err != nil {
+		return nil, err
+	}
+	lags := make([]PartitionLag, 0, len(positions))
+	for _, tp := range positions {
+		// Cached watermarks from the last fetch; no broker round trip
+		_, high, err := c.consumer.GetWatermarkOffsets(*tp.Topic, tp.Partition)
+		if err != nil || tp.Offset < 0 {
+			continue
+		}
+		lags = append(lags, PartitionLag{
+			TopicPartition: TopicPartition{Topic: *tp.Topic, Partition: tp.Partition},
+			Lag:            high - int64(tp.Offset),
+		})
+	}
+	return lags, nil
+}
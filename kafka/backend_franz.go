@@ -0,0 +1,425 @@
+//go:build !kafka_nofranz
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+func init() {
+	RegisterBackend("franz", franzBackend{})
+	retriableCheckers = append(retriableCheckers, kerr.IsRetriable)
+	fatalCheckers = append(fatalCheckers, franzFatal)
+}
+
+// franzFatal matches the errors after which franz-go stops producing
+func franzFatal(err error) bool {
+	return errors.Is(err, kerr.ProducerFenced) ||
+		errors.Is(err, kerr.InvalidProducerEpoch) ||
+		errors.Is(err, kerr.OutOfOrderSequenceNumber) ||
+		errors.Is(err, kerr.UnknownProducerID) ||
+		errors.Is(err, kerr.ClusterAuthorizationFailed) ||
+		errors.Is(err, kerr.TransactionalIDAuthorizationFailed)
+}
+
+// franzBackend uses twmb/franz-go (pure Go)
+type franzBackend struct{}
+
+// franzOpts translates the Config into franz-go client options
+func (c *Config) franzOpts() ([]kgo.Opt, error) {
+	opts := []kgo.Opt{kgo.SeedBrokers(c.Brokers()...)}
+
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		opts = append(opts, kgo.DialTLSConfig(tlsCfg))
+	}
+
+	if c.useSASL() {
+		mech, err := c.franzSASL()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mech))
+	}
+	return opts, nil
+}
+
+func (c *Config) franzSASL() (sasl.Mechanism, error) {
+	switch strings.ToUpper(c.SASLMechanism) {
+	case "", "PLAIN":
+		return plain.Auth{User: c.SASLUsername, Pass: c.SASLPassword}.AsMechanism(), nil
+	case "SCRAM-SHA-256":
+		return scram.Auth{User: c.SASLUsername, Pass: c.SASLPassword}.AsSha256Mechanism(), nil
+	case "SCRAM-SHA-512":
+		return scram.Auth{User: c.SASLUsername, Pass: c.SASLPassword}.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism %q", c.SASLMechanism)
+	}
+}
+
+func (franzBackend) NewProducer(cfg *Config) (Producer, error) {
+	opts, err := cfg.franzOpts()
+	if err != nil {
+		return nil, err
+	}
+	partitioner, err := cfg.franzPartitioner()
+	if err != nil {
+		return nil, err
+	}
+	if partitioner != nil {
+		opts = append(opts, kgo.RecordPartitioner(partitioner))
+	}
+	if cfg.DisableIdempotence {
+		opts = append(opts, kgo.DisableIdempotentWrite()) // franz-go is idempotent with acks=all by default
+	}
+	codec, err := cfg.franzCompression()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts,
+		kgo.ProducerBatchCompression(codec),
+		kgo.ProducerLinger(cfg.linger()),
+		kgo.ProducerBatchMaxBytes(int32(cfg.batchBytes())),
+	)
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &franzProducer{client: cl}, nil
+}
+
+func (c *Config) franzCompression() (kgo.CompressionCodec, error) {
+	codec, err := c.compression()
+	if err != nil {
+		return kgo.NoCompression(), err
+	}
+	switch codec {
+	case CompressionGzip:
+		return kgo.GzipCompression(), nil
+	case CompressionSnappy:
+		return kgo.SnappyCompression(), nil
+	case CompressionLZ4:
+		return kgo.Lz4Compression(), nil
+	case CompressionZstd:
+		return kgo.ZstdCompression(), nil
+	}
+	return kgo.NoCompression(), nil
+}
+
+// franzPartitioner maps the configured partitioner, preferring the native
+// franz-go ones, which handle batching and unavailable partitions
+func (c *Config) franzPartitioner() (kgo.Partitioner, error) {
+	fn, err := c.partitioner()
+	if err != nil || fn == nil {
+		return nil, err
+	}
+	if c.PartitionFunc == nil {
+		switch c.Partitioner {
+		case PartitionerSticky:
+			return kgo.StickyKeyPartitioner(nil), nil // Kafka murmur2 compatible by default
+		case PartitionerRoundRobin:
+			return kgo.RoundRobinPartitioner(), nil
+		}
+	}
+	return kgo.BasicConsistentPartitioner(func(topic string) func(*kgo.Record, int) int {
+		return func(r *kgo.Record, n int) int {
+			return int(fn(&Message{Topic: topic, Key: r.Key, Value: r.Value}, n))
+		}
+	}), nil
+}
+
+func (franzBackend) NewConsumer(cfg *Config, topics []string) (Consumer, error) {
+	opts, err := cfg.franzOpts()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, kgo.ConsumerGroup(cfg.GroupID), kgo.ConsumeTopics(topics...))
+	if !cfg.EnableAutoCommit {
+		opts = append(opts, kgo.DisableAutoCommit())
+	}
+	if cfg.AutoOffsetReset == "earliest" {
+		opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	} else {
+		opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()))
+	}
+
+	start, err := cfg.startFrom()
+	if err != nil {
+		return nil, err
+	}
+	if !start.committed() {
+		opts = append(opts, kgo.AdjustFetchOffsetsFn(startAdjuster(start)))
+	}
+
+	fc := &franzConsumer{group: cfg.GroupID, state: make(map[TopicPartition]*franzPartition)}
+	opts = append(opts,
+		kgo.OnPartitionsAssigned(fc.onAssigned),
+		kgo.OnPartitionsRevoked(fc.onRevoked),
+		kgo.OnPartitionsLost(fc.onRevoked),
+	)
+
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	fc.client = cl
+	return fc, nil
+}
+
+// startAdjuster replaces the fetched offsets of the first assignment with
+// the configured start; later assignments keep the committed offsets
+func startAdjuster(start startPosition) func(context.Context, map[string]map[int32]kgo.Offset) (map[string]map[int32]kgo.Offset, error) {
+	var started atomic.Bool
+	return func(_ context.Context, offsets map[string]map[int32]kgo.Offset) (map[string]map[int32]kgo.Offset, error) {
+		if !started.CompareAndSwap(false, true) {
+			return offsets, nil
+		}
+		offset := kgo.NewOffset().WithEpoch(-1) // No data loss detection against the old position
+		switch start.mode {
+		case StartFromEarliest:
+			offset = offset.AtStart()
+		case StartFromLatest:
+			offset = offset.AtEnd()
+		case StartFromTimestamp:
+			offset = offset.AfterMilli(start.at.UnixMilli())
+		}
+		n := 0
+		for _, partitions := range offsets {
+			for p := range partitions {
+				partitions[p] = offset
+				n++
+			}
+		}
+		log.Printf("Starting %d partitions from %s", n, start.mode)
+		return offsets, nil
+	}
+}
+
+type franzProducer struct {
+	client *kgo.Client
+}
+
+func (p *franzProducer) Produce(ctx context.Context, msg *Message) error {
+	rec := &kgo.Record{Topic: msg.Topic, Key: msg.Key, Value: msg.Value, Timestamp: msg.Timestamp}
+	for _, h := range msg.Headers {
+		rec.Headers = append(rec.Headers, kgo.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	if err := p.client.ProduceSync(ctx, rec).FirstErr(); err != nil {
+		return newProduceError(msg.Topic, err)
+	}
+	msg.Partition = rec.Partition
+	msg.Offset = rec.Offset
+	return nil
+}
+
+func (p *franzProducer) Close() error {
+	p.client.Close()
+	return nil
+}
+
+type franzConsumer struct {
+	client  *kgo.Client
+	group   string
+	pending []*kgo.Record // Records fetched but not yet returned
+	errs    []error       // Fetch errors of the last poll, reported once its records are returned
+
+	mu        sync.Mutex
+	state     map[TopicPartition]*franzPartition // Assigned partitions
+	revokeFns []func([]TopicPartition)
+}
+
+// franzPartition tracks the offsets of an assigned partition for lag reporting
+type franzPartition struct {
+	highWatermark int64
+	next          int64 // Offset after the last returned record, -1 until known
+}
+
+func (c *franzConsumer) onAssigned(_ context.Context, _ *kgo.Client, assigned map[string][]int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic, partitions := range assigned {
+		for _, p := range partitions {
+			c.state[TopicPartition{Topic: topic, Partition: p}] = &franzPartition{next: -1}
+		}
+	}
+}
+
+func (c *franzConsumer) onRevoked(_ context.Context, _ *kgo.Client, revoked map[string][]int32) {
+	var tps []TopicPartition
+	c.mu.Lock()
+	for topic, partitions := range revoked {
+		for _, p := range partitions {
+			tp := TopicPartition{Topic: topic, Partition: p}
+			delete(c.state, tp)
+			tps = append(tps, tp)
+		}
+	}
+	fns := c.revokeFns
+	c.mu.Unlock()
+	for _, fn := range fns {
+		fn(tps)
+	}
+}
+
+// OnRevoke registers fn to run before partitions are revoked or after they
+// are lost, on the client's group goroutine
+func (c *franzConsumer) OnRevoke(fn func(revoked []TopicPartition)) {
+	c.mu.Lock()
+	c.revokeFns = append(c.revokeFns, fn)
+	c.mu.Unlock()
+}
+
+func (c *franzConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	for len(c.pending) == 0 {
+		if len(c.errs) > 0 {
+			err := c.errs[0]
+			c.errs = c.errs[1:]
+			return nil, err
+		}
+		fetches := c.client.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// The client has moved past the records of this poll whatever else
+		// failed, so they are returned before the errors
+		c.pending = fetches.Records()
+		for _, e := range fetches.Errors() {
+			c.errs = append(c.errs, fmt.Errorf("fetch %s[%d]: %w", e.Topic, e.Partition, e.Err))
+		}
+
+		c.mu.Lock()
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			if st, ok := c.state[TopicPartition{Topic: p.Topic, Partition: p.Partition}]; ok {
+				st.highWatermark = p.HighWatermark
+			}
+		})
+		c.mu.Unlock()
+	}
+
+	rec := c.pending[0]
+	c.pending = c.pending[1:]
+
+	c.mu.Lock()
+	if st, ok := c.state[TopicPartition{Topic: rec.Topic, Partition: rec.Partition}]; ok {
+		st.next = rec.Offset + 1
+	}
+	c.mu.Unlock()
+	msg := &Message{
+		Topic:     rec.Topic,
+		Partition: rec.Partition,
+		Offset:    rec.Offset,
+		Key:       rec.Key,
+		Value:     rec.Value,
+		Timestamp: rec.Timestamp,
+		raw:       rec,
+	}
+	for _, h := range rec.Headers {
+		msg.Headers = append(msg.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+	return msg, nil
+}
+
+func (c *franzConsumer) CommitMessage(ctx context.Context, msg *Message) error {
+	rec, ok := msg.raw.(*kgo.Record)
+	if !ok {
+		rec = &kgo.Record{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset, LeaderEpoch: -1}
+	}
+	return c.client.CommitRecords(ctx, rec)
+}
+
+func (c *franzConsumer) Seek(ctx context.Context, tp TopicPartition, offset int64) error {
+	c.client.SetOffsets(map[string]map[int32]kgo.EpochOffset{
+		tp.Topic: {tp.Partition: {Epoch: -1, Offset: offset}},
+	})
+	// Drop already fetched records of the partition so reads restart at offset
+	kept := c.pending[:0]
+	for _, rec := range c.pending {
+		if rec.Topic != tp.Topic || rec.Partition != tp.Partition {
+			kept = append(kept, rec)
+		}
+	}
+	c.pending = kept
+	return nil
+}
+
+func (c *franzConsumer) Pause(tps ...TopicPartition) error {
+	c.client.PauseFetchPartitions(franzPartitions(tps))
+	return nil
+}
+
+func (c *franzConsumer) Resume(tps ...TopicPartition) error {
+	c.client.ResumeFetchPartitions(franzPartitions(tps))
+	return nil
+}
+
+func franzPartitions(tps []TopicPartition) map[string][]int32 {
+	m := make(map[string][]int32, len(tps))
+	for _, tp := range tps {
+		m[tp.Topic] = append(m[tp.Topic], tp.Partition)
+	}
+	return m
+}
+
+func (c *franzConsumer) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx)
+}
+
+func (c *franzConsumer) Assigned() []TopicPartition {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assigned := make([]TopicPartition, 0, len(c.state))
+	for tp := range c.state {
+		assigned = append(assigned, tp)
+	}
+	return assigned
+}
+
+func (c *franzConsumer) Lag(ctx context.Context) ([]PartitionLag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lags := make([]PartitionLag, 0, len(c.state))
+	for tp, st := range c.state {
+		if st.next < 0 {
+			continue
+		}
+		lags = append(lags, PartitionLag{TopicPartition: tp, Lag: st.highWatermark - st.next})
+	}
+	return lags, nil
+}
+
+// GroupLag sums the lag of every partition of the group from its committed offsets
+func (c *franzConsumer) GroupLag(ctx context.Context) (int64, error) {
+	lags, err := kadm.NewClient(c.client).Lag(ctx, c.group)
+	if err != nil {
+		return 0, err
+	}
+	l, ok := lags[c.group]
+	if !ok {
+		return 0, fmt.Errorf("no lag reported for group %s", c.group)
+	}
+	if err := l.Error(); err != nil {
+		return 0, err
+	}
+	return l.Lag.Total(), nil
+}
+
+func (c *franzConsumer) Close() error {
+	c.client.Close()
+	return nil
+}
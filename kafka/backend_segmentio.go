@@ -0,0 +1,293 @@
+//go:build !kafka_nosegmentio
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	skafka "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+func init() {
+	RegisterBackend("segmentio", segmentioBackend{})
+}
+
+// segmentioBackend uses segmentio/kafka-go (pure Go)
+type segmentioBackend struct{}
+
+func (c *Config) segmentioSASL() (sasl.Mechanism, error) {
+	switch strings.ToUpper(c.SASLMechanism) {
+	case "", "PLAIN":
+		return plain.Mechanism{Username: c.SASLUsername, Password: c.SASLPassword}, nil
+	case "SCRAM-SHA-256":
+		return scram.Mechanism(scram.SHA256, c.SASLUsername, c.SASLPassword)
+	case "SCRAM-SHA-512":
+		return scram.Mechanism(scram.SHA512, c.SASLUsername, c.SASLPassword)
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism %q", c.SASLMechanism)
+	}
+}
+
+// segmentioDialer builds the dialer used by readers
+func (c *Config) segmentioDialer() (*skafka.Dialer, error) {
+	dialer := &skafka.Dialer{Timeout: 10 * time.Second, DualStack: true}
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer.TLS = tlsCfg
+	if c.useSASL() {
+		if dialer.SASLMechanism, err = c.segmentioSASL(); err != nil {
+			return nil, err
+		}
+	}
+	return dialer, nil
+}
+
+// segmentioTransport builds the transport used by writers
+func (c *Config) segmentioTransport() (*skafka.Transport, error) {
+	transport := &skafka.Transport{}
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLS = tlsCfg
+	if c.useSASL() {
+		if transport.SASL, err = c.segmentioSASL(); err != nil {
+			return nil, err
+		}
+	}
+	return transport, nil
+}
+
+func (c *Config) segmentioCompression() (skafka.Compression, error) {
+	codec, err := c.compression()
+	if err != nil {
+		return 0, err
+	}
+	switch codec {
+	case CompressionGzip:
+		return skafka.Gzip, nil
+	case CompressionSnappy:
+		return skafka.Snappy, nil
+	case CompressionLZ4:
+		return skafka.Lz4, nil
+	case CompressionZstd:
+		return skafka.Zstd, nil
+	}
+	return 0, nil
+}
+
+// segmentioBalancer maps the configured partitioner onto a kafka-go balancer
+func (c *Config) segmentioBalancer() (skafka.Balancer, error) {
+	fn, err := c.partitioner()
+	if err != nil {
+		return nil, err
+	}
+	if fn == nil {
+		return &skafka.Hash{}, nil
+	}
+	if c.PartitionFunc == nil {
+		switch c.Partitioner {
+		case PartitionerMurmur2:
+			return &skafka.Murmur2Balancer{}, nil
+		case PartitionerRoundRobin:
+			return &skafka.RoundRobin{}, nil
+		}
+	}
+	return skafka.BalancerFunc(func(km skafka.Message, partitions ...int) int {
+		msg := &Message{Topic: km.Topic, Key: km.Key, Value: km.Value}
+		return partitions[int(fn(msg, len(partitions)))%len(partitions)]
+	}), nil
+}
+
+func (segmentioBackend) NewProducer(cfg *Config) (Producer, error) {
+	transport, err := cfg.segmentioTransport()
+	if err != nil {
+		return nil, err
+	}
+	balancer, err := cfg.segmentioBalancer()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.DisableIdempotence {
+		log.Printf("kafka-go has no idempotent producer; retries may duplicate messages")
+	}
+	codec, err := cfg.segmentioCompression()
+	if err != nil {
+		return nil, err
+	}
+	w := &skafka.Writer{
+		Addr:         skafka.TCP(cfg.Brokers()...),
+		Balancer:     balancer,
+		Compression:  codec,
+		BatchTimeout: cfg.linger(),
+		BatchBytes:   int64(cfg.batchBytes()),
+		RequiredAcks: skafka.RequireAll,
+		Transport:    transport,
+		Completion:   segmentioCompletion,
+	}
+	return &segmentioProducer{writer: w}, nil
+}
+
+func (segmentioBackend) NewConsumer(cfg *Config, topics []string) (Consumer, error) {
+	start, err := cfg.startFrom()
+	if err != nil {
+		return nil, err
+	}
+	if !start.committed() {
+		// kafka-go readers in a group cannot be repositioned
+		return nil, fmt.Errorf("start position %q is not supported by the segmentio backend", cfg.StartFrom)
+	}
+	dialer, err := cfg.segmentioDialer()
+	if err != nil {
+		return nil, err
+	}
+	rc := skafka.ReaderConfig{
+		Brokers:     cfg.Brokers(),
+		GroupID:     cfg.GroupID,
+		GroupTopics: topics,
+		Dialer:      dialer,
+		StartOffset: skafka.LastOffset,
+	}
+	if cfg.AutoOffsetReset == "earliest" {
+		rc.StartOffset = skafka.FirstOffset
+	}
+	if cfg.EnableAutoCommit {
+		rc.CommitInterval = 5 * time.Second
+	}
+	return &segmentioConsumer{
+		reader:     skafka.NewReader(rc),
+		autoCommit: cfg.EnableAutoCommit,
+		dialer:     dialer,
+		brokers:    rc.Brokers,
+		seen:       make(map[TopicPartition]struct{}),
+	}, nil
+}
+
+type segmentioProducer struct {
+	writer *skafka.Writer
+}
+
+func (p *segmentioProducer) Produce(ctx context.Context, msg *Message) error {
+	km := skafka.Message{Topic: msg.Topic, Key: msg.Key, Value: msg.Value, Time: msg.Timestamp, WriterData: msg}
+	for _, h := range msg.Headers {
+		km.Headers = append(km.Headers, skafka.Header{Key: h.Key, Value: h.Value})
+	}
+	if err := p.writer.WriteMessages(ctx, km); err != nil {
+		return newProduceError(msg.Topic, err)
+	}
+	return nil
+}
+
+func (p *segmentioProducer) Close() error {
+	return p.writer.Close()
+}
+
+// segmentioCompletion fills in the partition and offset of delivered
+// messages; the writer works on copies, so the original rides in
+// WriterData. WriteMessages returns only after the callback has run.
+func segmentioCompletion(kms []skafka.Message, err error) {
+	if err != nil {
+		return
+	}
+	for _, km := range kms {
+		if msg, ok := km.WriterData.(*Message); ok {
+			msg.Partition, msg.Offset = int32(km.Partition), km.Offset
+		}
+	}
+}
+
+type segmentioConsumer struct {
+	reader     *skafka.Reader
+	autoCommit bool
+	dialer     *skafka.Dialer
+	brokers    []string
+
+	mu   sync.Mutex
+	seen map[TopicPartition]struct{} // kafka-go does not expose the group assignment
+}
+
+func (c *segmentioConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	var (
+		km  skafka.Message
+		err error
+	)
+	if c.autoCommit {
+		km, err = c.reader.ReadMessage(ctx)
+	} else {
+		km, err = c.reader.FetchMessage(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.seen[TopicPartition{Topic: km.Topic, Partition: int32(km.Partition)}] = struct{}{}
+	c.mu.Unlock()
+
+	msg := &Message{
+		Topic:     km.Topic,
+		Partition: int32(km.Partition),
+		Offset:    km.Offset,
+		Key:       km.Key,
+		Value:     km.Value,
+		Timestamp: km.Time,
+	}
+	for _, h := range km.Headers {
+		msg.Headers = append(msg.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+	return msg, nil
+}
+
+func (c *segmentioConsumer) CommitMessage(ctx context.Context, msg *Message) error {
+	return c.reader.CommitMessages(ctx, skafka.Message{
+		Topic:     msg.Topic,
+		Partition: int(msg.Partition),
+		Offset:    msg.Offset,
+	})
+}
+
+func (c *segmentioConsumer) Ping(ctx context.Context) error {
+	var lastErr error
+	for _, broker := range c.brokers {
+		conn, err := c.dialer.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// Assigned approximates the assignment with the partitions read from so far
+func (c *segmentioConsumer) Assigned() []TopicPartition {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assigned := make([]TopicPartition, 0, len(c.seen))
+	for tp := range c.seen {
+		assigned = append(assigned, tp)
+	}
+	return assigned
+}
+
+// Lag reports the reader's aggregate lag; kafka-go does not break it down per partition
+func (c *segmentioConsumer) Lag(ctx context.Context) ([]PartitionLag, error) {
+	stats := c.reader.Stats()
+	if stats.Lag < 0 {
+		return nil, nil
+	}
+	return []PartitionLag{{TopicPartition: TopicPartition{Topic: stats.Topic, Partition: -1}, Lag: stats.Lag}}, nil
+}
+
+func (c *segmentioConsumer) Close() error {
+	return c.reader.Close()
+}
@@ -0,0 +1,172 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Checkpoint is the stored position of a consumer group on one partition
+type Checkpoint struct {
+	TopicPartition
+	Offset    int64             // Next offset to consume
+	Metadata  map[string]string // Application data saved with the offset, e.g. the source cluster
+	UpdatedAt time.Time
+}
+
+// CheckpointStore keeps consumer offsets outside of Kafka
+type CheckpointStore interface {
+	// Load returns the checkpoint of group on tp; ok is false when none was saved
+	Load(ctx context.Context, group string, tp TopicPartition) (cp Checkpoint, ok bool, err error)
+	// Save stores cp for group, replacing the previous checkpoint
+	Save(ctx context.Context, group string, cp Checkpoint) error
+}
+
+// Seeker is implemented by consumers that can move the fetch position of an
+// assigned partition
+type Seeker interface {
+	Seek(ctx context.Context, tp TopicPartition, offset int64) error
+}
+
+// RevokeNotifier is implemented by consumers that report partitions leaving
+// the assignment, so state kept per partition can be dropped. Callbacks run
+// before the partitions are given up and may block to finish work on them.
+type RevokeNotifier interface {
+	OnRevoke(fn func(revoked []TopicPartition))
+}
+
+// CheckpointMode selects whether Kafka commits are still made
+type CheckpointMode int
+
+const (
+	// CheckpointAlongside saves to the store and then commits to Kafka
+	CheckpointAlongside CheckpointMode = iota
+	// CheckpointOnly saves to the store only; run with EnableAutoCommit off
+	CheckpointOnly
+)
+
+// CheckpointConfig configures NewCheckpointedConsumer
+type CheckpointConfig struct {
+	Store    CheckpointStore
+	Group    string                               // Key under which checkpoints are saved, usually the group ID
+	Mode     CheckpointMode                       // Default CheckpointAlongside
+	Metadata func(msg *Message) map[string]string // Optional metadata saved with each checkpoint
+}
+
+// CheckpointedConsumer resumes each partition from its stored checkpoint and
+// saves a new checkpoint on every commit. Partitions are repositioned with
+// Seek when the backend supports it; otherwise messages before the
+// checkpoint are skipped as they are read. Checkpoints are loaded again when
+// a partition comes back after a revocation, since another member may have
+// moved it on; backends without RevokeNotifier keep the first one loaded.
+type CheckpointedConsumer struct {
+	Consumer
+	cfg CheckpointConfig
+
+	mu     sync.Mutex               // Commits may come from concurrent handlers
+	resume map[TopicPartition]int64 // Next offset to deliver per partition, once loaded
+}
+
+// NewCheckpointedConsumer wraps c; pass the returned value to Consume in place of c
+func NewCheckpointedConsumer(c Consumer, cfg CheckpointConfig) *CheckpointedConsumer {
+	cc := &CheckpointedConsumer{Consumer: c, cfg: cfg, resume: make(map[TopicPartition]int64)}
+	if n, ok := unwrapConsumer[RevokeNotifier](c); ok {
+		n.OnRevoke(cc.forget)
+	}
+	return cc
+}
+
+// forget drops the cached positions of revoked partitions
+func (c *CheckpointedConsumer) forget(revoked []TopicPartition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tp := range revoked {
+		delete(c.resume, tp)
+	}
+}
+
+// ReadMessage returns the next message at or after the partition's checkpoint
+func (c *CheckpointedConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	for {
+		msg, err := c.Consumer.ReadMessage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tp := TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+
+		c.mu.Lock()
+		next, loaded := c.resume[tp]
+		c.mu.Unlock()
+		if !loaded {
+			cp, ok, err := c.cfg.Store.Load(ctx, c.cfg.Group, tp)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load checkpoint for %s[%d]: %w", tp.Topic, tp.Partition, err)
+			}
+			next = -1
+			if ok {
+				next = cp.Offset
+			}
+			c.mu.Lock()
+			c.resume[tp] = next
+			c.mu.Unlock()
+
+			if next > msg.Offset {
+				if s, ok := unwrapConsumer[Seeker](c.Consumer); ok {
+					if err := s.Seek(ctx, tp, next); err != nil {
+						return nil, fmt.Errorf("failed to seek %s[%d] to %d: %w", tp.Topic, tp.Partition, next, err)
+					}
+					continue
+				}
+			}
+		}
+		if msg.Offset < next {
+			continue // Already checkpointed
+		}
+		return msg, nil
+	}
+}
+
+// CommitMessage saves the checkpoint after msg and, unless the mode is
+// CheckpointOnly, commits it to Kafka too
+func (c *CheckpointedConsumer) CommitMessage(ctx context.Context, msg *Message) error {
+	cp := Checkpoint{
+		TopicPartition: TopicPartition{Topic: msg.Topic, Partition: msg.Partition},
+		Offset:         msg.Offset + 1,
+		UpdatedAt:      time.Now(),
+	}
+	if c.cfg.Metadata != nil {
+		cp.Metadata = c.cfg.Metadata(msg)
+	}
+	if err := c.cfg.Store.Save(ctx, c.cfg.Group, cp); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	c.mu.Lock()
+	c.resume[cp.TopicPartition] = cp.Offset
+	c.mu.Unlock()
+
+	if c.cfg.Mode == CheckpointOnly {
+		return nil
+	}
+	return c.Consumer.CommitMessage(ctx, msg)
+}
+
+// Unwrap returns the wrapped consumer
+func (c *CheckpointedConsumer) Unwrap() Consumer { return c.Consumer }
+
+// unwrapConsumer finds the first consumer in a chain of wrappers that
+// implements T, so optional interfaces survive wrapping
+func unwrapConsumer[T any](c Consumer) (T, bool) {
+	for c != nil {
+		if t, ok := c.(T); ok {
+			return t, true
+		}
+		u, ok := c.(interface{ Unwrap() Consumer })
+		if !ok {
+			break
+		}
+		c = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
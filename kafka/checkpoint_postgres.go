@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CheckpointTableSchema creates the table used by PostgresCheckpointStore;
+// %s is replaced by the table name
+const CheckpointTableSchema = `CREATE TABLE IF NOT EXISTS %s (
+	group_id   TEXT        NOT NULL,
+	topic      TEXT        NOT NULL,
+	partition  INTEGER     NOT NULL,
+	"offset"   BIGINT      NOT NULL,
+	metadata   JSONB,
+	updated_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (group_id, topic, partition)
+)`
+
+// PostgresCheckpointStore keeps checkpoints in a Postgres table, which lets
+// them be updated in the same database the handler writes to
+type PostgresCheckpointStore struct {
+	pool  *pgxpool.Pool
+	table string
+}
+
+// NewPostgresCheckpointStore creates a store on pool; table defaults to "kafka_checkpoints"
+func NewPostgresCheckpointStore(pool *pgxpool.Pool, table string) *PostgresCheckpointStore {
+	if table == "" {
+		table = "kafka_checkpoints"
+	}
+	return &PostgresCheckpointStore{pool: pool, table: pgx.Identifier{table}.Sanitize()}
+}
+
+// CreateTable creates the checkpoint table if it does not exist
+func (s *PostgresCheckpointStore) CreateTable(ctx context.Context) error {
+	if _, err := s.pool.Exec(ctx, fmt.Sprintf(CheckpointTableSchema, s.table)); err != nil {
+		return fmt.Errorf("failed to create checkpoint table: %w", err)
+	}
+	return nil
+}
+
+// Load reads the checkpoint of group on tp
+func (s *PostgresCheckpointStore) Load(ctx context.Context, group string, tp TopicPartition) (Checkpoint, bool, error) {
+	cp := Checkpoint{TopicPartition: tp}
+	err := s.pool.QueryRow(ctx,
+		`SELECT "offset", metadata, updated_at FROM `+s.table+` WHERE group_id = $1 AND topic = $2 AND partition = $3`,
+		group, tp.Topic, tp.Partition,
+	).Scan(&cp.Offset, &cp.Metadata, &cp.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	return cp, true, nil
+}
+
+// Save upserts cp for group
+func (s *PostgresCheckpointStore) Save(ctx context.Context, group string, cp Checkpoint) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO `+s.table+` (group_id, topic, partition, "offset", metadata, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (group_id, topic, partition)
+		DO UPDATE SET "offset" = EXCLUDED."offset", metadata = EXCLUDED.metadata, updated_at = EXCLUDED.updated_at`,
+		group, cp.Topic, cp.Partition, cp.Offset, cp.Metadata, cp.UpdatedAt,
+	)
+	return err
+}
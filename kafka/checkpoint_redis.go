@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCheckpointStore keeps checkpoints in Redis hashes named
+// <prefix><group>:<topic>:<partition>
+type RedisCheckpointStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisCheckpointStore creates a store on client; prefix defaults to "kafka:checkpoint:"
+func NewRedisCheckpointStore(client redis.UniversalClient, prefix string) *RedisCheckpointStore {
+	if prefix == "" {
+		prefix = "kafka:checkpoint:"
+	}
+	return &RedisCheckpointStore{client: client, prefix: prefix}
+}
+
+func (s *RedisCheckpointStore) key(group string, tp TopicPartition) string {
+	return fmt.Sprintf("%s%s:%s:%d", s.prefix, group, tp.Topic, tp.Partition)
+}
+
+// Load reads the checkpoint of group on tp
+func (s *RedisCheckpointStore) Load(ctx context.Context, group string, tp TopicPartition) (Checkpoint, bool, error) {
+	h, err := s.client.HGetAll(ctx, s.key(group, tp)).Result()
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	if len(h) == 0 {
+		return Checkpoint{}, false, nil
+	}
+
+	cp := Checkpoint{TopicPartition: tp}
+	if cp.Offset, err = strconv.ParseInt(h["offset"], 10, 64); err != nil {
+		return Checkpoint{}, false, fmt.Errorf("invalid offset %q: %w", h["offset"], err)
+	}
+	if v := h["metadata"]; v != "" {
+		if err := json.Unmarshal([]byte(v), &cp.Metadata); err != nil {
+			return Checkpoint{}, false, fmt.Errorf("invalid metadata: %w", err)
+		}
+	}
+	if v := h["updated_at"]; v != "" {
+		cp.UpdatedAt, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	return cp, true, nil
+}
+
+// Save writes cp for group
+func (s *RedisCheckpointStore) Save(ctx context.Context, group string, cp Checkpoint) error {
+	metadata, err := json.Marshal(cp.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	return s.client.HSet(ctx, s.key(group, cp.TopicPartition),
+		"offset", cp.Offset,
+		"metadata", string(metadata),
+		"updated_at", cp.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	).Err()
+}
@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// memStore is an in-memory CheckpointStore
+type memStore struct {
+	mu  sync.Mutex
+	cps map[memKey]Checkpoint
+}
+
+type memKey struct {
+	group string
+	tp    TopicPartition
+}
+
+func (s *memStore) Load(_ context.Context, group string, tp TopicPartition) (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.cps[memKey{group, tp}]
+	return cp, ok, nil
+}
+
+func (s *memStore) Save(_ context.Context, group string, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cps == nil {
+		s.cps = make(map[memKey]Checkpoint)
+	}
+	s.cps[memKey{group, cp.TopicPartition}] = cp
+	return nil
+}
+
+func TestCheckpointedConsumerResume(t *testing.T) {
+	orders := TopicPartition{Topic: "orders", Partition: 0}
+	tests := []struct {
+		name       string
+		checkpoint int64 // -1 for none
+		seek       bool
+		offsets    []int64
+		want       int64
+		wantSeeks  int
+	}{
+		{name: "no checkpoint", checkpoint: -1, offsets: []int64{3, 4}, want: 3},
+		{name: "skips checkpointed", checkpoint: 5, offsets: []int64{3, 4, 5, 6}, want: 5},
+		{name: "seeks to checkpoint", checkpoint: 5, seek: true, offsets: []int64{3, 5}, want: 5, wantSeeks: 1},
+		{name: "checkpoint behind", checkpoint: 2, seek: true, offsets: []int64{3}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memStore{}
+			if tt.checkpoint >= 0 {
+				_ = store.Save(context.Background(), "g", Checkpoint{TopicPartition: orders, Offset: tt.checkpoint})
+			}
+			fc := &fakeConsumer{}
+			for _, o := range tt.offsets {
+				fc.queue = append(fc.queue, &Message{Topic: "orders", Offset: o})
+			}
+			var c Consumer = struct{ Consumer }{fc} // Hides Seek
+			if tt.seek {
+				c = fc
+			}
+			cc := NewCheckpointedConsumer(c, CheckpointConfig{Store: store, Group: "g"})
+
+			msg, err := cc.ReadMessage(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Offset != tt.want {
+				t.Errorf("first message at offset %d, want %d", msg.Offset, tt.want)
+			}
+			if len(fc.seeks) != tt.wantSeeks {
+				t.Errorf("seeks = %v, want %d", fc.seeks, tt.wantSeeks)
+			}
+		})
+	}
+}
+
+func TestCheckpointedConsumerCommit(t *testing.T) {
+	for _, mode := range []CheckpointMode{CheckpointAlongside, CheckpointOnly} {
+		store := &memStore{}
+		fc := &fakeConsumer{}
+		cc := NewCheckpointedConsumer(fc, CheckpointConfig{
+			Store:    store,
+			Group:    "g",
+			Mode:     mode,
+			Metadata: func(msg *Message) map[string]string { return map[string]string{"source": "eu"} },
+		})
+		msg := &Message{Topic: "orders", Partition: 1, Offset: 41}
+		if err := cc.CommitMessage(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+
+		cp, ok, _ := store.Load(context.Background(), "g", TopicPartition{Topic: "orders", Partition: 1})
+		if !ok || cp.Offset != 42 || cp.Metadata["source"] != "eu" {
+			t.Errorf("mode %d: checkpoint = %+v, want offset 42 with the metadata", mode, cp)
+		}
+		if committed := len(fc.committed) == 1; committed != (mode == CheckpointAlongside) {
+			t.Errorf("mode %d: committed to Kafka = %v", mode, committed)
+		}
+
+		// A message redelivered after the commit is skipped
+		fc.queue = []*Message{{Topic: "orders", Partition: 1, Offset: 41}, {Topic: "orders", Partition: 1, Offset: 42}}
+		if next, err := cc.ReadMessage(context.Background()); err != nil || next.Offset != 42 {
+			t.Errorf("mode %d: read %v, %v after the commit, want offset 42", mode, next, err)
+		}
+	}
+}
+
+func TestCheckpointedConsumerReloadsAfterRevoke(t *testing.T) {
+	store := &memStore{}
+	orders := TopicPartition{Topic: "orders", Partition: 0}
+	fc := &fakeConsumer{queue: []*Message{{Topic: "orders", Offset: 1}}}
+	cc := NewCheckpointedConsumer(struct{ Consumer }{fc}, CheckpointConfig{Store: store, Group: "g"})
+	if _, err := cc.ReadMessage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another member moved the partition on while it was away
+	_ = store.Save(context.Background(), "g", Checkpoint{TopicPartition: orders, Offset: 10})
+	cc.forget([]TopicPartition{orders})
+	fc.queue = []*Message{{Topic: "orders", Offset: 2}, {Topic: "orders", Offset: 10}}
+	if msg, err := cc.ReadMessage(context.Background()); err != nil || msg.Offset != 10 {
+		t.Errorf("read %v, %v after the revocation, want offset 10", msg, err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package cloudevent maps CloudEvents 1.0 to and from Kafka messages
+// following the Kafka protocol binding, in structured and binary content mode.
+//
+// The binding names attribute headers ce_<attribute>; headers spelled
+// ce-<attribute>, as sent by some HTTP bridges, are accepted on read.
+package cloudevent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/upendravikram5/upendra/kafka"
+)
+
+// Mode selects how an event is laid out in a message
+type Mode int
+
+const (
+	// Binary puts attributes in ce_ headers and the data as the value
+	Binary Mode = iota
+	// Structured puts the whole event, as JSON, in the value
+	Structured
+)
+
+// ContentTypeStructured is the content type of structured mode messages
+const ContentTypeStructured = "application/cloudevents+json"
+
+// PartitionKeyExtension holds the message key, per the partitioning extension
+const PartitionKeyExtension = "partitionkey"
+
+const headerContentType = "content-type"
+
+// ToMessage converts e to a message in the given mode; the partitionkey
+// extension, if set, becomes the message key
+func ToMessage(e event.Event, mode Mode) (*kafka.Message, error) {
+	if err := e.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cloudevent: %w", err)
+	}
+	msg := &kafka.Message{}
+	if key, ok := e.Extensions()[PartitionKeyExtension]; ok {
+		msg.Key = []byte(fmt.Sprint(key))
+	}
+
+	if mode == Structured {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode cloudevent: %w", err)
+		}
+		msg.Value = value
+		msg.SetHeader(headerContentType, []byte(ContentTypeStructured))
+		return msg, nil
+	}
+
+	msg.Value = e.Data()
+	msg.SetHeader("ce_specversion", []byte(e.SpecVersion()))
+	msg.SetHeader("ce_id", []byte(e.ID()))
+	msg.SetHeader("ce_source", []byte(e.Source()))
+	msg.SetHeader("ce_type", []byte(e.Type()))
+	if !e.Time().IsZero() {
+		msg.SetHeader("ce_time", []byte(e.Time().UTC().Format(time.RFC3339Nano)))
+	}
+	if v := e.Subject(); v != "" {
+		msg.SetHeader("ce_subject", []byte(v))
+	}
+	if v := e.DataSchema(); v != "" {
+		msg.SetHeader("ce_dataschema", []byte(v))
+	}
+	if v := e.DataContentType(); v != "" {
+		msg.SetHeader(headerContentType, []byte(v))
+	}
+	for name, v := range e.Extensions() {
+		msg.SetHeader("ce_"+name, []byte(fmt.Sprint(v)))
+	}
+	return msg, nil
+}
+
+// FromMessage converts msg to an event, detecting the content mode from the
+// content-type header
+func FromMessage(msg *kafka.Message) (*event.Event, error) {
+	contentType, _ := msg.Header(headerContentType)
+	if strings.HasPrefix(string(contentType), "application/cloudevents") {
+		e := event.New()
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			return nil, fmt.Errorf("failed to decode structured cloudevent: %w", err)
+		}
+		return &e, nil
+	}
+
+	e := event.New()
+	found := false
+	for _, h := range msg.Headers {
+		name, ok := attributeName(h.Key)
+		if !ok {
+			continue
+		}
+		found = true
+		v := string(h.Value)
+		switch name {
+		case "specversion":
+			e.SetSpecVersion(v)
+		case "id":
+			e.SetID(v)
+		case "source":
+			e.SetSource(v)
+		case "type":
+			e.SetType(v)
+		case "subject":
+			e.SetSubject(v)
+		case "dataschema":
+			e.SetDataSchema(v)
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ce_time %q: %w", v, err)
+			}
+			e.SetTime(t)
+		default:
+			e.SetExtension(name, v)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("message at %s[%d]@%d is not a cloudevent", msg.Topic, msg.Partition, msg.Offset)
+	}
+	if len(contentType) > 0 {
+		e.SetDataContentType(string(contentType))
+	}
+	if msg.Value != nil {
+		e.DataEncoded = msg.Value
+	}
+	if err := e.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cloudevent: %w", err)
+	}
+	return &e, nil
+}
+
+// attributeName strips the ce_ (or ce-) prefix from a header key
+func attributeName(key string) (string, bool) {
+	if len(key) <= 3 || !strings.EqualFold(key[:2], "ce") || (key[2] != '_' && key[2] != '-') {
+		return "", false
+	}
+	return strings.ToLower(key[3:]), true
+}
+
+// Publish produces e to topic in the given mode
+func Publish(ctx context.Context, p kafka.Producer, topic string, e event.Event, mode Mode) error {
+	msg, err := ToMessage(e, mode)
+	if err != nil {
+		return err
+	}
+	msg.Topic = topic
+	return p.Produce(ctx, msg)
+}
+
+// Handler adapts a function taking events to kafka.Handler
+func Handler(next func(ctx context.Context, e event.Event) error) kafka.Handler {
+	return func(ctx context.Context, msg *kafka.Message) error {
+		e, err := FromMessage(msg)
+		if err != nil {
+			return err
+		}
+		return next(ctx, *e)
+	}
+}
@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Compression codecs accepted by Config.Compression.
+//
+// Trade-offs, roughly: lz4 compresses fast with moderate ratio and is the
+// default; zstd gives the best ratio (often 20-30% smaller than lz4) for a
+// little more producer CPU and needs brokers >= 2.1; snappy is close to lz4
+// but usually compresses less; gzip has a good ratio but is the most CPU
+// hungry and caps single-partition throughput. Compression works per batch,
+// so a longer linger and bigger batches improve the ratio at the cost of
+// latency.
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionLZ4    = "lz4"
+	CompressionZstd   = "zstd"
+)
+
+// Producer batching defaults
+const (
+	defaultCompression = CompressionLZ4
+	defaultLinger      = 5 * time.Millisecond // Adds at most 5ms per send, recovers most of the batching gain
+	defaultBatchBytes  = 1 << 20              // 1 MiB, the broker's default message.max.bytes
+)
+
+// compression returns the configured codec, defaulting to lz4
+func (c *Config) compression() (string, error) {
+	switch codec := strings.ToLower(c.Compression); codec {
+	case "":
+		return defaultCompression, nil
+	case CompressionNone, CompressionGzip, CompressionSnappy, CompressionLZ4, CompressionZstd:
+		return codec, nil
+	default:
+		return "", fmt.Errorf("unknown compression %q", c.Compression)
+	}
+}
+
+func (c *Config) linger() time.Duration {
+	if c.Linger > 0 {
+		return c.Linger
+	}
+	return defaultLinger
+}
+
+func (c *Config) batchBytes() int {
+	if c.BatchBytes > 0 {
+		return c.BatchBytes
+	}
+	return defaultBatchBytes
+}
@@ -0,0 +1,127 @@
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// Concurrency modes of Consume
+const (
+	ConcurrencySequential = "sequential" // One message at a time on the calling goroutine
+	ConcurrencyPartition  = "partition"  // One goroutine per assigned partition
+	ConcurrencyPool       = "pool"       // Workers goroutines, each owning a subset of the partitions
+	ConcurrencyPriority   = "priority"   // Workers goroutines taking from per-topic queues by TopicConfig.Weight
+)
+
+// laneBuffer is how many messages may queue for a lane before fetching blocks
+const laneBuffer = 16
+
+// consumeConcurrently fans messages out to lanes. A partition always maps to
+// the same lane, so messages of a partition are handled and committed in
+// order while partitions progress independently. Under "partition"
+// concurrency the lane of a revoked partition finishes what it holds and
+// stops before the partition is given up, when the backend reports
+// revocations; "pool" lanes are shared by partitions and keep running.
+func consumeConcurrently(ctx, hctx context.Context, c Consumer, cfg *Config, handler Handler) error {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type laneState struct {
+		ch   chan *Message
+		done chan struct{}
+	}
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex // Held while handing a message over, so a lane is not closed under the send
+		lanes = make(map[string]*laneState)
+	)
+	laneKey := func(topic string, partition int32) string {
+		key := topic + "/" + strconv.Itoa(int(partition))
+		if cfg.Concurrency == ConcurrencyPool {
+			h := fnv.New32a()
+			h.Write([]byte(key))
+			key = strconv.Itoa(int(h.Sum32() % uint32(workers)))
+		}
+		return key
+	}
+	lane := func(msg *Message) chan *Message {
+		key := laneKey(msg.Topic, msg.Partition)
+		l, ok := lanes[key]
+		if !ok {
+			l = &laneState{ch: make(chan *Message, laneBuffer), done: make(chan struct{})}
+			lanes[key] = l
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(l.done)
+				runLane(hctx, c, cfg, handler, l.ch)
+			}()
+		}
+		return l.ch
+	}
+	if n, ok := unwrapConsumer[RevokeNotifier](c); ok && cfg.Concurrency == ConcurrencyPartition {
+		n.OnRevoke(func(revoked []TopicPartition) {
+			var stopped []*laneState
+			mu.Lock()
+			for _, tp := range revoked {
+				key := laneKey(tp.Topic, tp.Partition)
+				if l, ok := lanes[key]; ok {
+					close(l.ch)
+					delete(lanes, key)
+					stopped = append(stopped, l)
+				}
+			}
+			mu.Unlock()
+			for _, l := range stopped {
+				<-l.done // Queued messages are handled and committed while still assigned
+			}
+		})
+	}
+	defer func() {
+		// Stop fetching and let every lane finish what it already has
+		mu.Lock()
+		for key, l := range lanes {
+			close(l.ch)
+			delete(lanes, key)
+		}
+		mu.Unlock()
+		wg.Wait()
+	}()
+
+	down := newOutage(cfg.Outage, "consumer")
+	for {
+		msg, err := c.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := down.failed(ctx, err); err != nil {
+				return err
+			}
+			continue
+		}
+		down.ok()
+		mu.Lock()
+		select {
+		case lane(msg) <- msg:
+			mu.Unlock()
+		case <-ctx.Done():
+			mu.Unlock()
+			return ctx.Err() // Not handed over, so neither handled nor committed
+		}
+	}
+}
+
+func runLane(ctx context.Context, c Consumer, cfg *Config, handler Handler, msgs <-chan *Message) {
+	for msg := range msgs {
+		if ctx.Err() != nil {
+			continue // Drain timeout passed; leave the rest uncommitted for redelivery
+		}
+		handle(ctx, c, cfg, handler, msg)
+	}
+}
@@ -0,0 +1,140 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/upendravikram5/upendra/config/env"
+)
+
+// Config represents the Kafka configuration
+type Config struct {
+	Backend                string          `env:"KAFKA_BACKEND" flag:"kafka.backend"`                                       // Client backend (e.g., "confluent", "franz", "segmentio"); empty picks the best available
+	BootstrapServers       string          `env:"KAFKA_BOOTSTRAP_SERVERS" flag:"kafka.brokers"`                             // Comma-separated list of broker addresses
+	SecurityProtocol       string          `env:"KAFKA_SECURITY_PROTOCOL,default=PLAINTEXT" flag:"kafka.security-protocol"` // PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL
+	SASLMechanism          string          `env:"KAFKA_SASL_MECHANISM" flag:"kafka.sasl-mechanism"`                         // PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512
+	SASLUsername           string          `env:"KAFKA_SASL_USERNAME" flag:"kafka.sasl-username"`
+	SASLPassword           string          `env:"KAFKA_SASL_PASSWORD"`
+	SASLPasswordFile       string          `env:"KAFKA_SASL_PASSWORD_FILE" flag:"kafka.sasl-password-file"`  // File with the SASL password, e.g. a mounted secret; read by each new client
+	SSLTruststoreLocation  string          `env:"KAFKA_SSL_TRUSTSTORE_LOCATION" flag:"kafka.ssl-truststore"` // Path to a PEM bundle with the broker CA certificates
+	SSLCertLocation        string          `env:"KAFKA_SSL_CERT_LOCATION" flag:"kafka.ssl-cert"`             // PEM client certificate for mutual TLS
+	SSLKeyLocation         string          `env:"KAFKA_SSL_KEY_LOCATION" flag:"kafka.ssl-key"`               // PEM private key of SSLCertLocation
+	GroupID                string          `env:"KAFKA_GROUP_ID" flag:"kafka.group-id"`
+	AutoOffsetReset        string          `env:"KAFKA_AUTO_OFFSET_RESET" flag:"kafka.auto-offset-reset"` // earliest or latest
+	EnableAutoCommit       bool            `env:"KAFKA_ENABLE_AUTO_COMMIT" flag:"kafka.auto-commit"`
+	OriginService          string          `env:"KAFKA_ORIGIN_SERVICE" flag:"kafka.origin-service"`   // Written as the origin-service header on produced messages
+	IDFormat               string          `env:"KAFKA_ID_FORMAT" flag:"kafka.id-format"`             // Correlation ID format: "uuidv7" (default), "ulid" or "snowflake", which needs ids.NodeEnv
+	HandlerTimeout         time.Duration   `env:"KAFKA_HANDLER_TIMEOUT" flag:"kafka.handler-timeout"` // Per-message deadline applied by Consume; 0 disables
+	DrainTimeout           time.Duration   `env:"KAFKA_DRAIN_TIMEOUT" flag:"kafka.drain-timeout"`     // How long in-flight handlers may run after shutdown starts (default 30s)
+	Concurrency            string          `env:"KAFKA_CONCURRENCY" flag:"kafka.concurrency"`         // "sequential" (default), "partition", "pool" or "priority"
+	Workers                int             `env:"KAFKA_WORKERS" flag:"kafka.workers"`                 // Pool size for "pool" and "priority" concurrency (default GOMAXPROCS)
+	Partitioner            string          `env:"KAFKA_PARTITIONER" flag:"kafka.partitioner"`         // "murmur2", "roundrobin" or "sticky"; empty keeps the backend default
+	PartitionFunc          PartitionerFunc // Custom partitioner; overrides Partitioner
+	Compression            string          `env:"KAFKA_COMPRESSION" flag:"kafka.compression"`                           // none, gzip, snappy, lz4 (default) or zstd
+	Linger                 time.Duration   `env:"KAFKA_LINGER" flag:"kafka.linger"`                                     // How long the producer waits to fill a batch (default 5ms)
+	BatchBytes             int             `env:"KAFKA_BATCH_BYTES" flag:"kafka.batch-bytes"`                           // Maximum batch size in bytes (default 1 MiB)
+	DisableIdempotence     bool            `env:"KAFKA_DISABLE_IDEMPOTENCE" flag:"kafka.disable-idempotence"`           // Opt out of the idempotent producer, e.g. for brokers without IDEMPOTENT_WRITE ACLs
+	StartFrom              string          `env:"KAFKA_START_FROM" flag:"kafka.start-from"`                             // Position on first assignment: committed (default), earliest, latest or timestamp=...
+	DisableProducerMetrics bool            `env:"KAFKA_DISABLE_PRODUCER_METRICS" flag:"kafka.disable-producer-metrics"` // Leave NewProducer's Prometheus metrics out
+
+	Filter      FilterConfig           // Pre-handler filters applied by Consume
+	Outage      OutageConfig           // Backoff and alerting while reads keep failing, e.g. with every broker down
+	Topics      map[string]TopicConfig `env:"KAFKA_TOPIC_OVERRIDES" flag:"kafka.topic-overrides"` // Per-topic overrides, keyed by topic name
+	Retry       RetryConfig            // Retry tiers and DLQ applied by Consume; subscribe to Retry.Topics() too
+	SchemaCheck SchemaCheckConfig      // Schema Registry validation of produced messages
+	Debug       DebugConfig            // Payload logging for NewDebugConsumer
+}
+
+// NewConfigFromEnv loads Kafka configuration from the environment
+// variables named in the env tags of Config
+func NewConfigFromEnv() (*Config, error) {
+	cfg := &Config{}
+	if err := env.Bind(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.checkRequired(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// checkRequired returns an error when fields needed to connect are missing
+func (c *Config) checkRequired() error {
+	// Basic validation of required fields
+	if c.BootstrapServers == "" {
+		return fmt.Errorf("bootstrap servers must be set")
+	}
+
+	// The SASL password must exist when SASL is enabled
+	if c.SASLMechanism != "" && c.SASLPassword == "" && c.SASLPasswordFile == "" {
+		return fmt.Errorf("SASL password cannot be empty when SASL mechanism is enabled")
+	}
+	return nil
+}
+
+// withPasswordFile returns c with SASLPassword read from SASLPasswordFile,
+// or c itself when no file is set
+func (c *Config) withPasswordFile() (*Config, error) {
+	if c.SASLPasswordFile == "" {
+		return c, nil
+	}
+	b, err := os.ReadFile(c.SASLPasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SASL password file: %w", err)
+	}
+	cp := *c
+	cp.SASLPassword = strings.TrimSpace(string(b))
+	return &cp, nil
+}
+
+// Brokers returns the bootstrap servers as a slice
+func (c *Config) Brokers() []string {
+	var brokers []string
+	for _, b := range strings.Split(c.BootstrapServers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	return brokers
+}
+
+// useTLS reports whether the security protocol requires TLS
+func (c *Config) useTLS() bool {
+	return strings.HasSuffix(strings.ToUpper(c.SecurityProtocol), "SSL")
+}
+
+// useSASL reports whether the security protocol requires SASL authentication
+func (c *Config) useSASL() bool {
+	return strings.HasPrefix(strings.ToUpper(c.SecurityProtocol), "SASL")
+}
+
+// tlsConfig builds the TLS configuration used by the pure-Go backends
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	if !c.useTLS() {
+		return nil, nil
+	}
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.SSLTruststoreLocation != "" {
+		pem, err := os.ReadFile(c.SSLTruststoreLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read truststore: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in truststore %s", c.SSLTruststoreLocation)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if c.SSLCertLocation != "" {
+		cert, err := tls.LoadX509KeyPair(c.SSLCertLocation, c.SSLKeyLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/logger/fields"
+)
+
+// DebugConfig controls the payload logging of DebugConsumer
+type DebugConfig struct {
+	Enabled    bool     `env:"KAFKA_DEBUG_PAYLOADS" flag:"kafka.debug-payloads"`
+	MaxBytes   int      `env:"KAFKA_DEBUG_MAX_BYTES" flag:"kafka.debug-max-bytes"` // Payload preview length (default 512)
+	RedactKeys []string `env:"KAFKA_DEBUG_REDACT_KEYS"`                            // JSON keys, at any depth, masked in previews
+}
+
+// DebugConsumer wraps a Consumer and logs every message it reads: the key,
+// the headers and a preview of the payload cut at MaxBytes, with the values
+// of RedactKeys masked when the payload is JSON. Binary keys, headers and
+// payloads are logged base64 encoded. Entries are written at info level, so
+// turning the mode on is enough to see them; it is meant for debugging and
+// logs every message, so leave it off in normal operation.
+type DebugConsumer struct {
+	Consumer
+	log     logger.FieldLogger
+	cfg     DebugConfig
+	redact  map[string]bool
+	enabled atomic.Bool
+}
+
+// NewDebugConsumer wraps c; pass the returned value to Consume in place of c
+func NewDebugConsumer(c Consumer, log logger.FieldLogger, cfg DebugConfig) *DebugConsumer {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 512
+	}
+	d := &DebugConsumer{Consumer: c, log: log, cfg: cfg, redact: make(map[string]bool)}
+	for _, k := range cfg.RedactKeys {
+		d.redact[strings.ToLower(k)] = true
+	}
+	d.enabled.Store(cfg.Enabled)
+	return d
+}
+
+// SetEnabled turns payload logging on or off at runtime
+func (d *DebugConsumer) SetEnabled(on bool) {
+	d.enabled.Store(on)
+}
+
+// ReadMessage reads from the wrapped consumer and logs msg when enabled
+func (d *DebugConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	msg, err := d.Consumer.ReadMessage(ctx)
+	if err != nil || !d.enabled.Load() {
+		return msg, err
+	}
+
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = printable(h.Value)
+	}
+	preview, truncated := d.preview(msg.Value)
+	d.log.Infow("kafka message",
+		"topic", msg.Topic,
+		"partition", msg.Partition,
+		"offset", msg.Offset,
+		"key", printable(msg.Key),
+		"headers", headers,
+		fields.Bytes("payload", len(msg.Value)),
+		"payload", preview,
+		"payload_truncated", truncated,
+	)
+	return msg, nil
+}
+
+// preview returns the payload as logged and whether it was cut
+func (d *DebugConsumer) preview(value []byte) (string, bool) {
+	if len(d.redact) > 0 {
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(value))
+		dec.UseNumber()
+		if dec.Decode(&v) == nil {
+			if masked, err := json.Marshal(d.mask(v)); err == nil {
+				value = masked
+			}
+		}
+	}
+	value = []byte(printable(value))
+	if len(value) <= d.cfg.MaxBytes {
+		return string(value), false
+	}
+	cut := d.cfg.MaxBytes
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return string(value[:cut]) + "...[" + strconv.Itoa(len(value)-cut) + " more bytes]", true
+}
+
+// mask replaces the values of the redacted keys in a decoded JSON value
+func (d *DebugConsumer) mask(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			if d.redact[strings.ToLower(k)] {
+				x[k] = "[REDACTED]"
+			} else {
+				x[k] = d.mask(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = d.mask(e)
+		}
+	}
+	return v
+}
+
+// printable returns b as text, or base64 encoded when it isn't UTF-8
+func printable(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return "base64:" + base64.StdEncoding.EncodeToString(b)
+}
+
+// Unwrap returns the wrapped consumer
+func (d *DebugConsumer) Unwrap() Consumer { return d.Consumer }
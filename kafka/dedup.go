@@ -0,0 +1,155 @@
+package kafka
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+var (
+	dedupMetricsOnce sync.Once
+
+	duplicatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "duplicates_total",
+		Help:      "Messages skipped because their dedup key was seen within the window, by original topic.",
+	}, []string{"topic"})
+)
+
+// DedupStore remembers dedup keys for a window
+type DedupStore interface {
+	// Seen reports whether key was marked within its window
+	Seen(ctx context.Context, key string) (bool, error)
+	// Mark records key for window
+	Mark(ctx context.Context, key string, window time.Duration) error
+}
+
+// DedupKeyFunc extracts the identity of a message; an empty key is never deduplicated
+type DedupKeyFunc func(msg *Message) string
+
+// DedupByCorrelationID uses the correlation-id header, which stays the same
+// when a producer resends the same Message
+func DedupByCorrelationID(msg *Message) string { return msg.CorrelationID() }
+
+// DedupByKey uses the record key, for topics with one message per key and event
+func DedupByKey(msg *Message) string { return string(msg.Key) }
+
+// DedupConfig configures Dedup
+type DedupConfig struct {
+	Store  DedupStore
+	Window time.Duration // How long keys are remembered (default 10m)
+	Key    DedupKeyFunc  // Default DedupByCorrelationID
+}
+
+// Dedup wraps next so that a message whose key was handled successfully
+// within the window is acknowledged without calling next. Keys are marked
+// only after next succeeds, so failed messages are retried as usual. Keys
+// are scoped by the original topic, so a message coming back from a retry
+// tier shares its key, and duplicates are counted under that topic too.
+// Store errors are logged and the message is handled.
+//
+// The check and the mark are not atomic: duplicates handled at the same time,
+// by lanes of one consumer or by two instances during a rebalance, both miss
+// the key and both run next. Dedup filters producer resends and redeliveries
+// that arrive after the first copy was handled; handlers that must never run
+// twice still need an idempotent write downstream.
+func Dedup(cfg DedupConfig, next Handler) Handler {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	if cfg.Key == nil {
+		cfg.Key = DedupByCorrelationID
+	}
+	dedupMetricsOnce.Do(func() { metrics.MustRegister(duplicatesTotal) })
+
+	return func(ctx context.Context, msg *Message) error {
+		id := cfg.Key(msg)
+		if id == "" {
+			return next(ctx, msg)
+		}
+		topic := baseTopic(msg)
+		key := topic + ":" + id
+
+		seen, err := cfg.Store.Seen(ctx, key)
+		if err != nil {
+			log.Printf("Dedup lookup failed for %s, handling message: %v", key, err)
+		} else if seen {
+			duplicatesTotal.WithLabelValues(topic).Inc()
+			return nil
+		}
+
+		if err := next(ctx, msg); err != nil {
+			return err
+		}
+		if err := cfg.Store.Mark(ctx, key, cfg.Window); err != nil {
+			log.Printf("Failed to record dedup key %s: %v", key, err)
+		}
+		return nil
+	}
+}
+
+// MemoryDedupStore is an in-process LRU of dedup keys. It only catches
+// duplicates handled by the same consumer instance, which holds for keyed
+// messages as long as partitions are not reassigned.
+type MemoryDedupStore struct {
+	size int
+
+	mu    sync.Mutex
+	order *list.List // Front is most recently marked
+	keys  map[string]*list.Element
+}
+
+type dedupEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryDedupStore keeps at most size keys (default 100000)
+func NewMemoryDedupStore(size int) *MemoryDedupStore {
+	if size <= 0 {
+		size = 100000
+	}
+	return &MemoryDedupStore{size: size, order: list.New(), keys: make(map[string]*list.Element)}
+}
+
+// Seen reports whether key was marked and has not expired
+func (s *MemoryDedupStore) Seen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.keys[key]
+	if !ok {
+		return false, nil
+	}
+	if time.Now().After(el.Value.(*dedupEntry).expires) {
+		s.order.Remove(el)
+		delete(s.keys, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Mark records key, evicting the least recently marked key when full
+func (s *MemoryDedupStore) Mark(_ context.Context, key string, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires := time.Now().Add(window)
+	if el, ok := s.keys[key]; ok {
+		el.Value.(*dedupEntry).expires = expires
+		s.order.MoveToFront(el)
+		return nil
+	}
+	s.keys[key] = s.order.PushFront(&dedupEntry{key: key, expires: expires})
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.keys, oldest.Value.(*dedupEntry).key)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisDedupStore keeps dedup keys in Redis as <prefix><key> with a TTL, so
+// duplicates are caught across consumer instances and rebalances
+type RedisDedupStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisDedupStore creates a store on client; prefix defaults to "kafka:dedup:"
+func NewRedisDedupStore(client redis.UniversalClient, prefix string) *RedisDedupStore {
+	if prefix == "" {
+		prefix = "kafka:dedup:"
+	}
+	return &RedisDedupStore{client: client, prefix: prefix}
+}
+
+// Seen reports whether key exists
+func (s *RedisDedupStore) Seen(ctx context.Context, key string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+key).Result()
+	return n > 0, err
+}
+
+// Mark sets key with the window as TTL
+func (s *RedisDedupStore) Mark(ctx context.Context, key string, window time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, 1, window).Err()
+}
@@ -0,0 +1,113 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const defaultDrainTimeout = 30 * time.Second
+
+// abortGrace is how long Stop waits for handlers to return once their
+// context is cancelled, before closing the consumer under them
+const abortGrace = 5 * time.Second
+
+func (c *Config) drainTimeout() time.Duration {
+	if c.DrainTimeout > 0 {
+		return c.DrainTimeout
+	}
+	return defaultDrainTimeout
+}
+
+// drainContext returns a context for handlers and commits that keeps the
+// values of ctx but is cancelled only timeout after ctx is done, so
+// shutdown doesn't interrupt a message halfway through
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	hctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-hctx.Done():
+			return
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-hctx.Done():
+		}
+	}()
+	return hctx, cancel
+}
+
+// Runner runs Consume as a lifecycle component. Stop stops fetching, waits
+// for in-flight messages to finish and be committed, then closes the consumer.
+type Runner struct {
+	consumer Consumer
+	cfg      *Config
+	handler  Handler
+	inFlight atomic.Int64
+
+	cancel context.CancelFunc
+	abort  context.CancelFunc // Cancels the handlers' context when Stop gives up waiting
+	done   chan error
+}
+
+// NewRunner creates a runner consuming from c with handler
+func NewRunner(c Consumer, cfg *Config, handler Handler) *Runner {
+	r := &Runner{consumer: c, cfg: cfg}
+	r.handler = func(ctx context.Context, msg *Message) error {
+		r.inFlight.Add(1)
+		defer r.inFlight.Add(-1)
+		return handler(ctx, msg)
+	}
+	return r
+}
+
+// InFlight returns the number of messages currently being handled
+func (r *Runner) InFlight() int64 {
+	return r.inFlight.Load()
+}
+
+// Start begins consuming in the background
+func (r *Runner) Start(ctx context.Context) error {
+	ctx, r.cancel = context.WithCancel(context.Background())
+	abort, cancelAbort := context.WithCancel(context.Background())
+	r.abort = cancelAbort
+	r.done = make(chan error, 1)
+	go func() { r.done <- consume(ctx, abort, r.consumer, r.cfg, r.handler) }()
+	return nil
+}
+
+// Stop drains in-flight messages for up to the configured drain timeout, or
+// until ctx is done, and closes the consumer. When ctx ends the drain early
+// the handlers' context is cancelled and Stop waits up to abortGrace more for
+// them to return, so the consumer isn't closed while they still commit.
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return r.consumer.Close()
+	}
+	r.cancel()
+	defer r.abort()
+
+	var err error
+	select {
+	case err = <-r.done:
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+	case <-ctx.Done():
+		log.Printf("Drain interrupted with %d messages in flight", r.InFlight())
+		err = ctx.Err()
+		r.abort()
+		select {
+		case <-r.done:
+		case <-time.After(abortGrace):
+			log.Printf("Closing the consumer with %d handlers still running", r.InFlight())
+		}
+	}
+	return errors.Join(err, r.consumer.Close())
+}
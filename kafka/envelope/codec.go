@@ -0,0 +1,75 @@
+package envelope
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+)
+
+// Content types written to the content-type header
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeAvro = "avro/binary"
+)
+
+// Codec converts envelopes to and from message values
+type Codec interface {
+	Marshal(env *Envelope) ([]byte, error)
+	Unmarshal(data []byte, env *Envelope) error
+	ContentType() string
+}
+
+// Available codecs
+var (
+	JSON Codec = jsonCodec{}
+	Avro Codec = avroCodec{}
+)
+
+// CodecFor returns the codec for a content type; empty selects JSON
+func CodecFor(contentType string) (Codec, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return JSON, nil
+	case ContentTypeAvro:
+		return Avro, nil
+	}
+	return nil, fmt.Errorf("no envelope codec for content type %q", contentType)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(env *Envelope) ([]byte, error) { return json.Marshal(env) }
+
+func (jsonCodec) Unmarshal(data []byte, env *Envelope) error { return json.Unmarshal(data, env) }
+
+func (jsonCodec) ContentType() string { return ContentTypeJSON }
+
+// AvroSchema is the Avro schema of the envelope; data holds the JSON payload as bytes
+const AvroSchema = `{
+	"type": "record",
+	"name": "Envelope",
+	"namespace": "com.upendravikram5.events",
+	"fields": [
+		{"name": "id", "type": "string"},
+		{"name": "type", "type": "string"},
+		{"name": "version", "type": "int"},
+		{"name": "source", "type": "string"},
+		{"name": "time", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+		{"name": "traceparent", "type": "string", "default": ""},
+		{"name": "tracestate", "type": "string", "default": ""},
+		{"name": "data", "type": "bytes"}
+	]
+}`
+
+var avroSchema = avro.MustParse(AvroSchema)
+
+type avroCodec struct{}
+
+func (avroCodec) Marshal(env *Envelope) ([]byte, error) { return avro.Marshal(avroSchema, env) }
+
+func (avroCodec) Unmarshal(data []byte, env *Envelope) error {
+	return avro.Unmarshal(avroSchema, data, env)
+}
+
+func (avroCodec) ContentType() string { return ContentTypeAvro }
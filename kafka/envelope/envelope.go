@@ -0,0 +1,119 @@
+// Package envelope defines the standard event envelope published on Kafka.
+// Every event carries the same metadata (id, type, version, source, time and
+// trace context) around a JSON payload, so consumers can route, trace and
+// upgrade events without knowing the producer.
+package envelope
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/upendravikram5/upendra/ids"
+	"github.com/upendravikram5/upendra/kafka"
+)
+
+// Envelope wraps an event payload with its metadata
+type Envelope struct {
+	ID          string          `json:"id" avro:"id"`                             // Unique event ID, used for deduplication
+	Type        string          `json:"type" avro:"type"`                         // Event name, e.g. "order.created"
+	Version     int             `json:"version" avro:"version"`                   // Payload schema version, starting at 1
+	Source      string          `json:"source" avro:"source"`                     // Producing service
+	Time        time.Time       `json:"time" avro:"time"`                         // When the event happened
+	TraceParent string          `json:"traceparent,omitempty" avro:"traceparent"` // W3C trace context of the producer
+	TraceState  string          `json:"tracestate,omitempty" avro:"tracestate"`
+	Data        json.RawMessage `json:"data" avro:"data"` // JSON payload
+}
+
+// Source is the default Envelope.Source set by New
+var Source string
+
+// New creates an envelope for data, encoded as JSON, with a fresh ID
+func New(eventType string, version int, data interface{}) (*Envelope, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event data: %w", err)
+	}
+	return &Envelope{
+		ID:      ids.NewID(),
+		Type:    eventType,
+		Version: version,
+		Source:  Source,
+		Time:    time.Now().UTC(),
+		Data:    raw,
+	}, nil
+}
+
+// Decode unmarshals the payload into v
+func (e *Envelope) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to decode %s v%d data: %w", e.Type, e.Version, err)
+	}
+	return nil
+}
+
+// InjectTrace copies the trace context of ctx into the envelope
+func (e *Envelope) InjectTrace(ctx context.Context) {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	e.TraceParent = carrier.Get("traceparent")
+	e.TraceState = carrier.Get("tracestate")
+}
+
+// Context returns ctx carrying the producer's trace context as the remote parent
+func (e *Envelope) Context(ctx context.Context) context.Context {
+	if e.TraceParent == "" {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{"traceparent": e.TraceParent}
+	if e.TraceState != "" {
+		carrier["tracestate"] = e.TraceState
+	}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
+
+// Publish encodes env with codec and produces it to topic under key. The
+// trace context of ctx is added unless env already has one.
+func Publish(ctx context.Context, p kafka.Producer, topic string, key []byte, env *Envelope, codec Codec) error {
+	if env.TraceParent == "" {
+		env.InjectTrace(ctx)
+	}
+	value, err := codec.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	msg := &kafka.Message{Topic: topic, Key: key, Value: value}
+	msg.SetContentType(codec.ContentType())
+	msg.SetCorrelationID(env.ID)
+	msg.SetSchemaVersion(env.Version) // Lets consumers dispatch with kafka.Versioned before decoding
+	return p.Produce(ctx, msg)
+}
+
+// FromMessage decodes the envelope in msg using the codec named by its
+// content-type header, defaulting to JSON
+func FromMessage(msg *kafka.Message) (*Envelope, error) {
+	codec, err := CodecFor(msg.ContentType())
+	if err != nil {
+		return nil, err
+	}
+	env := &Envelope{}
+	if err := codec.Unmarshal(msg.Value, env); err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	return env, nil
+}
+
+// Handler adapts a function taking envelopes to kafka.Handler. The handler
+// context carries the producer's trace context.
+func Handler(next func(ctx context.Context, env *Envelope) error) kafka.Handler {
+	return func(ctx context.Context, msg *kafka.Message) error {
+		env, err := FromMessage(msg)
+		if err != nil {
+			return err
+		}
+		return next(env.Context(ctx), env)
+	}
+}
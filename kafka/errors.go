@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProduceError is returned by Produce when a message could not be delivered.
+// Retriable errors may succeed if the message is sent again; fatal ones mean
+// the producer itself is unusable and must be closed and recreated.
+type ProduceError struct {
+	Topic     string
+	Err       error
+	Retriable bool
+	Fatal     bool
+}
+
+func newProduceError(topic string, err error) *ProduceError {
+	return &ProduceError{Topic: topic, Err: err, Retriable: IsRetriable(err), Fatal: IsFatal(err)}
+}
+
+func (e *ProduceError) Error() string {
+	return fmt.Sprintf("delivery to %s failed: %v", e.Topic, e.Err)
+}
+
+func (e *ProduceError) Unwrap() error { return e.Err }
+
+// IsRetriable reports whether sending the message again may succeed
+func (e *ProduceError) IsRetriable() bool { return e.Retriable }
+
+// IsFatal reports whether the producer must be recreated
+func (e *ProduceError) IsFatal() bool { return e.Fatal }
+
+// fatalCheckers are contributed by backends whose errors carry no fatal method
+var fatalCheckers []func(error) bool
+
+// IsFatal reports whether err leaves the client unusable, e.g. a fenced
+// idempotent producer or a lost sequence number
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+	var f interface{ IsFatal() bool }
+	if errors.As(err, &f) {
+		return f.IsFatal()
+	}
+	for _, check := range fatalCheckers {
+		if check(err) {
+			return true
+		}
+	}
+	return false
+}
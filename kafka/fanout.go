@@ -0,0 +1,194 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/upendravikram5/upendra/ids"
+)
+
+// HeaderFanoutID is shared by the copies of one fanned-out event, so a
+// consumer can recognise a copy published again after a partial failure
+const HeaderFanoutID = "fanout-id"
+
+// Fan-out defaults
+const (
+	defaultFanoutAttempts   = 3
+	defaultFanoutRetryDelay = 100 * time.Millisecond
+)
+
+// FanoutConfig configures a Fanout
+type FanoutConfig struct {
+	Topics     []string      // Destinations of Publish
+	Attempts   int           // Tries per destination while its error is retriable (default 3)
+	RetryDelay time.Duration // Wait between tries to one destination (default 100ms)
+}
+
+// Fanout publishes one event to several topics, e.g. a topic per tenant:
+//
+//	fan := kafka.NewFanout(producer, kafka.FanoutConfig{Topics: kafka.TenantTopics("orders.%s", tenants...)})
+//	res, err := fan.Publish(ctx, msg)
+//	if err != nil {
+//		// Later, or from an outbox: send only what is missing
+//		res, err = fan.PublishTo(ctx, msg, res.Failed()...)
+//	}
+//
+// Kafka has no transaction across the destinations here, so delivery is as
+// atomic as retries make it: every destination is sent to concurrently and
+// retried on its own, and when some still fail the error lists them while
+// the others keep their copy. The copies carry the same HeaderFanoutID.
+type Fanout struct {
+	producer Producer
+	cfg      FanoutConfig
+}
+
+// NewFanout creates a fan-out publisher sending through producer
+func NewFanout(producer Producer, cfg FanoutConfig) *Fanout {
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = defaultFanoutAttempts
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultFanoutRetryDelay
+	}
+	return &Fanout{producer: producer, cfg: cfg}
+}
+
+// TenantTopics formats pattern with each tenant, e.g.
+// TenantTopics("orders.%s", "acme", "globex") is orders.acme and orders.globex
+func TenantTopics(pattern string, tenants ...string) []string {
+	topics := make([]string, len(tenants))
+	for i, t := range tenants {
+		topics[i] = fmt.Sprintf(pattern, t)
+	}
+	return topics
+}
+
+// Delivery is the outcome of publishing to one destination
+type Delivery struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Attempts  int
+	Err       error // nil once delivered
+}
+
+// FanoutResult reports the delivery to each destination, in the order given
+type FanoutResult struct {
+	ID         string // The HeaderFanoutID of the copies
+	Deliveries []Delivery
+}
+
+// Delivered returns the topics that received their copy
+func (r FanoutResult) Delivered() []string {
+	var topics []string
+	for _, d := range r.Deliveries {
+		if d.Err == nil {
+			topics = append(topics, d.Topic)
+		}
+	}
+	return topics
+}
+
+// Failed returns the topics that did not receive their copy
+func (r FanoutResult) Failed() []string {
+	var topics []string
+	for _, d := range r.Deliveries {
+		if d.Err != nil {
+			topics = append(topics, d.Topic)
+		}
+	}
+	return topics
+}
+
+// FanoutError is returned when some destinations of a fan-out failed
+type FanoutError struct {
+	Result FanoutResult
+}
+
+func (e *FanoutError) Error() string {
+	var failed []string
+	for _, d := range e.Result.Deliveries {
+		if d.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", d.Topic, d.Err))
+		}
+	}
+	return fmt.Sprintf("fan-out %s delivered to %d of %d topics: %s",
+		e.Result.ID, len(e.Result.Deliveries)-len(failed), len(e.Result.Deliveries), strings.Join(failed, "; "))
+}
+
+// Unwrap returns the error of each failed destination
+func (e *FanoutError) Unwrap() []error {
+	var errs []error
+	for _, d := range e.Result.Deliveries {
+		if d.Err != nil {
+			errs = append(errs, d.Err)
+		}
+	}
+	return errs
+}
+
+// Publish sends a copy of msg to each configured topic
+func (f *Fanout) Publish(ctx context.Context, msg *Message) (FanoutResult, error) {
+	return f.PublishTo(ctx, msg, f.cfg.Topics...)
+}
+
+// PublishTo sends a copy of msg to each of topics and waits for all of them.
+// msg.Topic is ignored. A new HeaderFanoutID is set on msg when it has none,
+// so publishing msg again to the failed topics keeps the ID. The error is a
+// *FanoutError when any destination failed.
+func (f *Fanout) PublishTo(ctx context.Context, msg *Message, topics ...string) (FanoutResult, error) {
+	if len(topics) == 0 {
+		return FanoutResult{}, errors.New("fan-out has no topics")
+	}
+	id := msg.headerString(HeaderFanoutID)
+	if id == "" {
+		id = ids.NewID()
+		msg.SetHeader(HeaderFanoutID, []byte(id))
+	}
+
+	res := FanoutResult{ID: id, Deliveries: make([]Delivery, len(topics))}
+	var wg sync.WaitGroup
+	for i, topic := range topics {
+		res.Deliveries[i].Topic = topic
+		wg.Add(1)
+		go func(d *Delivery) {
+			defer wg.Done()
+			f.deliver(ctx, msg, d)
+		}(&res.Deliveries[i])
+	}
+	wg.Wait()
+
+	if len(res.Failed()) > 0 {
+		return res, &FanoutError{Result: res}
+	}
+	return res, nil
+}
+
+// deliver produces the copy of msg for d.Topic, retrying retriable errors
+func (f *Fanout) deliver(ctx context.Context, msg *Message, d *Delivery) {
+	out := &Message{
+		Topic:     d.Topic,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Timestamp: msg.Timestamp,
+		Headers:   append([]Header(nil), msg.Headers...),
+	}
+	for {
+		d.Attempts++
+		d.Err = f.producer.Produce(ctx, out)
+		if d.Err == nil {
+			d.Partition, d.Offset = out.Partition, out.Offset
+			return
+		}
+		if d.Attempts >= f.cfg.Attempts || !IsRetriable(d.Err) {
+			return
+		}
+		if err := waitUntil(ctx, time.Now().Add(f.cfg.RetryDelay)); err != nil {
+			return // d.Err keeps the last delivery error
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Filter decides whether a message should reach the handler. Messages that
+// are filtered out are committed without invoking business logic.
+type Filter func(msg *Message) bool
+
+// FilterConfig describes the pre-handler filters; all configured conditions must match
+type FilterConfig struct {
+	Headers     map[string][]string // Header key -> accepted values
+	KeyPrefixes []string            // Accepted key prefixes
+	JSONFields  map[string]string   // Dotted JSON path -> expected value (compared as text)
+}
+
+// Filter builds the combined filter, or nil when nothing is configured
+func (fc FilterConfig) Filter() Filter {
+	var filters []Filter
+	for key, values := range fc.Headers {
+		filters = append(filters, HeaderIn(key, values...))
+	}
+	if len(fc.KeyPrefixes) > 0 {
+		filters = append(filters, KeyPrefix(fc.KeyPrefixes...))
+	}
+	for path, want := range fc.JSONFields {
+		filters = append(filters, JSONFieldEquals(path, want))
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return All(filters...)
+}
+
+// All matches when every filter matches
+func All(filters ...Filter) Filter {
+	return func(msg *Message) bool {
+		for _, f := range filters {
+			if !f(msg) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any matches when at least one filter matches
+func Any(filters ...Filter) Filter {
+	return func(msg *Message) bool {
+		for _, f := range filters {
+			if f(msg) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HeaderIn matches messages whose header key carries one of values
+func HeaderIn(key string, values ...string) Filter {
+	return func(msg *Message) bool {
+		for _, h := range msg.Headers {
+			if h.Key != key {
+				continue
+			}
+			for _, v := range values {
+				if string(h.Value) == v {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// KeyPrefix matches messages whose key starts with one of prefixes
+func KeyPrefix(prefixes ...string) Filter {
+	return func(msg *Message) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(msg.Key, []byte(p)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// JSONField matches messages whose JSON value at the dotted path satisfies
+// pred. Only the objects along the path are decoded; the rest of the payload
+// is skipped as raw bytes.
+func JSONField(path string, pred func(raw json.RawMessage) bool) Filter {
+	keys := strings.Split(path, ".")
+	return func(msg *Message) bool {
+		raw := json.RawMessage(msg.Value)
+		for _, k := range keys {
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &obj); err != nil {
+				return false
+			}
+			var ok bool
+			if raw, ok = obj[k]; !ok {
+				return false
+			}
+		}
+		return pred(raw)
+	}
+}
+
+// JSONFieldEquals matches when the value at path equals want. Strings are
+// compared unquoted, other JSON values by their literal text.
+func JSONFieldEquals(path, want string) Filter {
+	return JSONField(path, func(raw json.RawMessage) bool {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s == want
+		}
+		return string(bytes.TrimSpace(raw)) == want
+	})
+}
+
+// Filtered wraps next so that messages rejected by filter are acknowledged
+// without being handled
+func Filtered(filter Filter, next Handler) Handler {
+	if filter == nil {
+		return next
+	}
+	return func(ctx context.Context, msg *Message) error {
+		if !filter(msg) {
+			return nil
+		}
+		return next(ctx, msg)
+	}
+}
@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/upendravikram5/upendra/ids"
+)
+
+// Standard headers shared by producers, consumers and the retry and
+// dead-letter subsystems. Values are UTF-8 text.
+const (
+	HeaderCorrelationID = "correlation-id" // Links a message to the request or message that caused it
+	HeaderContentType   = "content-type"   // MIME type of the value, e.g. application/json
+	HeaderSchemaID      = "schema-id"      // Schema registry ID of the value, in decimal
+	HeaderSchemaVersion = "schema-version" // Version of the payload's contract, in decimal, e.g. 2
+	HeaderRetryCount    = "retry-count"    // Times the message has been republished for retry
+	HeaderOriginService = "origin-service" // Service that first produced the message
+	HeaderMessageType   = "message-type"   // Kind of payload on topics carrying several, e.g. order.created
+)
+
+// CorrelationID returns the correlation ID header, or "" if unset
+func (m *Message) CorrelationID() string {
+	return m.headerString(HeaderCorrelationID)
+}
+
+// SetCorrelationID sets the correlation ID header
+func (m *Message) SetCorrelationID(id string) {
+	m.SetHeader(HeaderCorrelationID, []byte(id))
+}
+
+// ContentType returns the content type header, or "" if unset
+func (m *Message) ContentType() string {
+	return m.headerString(HeaderContentType)
+}
+
+// SetContentType sets the content type header
+func (m *Message) SetContentType(contentType string) {
+	m.SetHeader(HeaderContentType, []byte(contentType))
+}
+
+// SchemaID returns the schema ID header; ok is false when it is unset or invalid
+func (m *Message) SchemaID() (id int, ok bool) {
+	v, ok := m.Header(HeaderSchemaID)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(string(v))
+	return id, err == nil
+}
+
+// SetSchemaID sets the schema ID header
+func (m *Message) SetSchemaID(id int) {
+	m.SetHeader(HeaderSchemaID, []byte(strconv.Itoa(id)))
+}
+
+// SchemaVersion returns the schema version header; ok is false when it is unset or invalid
+func (m *Message) SchemaVersion() (version int, ok bool) {
+	v, ok := m.Header(HeaderSchemaVersion)
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(string(v))
+	return version, err == nil
+}
+
+// SetSchemaVersion sets the schema version header
+func (m *Message) SetSchemaVersion(version int) {
+	m.SetHeader(HeaderSchemaVersion, []byte(strconv.Itoa(version)))
+}
+
+// RetryCount returns how many times the message has already been retried
+func (m *Message) RetryCount() int {
+	n, _ := strconv.Atoi(m.headerString(HeaderRetryCount))
+	return n
+}
+
+// SetRetryCount sets the retry count header
+func (m *Message) SetRetryCount(n int) {
+	m.SetHeader(HeaderRetryCount, []byte(strconv.Itoa(n)))
+}
+
+// OriginService returns the origin service header, or "" if unset
+func (m *Message) OriginService() string {
+	return m.headerString(HeaderOriginService)
+}
+
+// SetOriginService sets the origin service header
+func (m *Message) SetOriginService(service string) {
+	m.SetHeader(HeaderOriginService, []byte(service))
+}
+
+// MessageType returns the message type header, or "" if unset
+func (m *Message) MessageType() string {
+	return m.headerString(HeaderMessageType)
+}
+
+// SetMessageType sets the message type header
+func (m *Message) SetMessageType(messageType string) {
+	m.SetHeader(HeaderMessageType, []byte(messageType))
+}
+
+func (m *Message) headerString(key string) string {
+	v, _ := m.Header(key)
+	return string(v)
+}
+
+// headerProducer fills in the standard headers a message doesn't carry yet,
+// so values set upstream are propagated unchanged
+type headerProducer struct {
+	Producer
+	ids    ids.Generator
+	origin string
+}
+
+func (p *headerProducer) Produce(ctx context.Context, msg *Message) error {
+	if _, ok := msg.Header(HeaderCorrelationID); !ok {
+		msg.SetCorrelationID(p.ids.NewID())
+	}
+	if _, ok := msg.Header(HeaderOriginService); !ok && p.origin != "" {
+		msg.SetOriginService(p.origin)
+	}
+	return p.Producer.Produce(ctx, msg)
+}
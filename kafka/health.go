@@ -0,0 +1,125 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// TopicPartition identifies one partition of a topic
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// PartitionLag is the number of messages a partition is behind the high watermark.
+// Partition is -1 when a backend only reports an aggregate.
+type PartitionLag struct {
+	TopicPartition
+	Lag int64
+}
+
+// Stater is implemented by consumers that can report group state for health checks
+type Stater interface {
+	// Ping checks connectivity to the brokers
+	Ping(ctx context.Context) error
+	// Assigned returns the partitions currently assigned to this member
+	Assigned() []TopicPartition
+	// Lag returns the lag of the assigned partitions
+	Lag(ctx context.Context) ([]PartitionLag, error)
+}
+
+// HealthConfig sets the thresholds of the consumer health check
+type HealthConfig struct {
+	MaxPollInterval   time.Duration // Unhealthy when lag is pending and nothing was read for this long (default 5m)
+	MaxLag            int64         // Unhealthy when total lag exceeds this; 0 disables
+	RequireAssignment bool          // Unhealthy while no partitions are assigned
+}
+
+// HealthCheck wraps a Consumer, recording successful polls, and implements
+// health.Checker so Kubernetes probes can restart stuck consumers.
+type HealthCheck struct {
+	Consumer
+	cfg       HealthConfig
+	lastPoll  atomic.Int64 // Unix nanos of the last successful ReadMessage
+	failingAt atomic.Int64 // Unix nanos of the first failed ReadMessage since then, or 0
+}
+
+// NewHealthCheck wraps c; pass the returned value to Consume in place of c
+func NewHealthCheck(c Consumer, cfg HealthConfig) *HealthCheck {
+	if cfg.MaxPollInterval <= 0 {
+		cfg.MaxPollInterval = 5 * time.Minute
+	}
+	h := &HealthCheck{Consumer: c, cfg: cfg}
+	h.lastPoll.Store(time.Now().UnixNano())
+	return h
+}
+
+// ReadMessage reads from the wrapped consumer and records the poll time
+func (h *HealthCheck) ReadMessage(ctx context.Context) (*Message, error) {
+	msg, err := h.Consumer.ReadMessage(ctx)
+	switch {
+	case err == nil:
+		h.lastPoll.Store(time.Now().UnixNano())
+		h.failingAt.Store(0)
+	case ctx.Err() == nil:
+		h.failingAt.CompareAndSwap(0, time.Now().UnixNano())
+	}
+	return msg, err
+}
+
+// Unwrap returns the wrapped consumer
+func (h *HealthCheck) Unwrap() Consumer { return h.Consumer }
+
+// SinceLastPoll returns the time elapsed since the last successful read
+func (h *HealthCheck) SinceLastPoll() time.Duration {
+	return time.Since(time.Unix(0, h.lastPoll.Load()))
+}
+
+// Ready fails while reads are failing, as when every broker is down, so a
+// readiness probe takes the instance out of service until a read succeeds
+// again. Register it next to Check:
+//
+//	readiness.Register("kafka", health.CheckerFunc(hc.Ready))
+func (h *HealthCheck) Ready(ctx context.Context) error {
+	if at := h.failingAt.Load(); at != 0 {
+		return fmt.Errorf("kafka reads failing for %s", time.Since(time.Unix(0, at)).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// Check reports broker connectivity, assignment, poll staleness and lag problems
+func (h *HealthCheck) Check(ctx context.Context) error {
+	var errs []error
+	lag := int64(-1) // Unknown unless the backend reports it
+
+	if s, ok := unwrapConsumer[Stater](h.Consumer); ok {
+		if err := s.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("broker connectivity: %w", err))
+		}
+		if h.cfg.RequireAssignment && len(s.Assigned()) == 0 {
+			errs = append(errs, errors.New("no partitions assigned"))
+		}
+		if lags, err := s.Lag(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("lag: %w", err))
+		} else if len(lags) > 0 {
+			lag = 0
+			for _, l := range lags {
+				if l.Lag > 0 {
+					lag += l.Lag
+				}
+			}
+		}
+	}
+
+	// An idle consumer with nothing to read is healthy; a stale one with pending lag is stuck
+	if since := h.SinceLastPoll(); since > h.cfg.MaxPollInterval && lag != 0 {
+		errs = append(errs, fmt.Errorf("no successful poll for %s", since.Round(time.Second)))
+	}
+	if h.cfg.MaxLag > 0 && lag > h.cfg.MaxLag {
+		errs = append(errs, fmt.Errorf("lag %d exceeds threshold %d", lag, h.cfg.MaxLag))
+	}
+	return errors.Join(errs...)
+}
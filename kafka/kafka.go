@@ -0,0 +1,269 @@
+// Package kafka wraps the Kafka producer and consumer behind small interfaces
+// so the client library can be chosen per build environment. The confluent
+// backend needs CGO (librdkafka); the franz and segmentio backends are pure Go.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/upendravikram5/upendra/ids"
+)
+
+// Header is a single Kafka record header
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a backend-neutral Kafka record
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Timestamp time.Time
+
+	raw  interface{} // Backend-specific record, used when committing
+	from Consumer    // Client that read the message, set by Rotator's consumers
+}
+
+// Header returns the value of the first header named key
+func (m *Message) Header(key string) ([]byte, bool) {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return h.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetHeader replaces the header named key, adding it if missing
+func (m *Message) SetHeader(key string, value []byte) {
+	for i, h := range m.Headers {
+		if h.Key == key {
+			m.Headers[i].Value = value
+			return
+		}
+	}
+	m.Headers = append(m.Headers, Header{Key: key, Value: value})
+}
+
+// Producer sends messages to Kafka
+type Producer interface {
+	// Produce sends the message and waits for the delivery report.
+	// On success the message partition and offset are filled in.
+	Produce(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// Consumer reads messages from the subscribed topics
+type Consumer interface {
+	// ReadMessage blocks until a message is available or ctx is done
+	ReadMessage(ctx context.Context) (*Message, error)
+	// CommitMessage commits the offset following msg
+	CommitMessage(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// Backend creates producers and consumers for one client library
+type Backend interface {
+	NewProducer(cfg *Config) (Producer, error)
+	NewConsumer(cfg *Config, topics []string) (Consumer, error)
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Backend)
+)
+
+// backendPreference is the order used when Config.Backend is empty
+var backendPreference = []string{"confluent", "franz", "segmentio"}
+
+// RegisterBackend makes a backend available by name. Backends compiled into
+// the binary register themselves from init.
+func RegisterBackend(name string, b Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, dup := backends[name]; dup {
+		panic("kafka: RegisterBackend called twice for backend " + name)
+	}
+	backends[name] = b
+}
+
+// Backends returns the names of the registered backends
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	return backendNames()
+}
+
+// backendNames lists the registered backends; callers must hold backendsMu
+func backendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// backend resolves the backend selected by the configuration
+func backend(cfg *Config) (Backend, error) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	if cfg.Backend != "" {
+		b, ok := backends[cfg.Backend]
+		if !ok {
+			return nil, fmt.Errorf("kafka backend %q is not available in this build (have %v)", cfg.Backend, backendNames())
+		}
+		return b, nil
+	}
+	for _, name := range backendPreference {
+		if b, ok := backends[name]; ok {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("no kafka backend compiled in")
+}
+
+// NewProducer creates a new Kafka producer using the configured backend
+func NewProducer(cfg *Config) (Producer, error) {
+	b, err := backend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg, err = cfg.withPasswordFile(); err != nil {
+		return nil, err
+	}
+	gen, err := ids.New(cfg.IDFormat)
+	if err != nil {
+		return nil, err
+	}
+	p, err := b.NewProducer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create producer: %w", err)
+	}
+	if !cfg.DisableProducerMetrics {
+		p = InstrumentProducer(p)
+	}
+	if cfg.SchemaCheck.RegistryURL != "" {
+		p = NewSchemaCheckedProducer(p, cfg.SchemaCheck)
+	}
+	return &headerProducer{Producer: p, ids: gen, origin: cfg.OriginService}, nil
+}
+
+// NewConsumer creates a new Kafka consumer subscribed to topics using the configured backend
+func NewConsumer(cfg *Config, topics ...string) (Consumer, error) {
+	b, err := backend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg, err = cfg.withPasswordFile(); err != nil {
+		return nil, err
+	}
+	c, err := b.NewConsumer(cfg, topics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	return c, nil
+}
+
+// Handler processes a single consumed message
+type Handler func(ctx context.Context, msg *Message) error
+
+// Consume reads messages until ctx is done, passing each one to handler and
+// committing its offset afterwards unless auto commit is enabled. Messages
+// rejected by cfg.Filter are committed without calling handler, and each
+// handler call is bounded by cfg.HandlerTimeout, or the timeout in
+// cfg.Topics for the message's topic, when set. With cfg.Retry set, failed
+// and timed-out messages move along the retry tiers to the DLQ, and messages
+// of a tier wait for their scheduled time with their partition paused. Once
+// ctx is done no new messages are fetched; the message in flight may finish
+// and be committed within cfg.DrainTimeout before Consume returns.
+// cfg.Concurrency selects whether partitions are handled one message at a
+// time or in parallel.
+func Consume(ctx context.Context, c Consumer, cfg *Config, handler Handler) error {
+	return consume(ctx, nil, c, cfg, handler)
+}
+
+// consume is Consume with abort, when not nil, cutting the drain short: once
+// it is done handlers and commits see their context cancelled
+func consume(ctx, abort context.Context, c Consumer, cfg *Config, handler Handler) error {
+	handler = cfg.topicHandler(handler)
+	if t, ok := unwrapConsumer[*StateTracker](c); ok && cfg.EnableAutoCommit {
+		t.autoCommitted()
+	}
+	if cfg.Retry.enabled() {
+		if cfg.Retry.Producer == nil {
+			return fmt.Errorf("retry config needs a producer")
+		}
+		rc := cfg.Retry
+		if rc.Overrides == nil {
+			rc.Overrides = cfg.Topics
+		}
+		handler = Retrying(rc.Producer, rc, handler)
+		c = newRetryGate(c, rc)
+	}
+	handler = Filtered(cfg.Filter.Filter(), handler)
+	hctx, cancel := drainContext(ctx, cfg.drainTimeout())
+	defer cancel()
+	if abort != nil {
+		defer context.AfterFunc(abort, cancel)()
+	}
+	switch cfg.Concurrency {
+	case ConcurrencyPartition, ConcurrencyPool:
+		return consumeConcurrently(ctx, hctx, c, cfg, handler)
+	case ConcurrencyPriority:
+		return consumePriority(ctx, hctx, c, cfg, handler)
+	}
+
+	down := newOutage(cfg.Outage, "consumer")
+	for {
+		msg, err := c.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := down.failed(ctx, err); err != nil {
+				return err
+			}
+			continue
+		}
+		down.ok()
+
+		handle(hctx, c, cfg, handler, msg)
+	}
+}
+
+// uncommitted marks a handler error after which the message must not be
+// committed: it was neither handled nor handed on to a retry or DLQ topic
+type uncommitted struct{ error }
+
+func (e uncommitted) Unwrap() error { return e.error }
+
+// handle passes msg to handler and commits it unless auto commit is enabled.
+// A message whose handler timed out, or that Retrying could neither retry
+// nor dead-letter, is left uncommitted, so it is read again after a restart
+// or rebalance unless a later message of its partition is committed first;
+// set cfg.Retry to have timeouts retried instead.
+func handle(ctx context.Context, c Consumer, cfg *Config, handler Handler, msg *Message) {
+	err := handler(ctx, msg)
+	if err != nil {
+		log.Printf("MessageHandler error: %v\n", err)
+	}
+	var u uncommitted
+	if cfg.EnableAutoCommit || errors.Is(err, ErrHandlerTimeout) || errors.As(err, &u) {
+		return
+	}
+	if err := c.CommitMessage(ctx, msg); err != nil {
+		log.Printf("Failed to commit offset: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package kafkatest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/upendravikram5/upendra/kafka"
+)
+
+// InjectedError is returned by injected faults. It is retriable like the
+// transient broker errors it stands in for.
+type InjectedError struct {
+	Op string // "read", "commit" or "produce"
+	N  int    // Call number that failed, starting at 1
+}
+
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("injected %s fault on call %d", e.Op, e.N)
+}
+
+// IsRetriable reports true so kafka.IsRetriable treats the fault as transient
+func (e *InjectedError) IsRetriable() bool { return true }
+
+// Faults lists the faults a FaultyConsumer or FaultyProducer injects. Faults
+// fire on fixed call counts rather than randomly, so tests are repeatable.
+type Faults struct {
+	ReadDelay        time.Duration // Added before every read
+	DisconnectEvery  int           // Every nth read fails as if the broker connection dropped
+	RebalanceEvery   int           // Every nth read revokes all partitions, redelivering uncommitted messages
+	FailCommitEvery  int           // Every nth commit fails
+	FailProduceEvery int           // Every nth produce fails
+}
+
+func every(n, call int) bool { return n > 0 && call%n == 0 }
+
+// FaultyConsumer wraps a consumer with injected faults
+type FaultyConsumer struct {
+	kafka.Consumer
+	faults Faults
+
+	mu          sync.Mutex
+	reads       int
+	commits     int
+	disconnect  bool
+	uncommitted []*kafka.Message // Read but not committed, in read order
+	replay      []*kafka.Message // Redelivered after a simulated rebalance
+}
+
+// NewFaultyConsumer wraps c
+func NewFaultyConsumer(c kafka.Consumer, faults Faults) *FaultyConsumer {
+	return &FaultyConsumer{Consumer: c, faults: faults}
+}
+
+// Disconnect makes the next read fail
+func (c *FaultyConsumer) Disconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnect = true
+}
+
+// Rebalance simulates losing and regaining every partition: messages read
+// since their last commit are delivered again
+func (c *FaultyConsumer) Rebalance() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replay = append(append([]*kafka.Message(nil), c.uncommitted...), c.replay...)
+	c.uncommitted = nil
+}
+
+// ReadMessage reads from the replay buffer or the wrapped consumer, injecting faults
+func (c *FaultyConsumer) ReadMessage(ctx context.Context) (*kafka.Message, error) {
+	if c.faults.ReadDelay > 0 {
+		select {
+		case <-time.After(c.faults.ReadDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	c.reads++
+	n := c.reads
+	if c.disconnect || every(c.faults.DisconnectEvery, n) {
+		c.disconnect = false
+		c.mu.Unlock()
+		return nil, &InjectedError{Op: "read", N: n}
+	}
+	if every(c.faults.RebalanceEvery, n) {
+		c.replay = append(append([]*kafka.Message(nil), c.uncommitted...), c.replay...)
+		c.uncommitted = nil
+	}
+	if len(c.replay) > 0 {
+		msg := c.replay[0]
+		c.replay = c.replay[1:]
+		c.uncommitted = append(c.uncommitted, msg)
+		c.mu.Unlock()
+		return msg, nil
+	}
+	c.mu.Unlock()
+
+	msg, err := c.Consumer.ReadMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.uncommitted = append(c.uncommitted, msg)
+	c.mu.Unlock()
+	return msg, nil
+}
+
+// CommitMessage commits through the wrapped consumer unless a fault is due
+func (c *FaultyConsumer) CommitMessage(ctx context.Context, msg *kafka.Message) error {
+	c.mu.Lock()
+	c.commits++
+	n := c.commits
+	c.mu.Unlock()
+	if every(c.faults.FailCommitEvery, n) {
+		return &InjectedError{Op: "commit", N: n}
+	}
+	if err := c.Consumer.CommitMessage(ctx, msg); err != nil {
+		return err
+	}
+
+	// A commit covers everything up to msg on its partition
+	c.mu.Lock()
+	kept := c.uncommitted[:0]
+	for _, m := range c.uncommitted {
+		if m.Topic != msg.Topic || m.Partition != msg.Partition || m.Offset > msg.Offset {
+			kept = append(kept, m)
+		}
+	}
+	c.uncommitted = kept
+	c.mu.Unlock()
+	return nil
+}
+
+// Unwrap returns the wrapped consumer
+func (c *FaultyConsumer) Unwrap() kafka.Consumer { return c.Consumer }
+
+// FaultyProducer wraps a producer with injected faults
+type FaultyProducer struct {
+	kafka.Producer
+	faults Faults
+
+	mu       sync.Mutex
+	produces int
+}
+
+// NewFaultyProducer wraps p
+func NewFaultyProducer(p kafka.Producer, faults Faults) *FaultyProducer {
+	return &FaultyProducer{Producer: p, faults: faults}
+}
+
+// Produce sends through the wrapped producer unless a fault is due
+func (p *FaultyProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+	p.mu.Lock()
+	p.produces++
+	n := p.produces
+	p.mu.Unlock()
+	if every(p.faults.FailProduceEvery, n) {
+		return &InjectedError{Op: "produce", N: n}
+	}
+	return p.Producer.Produce(ctx, msg)
+}
@@ -0,0 +1,232 @@
+// Package kafkatest runs a throwaway Kafka broker in Docker for end-to-end
+// tests of producers and consumers built on the kafka package, and wraps
+// clients with deterministic faults to exercise retry, DLQ and drain logic.
+//
+//	b := kafkatest.Start(t)
+//	b.CreateTopic(t, "orders", 3)
+//	b.Produce(t, "orders", &kafka.Message{Key: []byte("k"), Value: []byte("v")})
+//	msgs := b.Consume(t, "orders", 1)
+package kafkatest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tckafka "github.com/testcontainers/testcontainers-go/modules/kafka"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/upendravikram5/upendra/kafka"
+)
+
+// DefaultImage is the single-node KRaft broker image used by Start
+const DefaultImage = "confluentinc/confluent-local:7.6.0"
+
+// DefaultTimeout bounds Consume and the broker calls made by the helpers
+const DefaultTimeout = 30 * time.Second
+
+// Option configures Start
+type Option func(*options)
+
+type options struct {
+	image   string
+	backend string
+}
+
+// WithImage overrides DefaultImage
+func WithImage(image string) Option {
+	return func(o *options) { o.image = image }
+}
+
+// WithBackend selects the kafka backend used by the helpers and returned in Config
+func WithBackend(name string) Option {
+	return func(o *options) { o.backend = name }
+}
+
+// Broker is a running test broker
+type Broker struct {
+	Config *kafka.Config // Points at the broker; copy it before changing fields
+
+	admin  *kadm.Client
+	groups atomic.Int64
+}
+
+// Start launches a broker and stops it when the test ends. The test is
+// skipped when no Docker daemon is reachable.
+func Start(tb testing.TB, opts ...Option) *Broker {
+	tb.Helper()
+	ctx := context.Background()
+	skipWithoutDocker(ctx, tb)
+
+	o := options{image: DefaultImage}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	container, err := tckafka.Run(ctx, o.image, tckafka.WithClusterID("kafkatest"))
+	testcontainers.CleanupContainer(tb, container)
+	if err != nil {
+		tb.Fatalf("failed to start kafka container: %v", err)
+	}
+	brokers, err := container.Brokers(ctx)
+	if err != nil {
+		tb.Fatalf("failed to get broker address: %v", err)
+	}
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...))
+	if err != nil {
+		tb.Fatalf("failed to create admin client: %v", err)
+	}
+	tb.Cleanup(client.Close)
+
+	return &Broker{
+		Config: &kafka.Config{
+			Backend:          o.backend,
+			BootstrapServers: strings.Join(brokers, ","),
+			SecurityProtocol: "PLAINTEXT",
+			AutoOffsetReset:  "earliest",
+		},
+		admin: kadm.NewClient(client),
+	}
+}
+
+func skipWithoutDocker(ctx context.Context, tb testing.TB) {
+	tb.Helper()
+	provider, err := testcontainers.NewDockerProvider()
+	if err == nil {
+		defer provider.Close()
+		err = provider.Health(ctx)
+	}
+	if err != nil {
+		tb.Skipf("docker is not available: %v", err)
+	}
+}
+
+// CreateTopic creates topic with the given number of partitions
+func (b *Broker) CreateTopic(tb testing.TB, topic string, partitions int) {
+	tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	resp, err := b.admin.CreateTopic(ctx, int32(partitions), 1, nil, topic)
+	if err == nil {
+		err = resp.Err
+	}
+	if err != nil {
+		tb.Fatalf("failed to create topic %s: %v", topic, err)
+	}
+}
+
+// Producer returns a producer for the broker, closed when the test ends
+func (b *Broker) Producer(tb testing.TB) kafka.Producer {
+	tb.Helper()
+	p, err := kafka.NewProducer(b.Config)
+	if err != nil {
+		tb.Fatalf("failed to create producer: %v", err)
+	}
+	tb.Cleanup(func() { p.Close() })
+	return p
+}
+
+// Consumer returns a consumer of topics in a fresh group that starts from
+// the earliest offset, closed when the test ends
+func (b *Broker) Consumer(tb testing.TB, topics ...string) kafka.Consumer {
+	tb.Helper()
+	cfg := *b.Config
+	cfg.GroupID = fmt.Sprintf("kafkatest-%d-%d", time.Now().UnixNano(), b.groups.Add(1))
+	c, err := kafka.NewConsumer(&cfg, topics...)
+	if err != nil {
+		tb.Fatalf("failed to create consumer: %v", err)
+	}
+	tb.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Produce writes fixture messages to topic
+func (b *Broker) Produce(tb testing.TB, topic string, msgs ...*kafka.Message) {
+	tb.Helper()
+	p := b.Producer(tb)
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	for i, msg := range msgs {
+		msg.Topic = topic
+		if err := p.Produce(ctx, msg); err != nil {
+			tb.Fatalf("failed to produce fixture %d to %s: %v", i, topic, err)
+		}
+	}
+}
+
+// ProduceJSON writes v encoded as JSON to topic under key
+func (b *Broker) ProduceJSON(tb testing.TB, topic, key string, v interface{}) {
+	tb.Helper()
+	value, err := json.Marshal(v)
+	if err != nil {
+		tb.Fatalf("failed to encode fixture: %v", err)
+	}
+	msg := &kafka.Message{Key: []byte(key), Value: value}
+	msg.SetContentType("application/json")
+	b.Produce(tb, topic, msg)
+}
+
+// Consume reads n messages from topic, failing the test if they don't
+// arrive within DefaultTimeout
+func (b *Broker) Consume(tb testing.TB, topic string, n int) []*kafka.Message {
+	tb.Helper()
+	c := b.Consumer(tb, topic)
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	msgs := make([]*kafka.Message, 0, n)
+	for len(msgs) < n {
+		msg, err := c.ReadMessage(ctx)
+		if err != nil {
+			tb.Fatalf("got %d of %d messages from %s: %v", len(msgs), n, topic, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// AssertProduced checks that topic holds at least one message matching
+// match among its first n messages
+func (b *Broker) AssertProduced(tb testing.TB, topic string, n int, match func(*kafka.Message) bool) *kafka.Message {
+	tb.Helper()
+	for _, msg := range b.Consume(tb, topic, n) {
+		if match(msg) {
+			return msg
+		}
+	}
+	tb.Fatalf("no matching message among the first %d of %s", n, topic)
+	return nil
+}
+
+// AssertEmpty checks that topic receives no message within wait, e.g. a
+// dead-letter topic after successful processing
+func (b *Broker) AssertEmpty(tb testing.TB, topic string, wait time.Duration) {
+	tb.Helper()
+	c := b.Consumer(tb, topic)
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	if msg, err := c.ReadMessage(ctx); err == nil {
+		tb.Fatalf("expected %s to be empty, got message at offset %d", topic, msg.Offset)
+	}
+}
+
+// KeyEquals matches messages with the given key
+func KeyEquals(key string) func(*kafka.Message) bool {
+	return func(msg *kafka.Message) bool { return string(msg.Key) == key }
+}
+
+// HeaderEquals matches messages whose header key has the given value
+func HeaderEquals(key, value string) func(*kafka.Message) bool {
+	return func(msg *kafka.Message) bool {
+		v, ok := msg.Header(key)
+		return ok && string(v) == value
+	}
+}
@@ -0,0 +1,227 @@
+package kafkatest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/upendravikram5/upendra/kafka"
+	"github.com/upendravikram5/upendra/kafka/kafkatest"
+)
+
+// start launches a broker for an end-to-end test; skipped with -short or
+// without Docker
+func start(t *testing.T) *kafkatest.Broker {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("end-to-end test needs a broker")
+	}
+	return kafkatest.Start(t)
+}
+
+// groupConsumer returns a consumer of topics in group, closed when the test ends
+func groupConsumer(t *testing.T, cfg *kafka.Config, group string, topics ...string) kafka.Consumer {
+	t.Helper()
+	cfg.GroupID = group
+	c, err := kafka.NewConsumer(cfg, topics...)
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// consumeUntil runs kafka.Consume until done reports true or
+// kafkatest.DefaultTimeout passes
+func consumeUntil(t *testing.T, c kafka.Consumer, cfg *kafka.Config, handler kafka.Handler, done func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), kafkatest.DefaultTimeout)
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil && !done() {
+			time.Sleep(50 * time.Millisecond)
+		}
+		cancel()
+	}()
+	if err := kafka.Consume(ctx, c, cfg, handler); !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Consume: %v", err)
+	}
+	if !done() {
+		t.Fatalf("condition not met within %s", kafkatest.DefaultTimeout)
+	}
+}
+
+// recorder collects the handled messages
+type recorder struct {
+	mu   sync.Mutex
+	msgs []*kafka.Message
+}
+
+func (r *recorder) add(msg *kafka.Message) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+	return len(r.msgs)
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.msgs)
+}
+
+func TestProduceConsume(t *testing.T) {
+	b := start(t)
+	b.CreateTopic(t, "orders", 1)
+
+	p := b.Producer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), kafkatest.DefaultTimeout)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		msg := &kafka.Message{Topic: "orders", Key: []byte(fmt.Sprint(i)), Value: []byte(fmt.Sprintf("order %d", i))}
+		if err := p.Produce(ctx, msg); err != nil {
+			t.Fatalf("Produce %d: %v", i, err)
+		}
+		if msg.Offset != int64(i) {
+			t.Errorf("message %d reported offset %d", i, msg.Offset)
+		}
+	}
+
+	for i, msg := range b.Consume(t, "orders", 3) {
+		if string(msg.Key) != fmt.Sprint(i) || string(msg.Value) != fmt.Sprintf("order %d", i) {
+			t.Errorf("message %d = %q/%q", i, msg.Key, msg.Value)
+		}
+		if _, ok := msg.Header(kafka.HeaderCorrelationID); !ok {
+			t.Errorf("message %d has no %s header", i, kafka.HeaderCorrelationID)
+		}
+	}
+}
+
+func TestCommitResume(t *testing.T) {
+	b := start(t)
+	b.CreateTopic(t, "payments", 1)
+	for i := 0; i < 4; i++ {
+		b.Produce(t, "payments", &kafka.Message{Key: []byte(fmt.Sprint(i))})
+	}
+
+	cfg := *b.Config
+	group := "resume-" + t.Name()
+	ctx, cancel := context.WithTimeout(context.Background(), kafkatest.DefaultTimeout)
+	defer cancel()
+
+	c := groupConsumer(t, &cfg, group, "payments")
+	var msg *kafka.Message
+	for i := 0; i < 2; i++ {
+		var err error
+		if msg, err = c.ReadMessage(ctx); err != nil {
+			t.Fatalf("ReadMessage %d: %v", i, err)
+		}
+	}
+	if err := c.CommitMessage(ctx, msg); err != nil {
+		t.Fatalf("CommitMessage: %v", err)
+	}
+	c.Close()
+
+	msg, err := groupConsumer(t, &cfg, group, "payments").ReadMessage(ctx)
+	if err != nil {
+		t.Fatalf("ReadMessage after restart: %v", err)
+	}
+	if msg.Offset != 2 || string(msg.Key) != "2" {
+		t.Errorf("resumed at offset %d key %q, want offset 2 key \"2\"", msg.Offset, msg.Key)
+	}
+}
+
+func TestRetryThenSucceed(t *testing.T) {
+	b := start(t)
+	for _, topic := range []string{"invoices", "invoices.retry", "invoices.dlq"} {
+		b.CreateTopic(t, topic, 1)
+	}
+	b.Produce(t, "invoices", &kafka.Message{Key: []byte("inv-1"), Value: []byte("{}")})
+
+	cfg := *b.Config
+	cfg.Retry = kafka.RetryConfig{
+		Tiers:    []kafka.RetryTier{{Topic: "invoices.retry", Delay: 2 * time.Second}},
+		DLQTopic: "invoices.dlq",
+		Producer: b.Producer(t),
+	}
+	var handled recorder
+	var firstAt, retryAt time.Time
+	consumeUntil(t, groupConsumer(t, &cfg, "retry-"+t.Name(), append(cfg.Retry.Topics(), "invoices")...), &cfg, func(_ context.Context, msg *kafka.Message) error {
+		if handled.add(msg) == 1 {
+			firstAt = time.Now()
+			return errors.New("downstream unavailable")
+		}
+		retryAt = time.Now()
+		return nil
+	}, func() bool { return handled.count() >= 2 })
+
+	retried := handled.msgs[1]
+	if retried.Topic != "invoices.retry" || retried.RetryCount() != 1 {
+		t.Errorf("second delivery from %s with retry count %d, want invoices.retry and 1", retried.Topic, retried.RetryCount())
+	}
+	if v, _ := retried.Header(kafka.HeaderOriginalTopic); string(v) != "invoices" {
+		t.Errorf("original topic header = %q", v)
+	}
+	if d := retryAt.Sub(firstAt); d < 2*time.Second {
+		t.Errorf("retried after %s, before the 2s tier delay", d)
+	}
+	b.AssertEmpty(t, "invoices.dlq", 2*time.Second)
+}
+
+func TestDeadLetter(t *testing.T) {
+	b := start(t)
+	for _, topic := range []string{"shipments", "shipments.retry", "shipments.dlq"} {
+		b.CreateTopic(t, topic, 1)
+	}
+	b.Produce(t, "shipments", &kafka.Message{Key: []byte("ship-1")})
+
+	cfg := *b.Config
+	cfg.Retry = kafka.RetryConfig{
+		Tiers:    []kafka.RetryTier{{Topic: "shipments.retry", Delay: time.Second}},
+		DLQTopic: "shipments.dlq",
+		Producer: b.Producer(t),
+	}
+	var handled recorder
+	consumeUntil(t, groupConsumer(t, &cfg, "dlq-"+t.Name(), append(cfg.Retry.Topics(), "shipments")...), &cfg, func(_ context.Context, msg *kafka.Message) error {
+		handled.add(msg)
+		return errors.New("invalid address")
+	}, func() bool { return handled.count() >= 2 })
+
+	dead := b.AssertProduced(t, "shipments.dlq", 1, kafkatest.KeyEquals("ship-1"))
+	if v, _ := dead.Header(kafka.HeaderRetryError); string(v) != "invalid address" {
+		t.Errorf("retry error header = %q", v)
+	}
+	if dead.RetryCount() != 2 {
+		t.Errorf("dead letter retry count = %d, want 2", dead.RetryCount())
+	}
+}
+
+func TestTimeoutRetried(t *testing.T) {
+	b := start(t)
+	for _, topic := range []string{"reports", "reports.retry"} {
+		b.CreateTopic(t, topic, 1)
+	}
+	b.Produce(t, "reports", &kafka.Message{Key: []byte("rep-1")})
+
+	cfg := *b.Config
+	cfg.HandlerTimeout = 200 * time.Millisecond
+	cfg.Retry = kafka.RetryConfig{
+		Tiers:    []kafka.RetryTier{{Topic: "reports.retry", Delay: time.Second}},
+		Producer: b.Producer(t),
+	}
+	var handled recorder
+	consumeUntil(t, groupConsumer(t, &cfg, "timeout-"+t.Name(), append(cfg.Retry.Topics(), "reports")...), &cfg, func(ctx context.Context, msg *kafka.Message) error {
+		if handled.add(msg) == 1 {
+			<-ctx.Done() // Stuck until the timeout
+			return ctx.Err()
+		}
+		return nil
+	}, func() bool { return handled.count() >= 2 })
+
+	if retried := handled.msgs[1]; retried.Topic != "reports.retry" {
+		t.Errorf("timed-out message redelivered from %s, want reports.retry", retried.Topic)
+	}
+}
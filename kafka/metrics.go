@@ -0,0 +1,115 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+// Producer metrics, registered with the shared registry on first use
+var (
+	producerMetricsOnce sync.Once
+
+	producedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_producer",
+		Name:      "messages_total",
+		Help:      "Messages successfully delivered, by topic.",
+	}, []string{"topic"})
+
+	produceFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_producer",
+		Name:      "failures_total",
+		Help:      "Messages that failed to deliver, by topic and error class (fatal, retriable or other).",
+	}, []string{"topic", "class"})
+
+	deliveryLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_producer",
+		Name:      "delivery_seconds",
+		Help:      "Time from Produce until the delivery report, by topic.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14), // 1ms .. ~8s
+	}, []string{"topic"})
+
+	producerInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_producer",
+		Name:      "in_flight",
+		Help:      "Produce calls waiting for their delivery report.",
+	})
+)
+
+// instrumentedProducer records metrics around every Produce call
+type instrumentedProducer struct {
+	Producer
+}
+
+// InstrumentProducer wraps p with Prometheus metrics. NewProducer applies it
+// unless Config.DisableProducerMetrics is set.
+func InstrumentProducer(p Producer) Producer {
+	producerMetricsOnce.Do(func() {
+		metrics.MustRegister(producedTotal, produceFailuresTotal, deliveryLatency, producerInFlight)
+	})
+	return &instrumentedProducer{Producer: p}
+}
+
+func (p *instrumentedProducer) Produce(ctx context.Context, msg *Message) error {
+	producerInFlight.Inc()
+	defer producerInFlight.Dec()
+
+	start := time.Now()
+	err := p.Producer.Produce(ctx, msg)
+	if err != nil {
+		produceFailuresTotal.WithLabelValues(msg.Topic, errorClass(err)).Inc()
+		return err
+	}
+	metrics.ObserveWithExemplar(ctx, deliveryLatency.WithLabelValues(msg.Topic), time.Since(start).Seconds())
+	producedTotal.WithLabelValues(msg.Topic).Inc()
+	return nil
+}
+
+// errorClass labels a produce failure: "fatal" only for errors that leave the
+// producer unusable, so alerts on it don't fire for cancelled contexts or
+// rejected messages, which are "other"
+func errorClass(err error) string {
+	switch {
+	case IsFatal(err):
+		return "fatal"
+	case IsRetriable(err):
+		return "retriable"
+	}
+	return "other"
+}
+
+// retriableCheckers are contributed by backends whose errors carry no retriable method
+var retriableCheckers []func(error) bool
+
+// IsRetriable reports whether err is a transient error that may succeed if retried
+func IsRetriable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var r interface{ IsRetriable() bool }
+	if errors.As(err, &r) {
+		return r.IsRetriable()
+	}
+	var t interface{ Temporary() bool }
+	if errors.As(err, &t) {
+		return t.Temporary()
+	}
+	for _, check := range retriableCheckers {
+		if check(err) {
+			return true
+		}
+	}
+	return false
+}
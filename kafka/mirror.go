@@ -0,0 +1,211 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+// Headers added to mirrored messages
+const (
+	HeaderMirrorSourceTopic     = "mirror-source-topic"
+	HeaderMirrorSourcePartition = "mirror-source-partition"
+	HeaderMirrorSourceOffset    = "mirror-source-offset"
+)
+
+// Mirror metrics, registered with the shared registry on first use
+var (
+	mirrorMetricsOnce sync.Once
+
+	mirroredTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_mirror",
+		Name:      "messages_total",
+		Help:      "Messages copied to the target cluster, by source topic.",
+	}, []string{"topic"})
+
+	mirrorFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_mirror",
+		Name:      "failures_total",
+		Help:      "Failed attempts to copy a message, by source topic.",
+	}, []string{"topic"})
+
+	mirrorOffset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_mirror",
+		Name:      "source_offset",
+		Help:      "Last source offset copied, by topic and partition.",
+	}, []string{"topic", "partition"})
+
+	mirrorDelay = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_mirror",
+		Name:      "replication_delay_seconds",
+		Help:      "Age of the last copied message when it reached the target, by source topic.",
+	}, []string{"topic"})
+)
+
+// MirrorConfig configures a Mirror
+type MirrorConfig struct {
+	Topic       func(source string) string // Maps source to target topic names; nil keeps the name
+	OffsetTopic string                     // Target topic receiving offset translation records; empty disables
+	RetryDelay  time.Duration              // Wait between attempts when the target rejects a message (default 1s)
+	Outage      OutageConfig               // Backoff while reads from the source keep failing
+}
+
+// OffsetTranslation maps a source offset to the offset of its copy; records
+// are keyed by source topic and partition so the offset topic can be compacted
+type OffsetTranslation struct {
+	SourceTopic     string    `json:"source_topic"`
+	SourcePartition int32     `json:"source_partition"`
+	SourceOffset    int64     `json:"source_offset"`
+	TargetTopic     string    `json:"target_topic"`
+	TargetPartition int32     `json:"target_partition"`
+	TargetOffset    int64     `json:"target_offset"` // -1 when the target backend does not report offsets
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// Mirror copies messages from a consumer on one cluster to a producer on
+// another, keeping keys, timestamps and headers. A source message is
+// committed only after its copy is acknowledged, so delivery is at least once.
+type Mirror struct {
+	source Consumer
+	target Producer
+	cfg    MirrorConfig
+
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// NewMirror creates a mirror; the source consumer decides which topics are mirrored
+func NewMirror(source Consumer, target Producer, cfg MirrorConfig) *Mirror {
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = time.Second
+	}
+	mirrorMetricsOnce.Do(func() {
+		metrics.MustRegister(mirroredTotal, mirrorFailuresTotal, mirrorOffset, mirrorDelay)
+	})
+	return &Mirror{source: source, target: target, cfg: cfg}
+}
+
+// Start runs the mirror in the background
+func (m *Mirror) Start(ctx context.Context) error {
+	ctx, m.cancel = context.WithCancel(context.Background())
+	m.done = make(chan error, 1)
+	go func() { m.done <- m.Run(ctx) }()
+	return nil
+}
+
+// Stop cancels the mirror and waits for the message in flight
+func (m *Mirror) Stop(ctx context.Context) error {
+	if m.cancel == nil {
+		return nil
+	}
+	m.cancel()
+	select {
+	case err := <-m.done:
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run copies messages until ctx is cancelled
+func (m *Mirror) Run(ctx context.Context) error {
+	down := newOutage(m.cfg.Outage, "mirror")
+	for {
+		msg, err := m.source.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := down.failed(ctx, err); err != nil {
+				return err
+			}
+			continue
+		}
+		down.ok()
+		if err := m.copy(ctx, msg); err != nil {
+			return err
+		}
+		if err := m.source.CommitMessage(ctx, msg); err != nil {
+			log.Printf("Failed to commit offset: %v", err)
+		}
+	}
+}
+
+// copy produces msg to the target, retrying until it succeeds or ctx ends
+func (m *Mirror) copy(ctx context.Context, msg *Message) error {
+	out := &Message{
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Timestamp: msg.Timestamp,
+		Headers:   append([]Header(nil), msg.Headers...),
+	}
+	if m.cfg.Topic != nil {
+		out.Topic = m.cfg.Topic(msg.Topic)
+	}
+	out.SetHeader(HeaderMirrorSourceTopic, []byte(msg.Topic))
+	out.SetHeader(HeaderMirrorSourcePartition, []byte(strconv.Itoa(int(msg.Partition))))
+	out.SetHeader(HeaderMirrorSourceOffset, []byte(strconv.FormatInt(msg.Offset, 10)))
+
+	for {
+		out.Offset = -1
+		err := m.target.Produce(ctx, out)
+		if err == nil {
+			break
+		}
+		mirrorFailuresTotal.WithLabelValues(msg.Topic).Inc()
+		log.Printf("Failed to mirror %s[%d]@%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		if err := waitUntil(ctx, time.Now().Add(m.cfg.RetryDelay)); err != nil {
+			return err
+		}
+	}
+
+	mirroredTotal.WithLabelValues(msg.Topic).Inc()
+	mirrorOffset.WithLabelValues(msg.Topic, strconv.Itoa(int(msg.Partition))).Set(float64(msg.Offset))
+	if !msg.Timestamp.IsZero() {
+		mirrorDelay.WithLabelValues(msg.Topic).Set(time.Since(msg.Timestamp).Seconds())
+	}
+
+	if m.cfg.OffsetTopic != "" {
+		if err := m.translate(ctx, msg, out); err != nil {
+			log.Printf("Failed to write offset translation: %v", err)
+		}
+	}
+	return nil
+}
+
+func (m *Mirror) translate(ctx context.Context, src, dst *Message) error {
+	value, err := json.Marshal(OffsetTranslation{
+		SourceTopic:     src.Topic,
+		SourcePartition: src.Partition,
+		SourceOffset:    src.Offset,
+		TargetTopic:     dst.Topic,
+		TargetPartition: dst.Partition,
+		TargetOffset:    dst.Offset,
+		Timestamp:       time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return m.target.Produce(ctx, &Message{
+		Topic: m.cfg.OffsetTopic,
+		Key:   []byte(fmt.Sprintf("%s:%d", src.Topic, src.Partition)),
+		Value: value,
+	})
+}
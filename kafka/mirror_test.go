@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyProducer fails the first fail calls, then assigns increasing offsets
+type flakyProducer struct {
+	mu       sync.Mutex
+	fail     int
+	attempts int
+	msgs     []*Message
+}
+
+func (p *flakyProducer) Produce(_ context.Context, msg *Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts++
+	if p.attempts <= p.fail {
+		return errors.New("target unavailable")
+	}
+	msg.Partition, msg.Offset = 0, int64(100+len(p.msgs))
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *flakyProducer) Close() error { return nil }
+
+func (p *flakyProducer) produced() []*Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Message(nil), p.msgs...)
+}
+
+func TestMirror(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         MirrorConfig
+		fail        int
+		wantTopic   string
+		translation bool
+	}{
+		{name: "same topic", wantTopic: "orders"},
+		{name: "renamed", cfg: MirrorConfig{Topic: func(s string) string { return "eu." + s }}, wantTopic: "eu.orders"},
+		{name: "retried", cfg: MirrorConfig{RetryDelay: time.Millisecond}, fail: 2, wantTopic: "orders"},
+		{name: "offset translation", cfg: MirrorConfig{OffsetTopic: "mirror.offsets"}, wantTopic: "orders", translation: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &Message{Topic: "orders", Partition: 3, Offset: 41, Key: []byte("k"), Value: []byte("v"), Timestamp: time.Now(), Headers: []Header{{"trace", []byte("t1")}}}
+			source := &fakeConsumer{queue: []*Message{src}}
+			target := &flakyProducer{fail: tt.fail}
+			m := NewMirror(source, target, tt.cfg)
+			_ = m.Start(context.Background())
+
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				source.mu.Lock()
+				committed := len(source.committed)
+				source.mu.Unlock()
+				if committed > 0 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("source message never committed")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if err := m.Stop(context.Background()); err != nil {
+				t.Errorf("Stop: %v", err)
+			}
+
+			msgs := target.produced()
+			want := 1
+			if tt.translation {
+				want = 2
+			}
+			if len(msgs) != want {
+				t.Fatalf("produced %d messages, want %d", len(msgs), want)
+			}
+			out := msgs[0]
+			if out.Topic != tt.wantTopic || string(out.Key) != "k" || string(out.Value) != "v" || !out.Timestamp.Equal(src.Timestamp) {
+				t.Errorf("copy = %s %q=%q at %s, want %s with the source key, value and timestamp", out.Topic, out.Key, out.Value, out.Timestamp, tt.wantTopic)
+			}
+			for key, want := range map[string]string{"trace": "t1", HeaderMirrorSourceTopic: "orders", HeaderMirrorSourcePartition: "3", HeaderMirrorSourceOffset: "41"} {
+				if v, _ := out.Header(key); string(v) != want {
+					t.Errorf("header %s = %q, want %q", key, v, want)
+				}
+			}
+			if !tt.translation {
+				return
+			}
+			var tr OffsetTranslation
+			if err := json.Unmarshal(msgs[1].Value, &tr); err != nil {
+				t.Fatal(err)
+			}
+			if msgs[1].Topic != "mirror.offsets" || string(msgs[1].Key) != "orders:3" || tr.SourceOffset != 41 || tr.TargetOffset != 100 || tr.TargetTopic != "orders" {
+				t.Errorf("translation %s %q = %+v, want orders[3]@41 mapped to offset 100", msgs[1].Topic, msgs[1].Key, tr)
+			}
+		})
+	}
+}
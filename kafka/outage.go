@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Outage defaults
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+	defaultMaxOutage  = 5 * time.Minute
+)
+
+// OutageConfig sets how consumers behave while reads keep failing, as when
+// every broker is down: each failed read waits an exponentially growing,
+// jittered backoff before the next one instead of retrying at once. Wrap
+// the consumer in a HealthCheck and register its Ready to also take the
+// instance out of service for the duration.
+type OutageConfig struct {
+	MinBackoff time.Duration `env:"KAFKA_RECONNECT_MIN_BACKOFF" flag:"kafka.reconnect-min-backoff"` // Wait after the first failed read (default 100ms)
+	MaxBackoff time.Duration `env:"KAFKA_RECONNECT_MAX_BACKOFF" flag:"kafka.reconnect-max-backoff"` // Cap of the doubling wait (default 30s)
+	MaxOutage  time.Duration `env:"KAFKA_MAX_OUTAGE" flag:"kafka.max-outage"`                       // How long reads may fail before OnMaxOutage (default 5m)
+
+	// OnMaxOutage runs once per outage when it has lasted MaxOutage, e.g.
+	// to exit so the orchestrator restarts the service elsewhere
+	OnMaxOutage func(outage time.Duration, err error)
+	// Log receives the outage events; the default writes them to the standard logger
+	Log logger.FieldLogger
+}
+
+// outage tracks a run of failed reads of one consumer loop
+type outage struct {
+	cfg       OutageConfig
+	log       logger.FieldLogger
+	since     time.Time // Zero while reads succeed
+	attempts  int
+	exhausted bool // OnMaxOutage has run for this outage
+}
+
+func newOutage(cfg OutageConfig, component string) *outage {
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	cfg.MaxBackoff = max(cfg.MaxBackoff, cfg.MinBackoff)
+	if cfg.MaxOutage <= 0 {
+		cfg.MaxOutage = defaultMaxOutage
+	}
+	var l logger.FieldLogger = stdLog{}
+	if cfg.Log != nil {
+		l = cfg.Log
+	}
+	return &outage{cfg: cfg, log: logger.AddFields(l, "component", component)}
+}
+
+// failed records a failed read and waits out the backoff before the next
+// one; it returns ctx's error when ctx is done first
+func (o *outage) failed(ctx context.Context, err error) error {
+	now := time.Now()
+	if o.since.IsZero() {
+		o.since = now
+	}
+	o.attempts++
+	elapsed := now.Sub(o.since)
+	backoff := o.backoff()
+	o.log.Warnw("kafka read failed, backing off",
+		"error", err, "attempt", o.attempts, "outage_ms", elapsed.Milliseconds(), "backoff_ms", backoff.Milliseconds())
+
+	if elapsed >= o.cfg.MaxOutage && !o.exhausted {
+		o.exhausted = true
+		o.log.Errorw("kafka outage exceeded limit",
+			"error", err, "outage_ms", elapsed.Milliseconds(), "max_outage_ms", o.cfg.MaxOutage.Milliseconds())
+		if o.cfg.OnMaxOutage != nil {
+			o.cfg.OnMaxOutage(elapsed, err)
+		}
+	}
+	return waitUntil(ctx, now.Add(backoff))
+}
+
+// ok records a successful read, ending the outage if there was one
+func (o *outage) ok() {
+	if o.since.IsZero() {
+		return
+	}
+	o.log.Infow("kafka reads recovered", "outage_ms", time.Since(o.since).Milliseconds(), "attempts", o.attempts)
+	o.since, o.attempts, o.exhausted = time.Time{}, 0, false
+}
+
+// backoff doubles from MinBackoff up to MaxBackoff; a random wait between
+// half and all of it keeps consumers from reconnecting in lockstep
+func (o *outage) backoff() time.Duration {
+	d := o.cfg.MaxBackoff
+	if shift := o.attempts - 1; shift < 32 {
+		d = min(o.cfg.MinBackoff<<shift, o.cfg.MaxBackoff)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// stdLog writes outage events to the standard logger when no Log is set
+type stdLog struct{}
+
+func (stdLog) print(level, msg string, kv []interface{}) {
+	log.Printf("%s %s %v\n", level, msg, kv)
+}
+
+func (l stdLog) Debugw(msg string, kv ...interface{}) { l.print("DEBUG", msg, kv) }
+func (l stdLog) Infow(msg string, kv ...interface{})  { l.print("INFO", msg, kv) }
+func (l stdLog) Warnw(msg string, kv ...interface{})  { l.print("WARN", msg, kv) }
+func (l stdLog) Errorw(msg string, kv ...interface{}) { l.print("ERROR", msg, kv) }
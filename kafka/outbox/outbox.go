@@ -0,0 +1,335 @@
+// Package outbox publishes rows of a SQL outbox table to Kafka. Services
+// insert events into the table in the same transaction as their state
+// change; the Poller publishes them and marks them published. A crash
+// between publishing and marking republishes the row, so every message
+// carries the row ID in the outbox-id header for consumers to deduplicate.
+// A row Kafka keeps rejecting is parked after Config.MaxAttempts tries, with
+// failed_at and last_error set, so it stops holding back the rows after it.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/upendravikram5/upendra/kafka"
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+// HeaderOutboxID carries the outbox row ID of a published message
+const HeaderOutboxID = "outbox-id"
+
+// Schema creates the outbox table on Postgres; %s is replaced by the table name
+const Schema = `CREATE TABLE IF NOT EXISTS %s (
+	id           BIGSERIAL   PRIMARY KEY,
+	topic        TEXT        NOT NULL,
+	key          BYTEA,
+	payload      BYTEA       NOT NULL,
+	headers      JSONB,
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	published_at TIMESTAMPTZ,
+	attempts     INT         NOT NULL DEFAULT 0,
+	failed_at    TIMESTAMPTZ,
+	last_error   TEXT
+)`
+
+// MigrateAttempts adds the columns used to park failing rows to a table
+// created from an earlier Schema; %s is replaced by the table name
+const MigrateAttempts = `ALTER TABLE %s
+	ADD COLUMN IF NOT EXISTS attempts   INT NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS failed_at  TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS last_error TEXT`
+
+// SQL dialects, which differ in placeholder syntax
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+)
+
+var (
+	metricsOnce sync.Once
+
+	publishedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "outbox",
+		Name:      "published_total",
+		Help:      "Outbox rows published to Kafka, by topic.",
+	}, []string{"topic"})
+
+	failuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "outbox",
+		Name:      "failures_total",
+		Help:      "Polls that failed to publish or mark rows.",
+	})
+
+	parkedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "outbox",
+		Name:      "parked_total",
+		Help:      "Outbox rows given up on after failing to publish, by topic.",
+	}, []string{"topic"})
+
+	lagSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "outbox",
+		Name:      "lag_seconds",
+		Help:      "Age of the oldest row in the last batch when it was published.",
+	})
+)
+
+// Config configures a Poller
+type Config struct {
+	Table     string        // Outbox table (default "outbox")
+	Dialect   string        // DialectPostgres (default) or DialectMySQL
+	BatchSize int           // Rows per poll (default 100)
+	Interval  time.Duration // Wait between polls when the table is drained (default 1s)
+
+	// MaxAttempts is how many failed publishes park a row (default 10).
+	// Retriable errors, as while the brokers are down, don't count; rows
+	// whose headers can't be decoded are parked on the first failure.
+	MaxAttempts int
+
+	// Exclusive makes instances take turns with a Postgres advisory lock, so
+	// rows reach Kafka in ID order even with several instances running
+	Exclusive bool
+}
+
+// Poller publishes outbox rows; it implements lifecycle.Component. Each batch
+// is published in ID order and stops at its first failure. Several instances
+// may run at once: rows are claimed with FOR UPDATE SKIP LOCKED, so each
+// instance publishes a different batch and the batches are not ordered with
+// respect to each other. Set Config.Exclusive, or run a single instance, when
+// consumers rely on ID order; parked rows are skipped either way.
+type Poller struct {
+	db       *sql.DB
+	producer kafka.Producer
+	log      logger.Logger
+	cfg      Config
+
+	selectSQL string
+	markSQL   string
+	failSQL   string
+	lockSQL   string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPoller creates a poller reading from db and publishing through producer
+func NewPoller(db *sql.DB, producer kafka.Producer, log logger.Logger, cfg Config) *Poller {
+	if cfg.Table == "" {
+		cfg.Table = "outbox"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	metricsOnce.Do(func() { metrics.MustRegister(publishedTotal, failuresTotal, parkedTotal, lagSeconds) })
+
+	p1, p2, p3, p4 := "$1", "$2", "$3", "$4"
+	if cfg.Dialect == DialectMySQL {
+		p1, p2, p3, p4 = "?", "?", "?", "?"
+	}
+	lock := fnv.New64a()
+	lock.Write([]byte(cfg.Table))
+	return &Poller{
+		db:       db,
+		producer: producer,
+		log:      log,
+		cfg:      cfg,
+		selectSQL: "SELECT id, topic, key, payload, headers, created_at, attempts FROM " + cfg.Table +
+			" WHERE published_at IS NULL AND failed_at IS NULL ORDER BY id LIMIT " + p1 + " FOR UPDATE SKIP LOCKED",
+		markSQL: "UPDATE " + cfg.Table + " SET published_at = " + p1 + " WHERE id = " + p2,
+		// failed_at comes first: MySQL sees the columns already set by earlier assignments
+		failSQL: "UPDATE " + cfg.Table + " SET failed_at = CASE WHEN attempts + 1 >= " + p1 + " THEN " + p2 +
+			" END, last_error = " + p3 + ", attempts = attempts + 1 WHERE id = " + p4,
+		lockSQL: "SELECT pg_try_advisory_xact_lock(" + strconv.FormatInt(int64(lock.Sum64()), 10) + ")",
+	}
+}
+
+// Start begins polling in the background
+func (p *Poller) Start(ctx context.Context) error {
+	ctx, p.cancel = context.WithCancel(context.Background())
+	p.done = make(chan struct{})
+	go p.run(ctx)
+	return nil
+}
+
+// Stop ends polling after the batch in progress
+func (p *Poller) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Poller) run(ctx context.Context) {
+	defer close(p.done)
+	for {
+		n, err := p.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			failuresTotal.Inc()
+			p.log.Errorw("outbox poll failed", "table", p.cfg.Table, "error", err)
+		}
+		if n == p.cfg.BatchSize && err == nil {
+			continue // More rows are likely waiting
+		}
+		select {
+		case <-time.After(p.cfg.Interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type row struct {
+	id        int64
+	topic     string
+	key       []byte
+	payload   []byte
+	headers   []byte
+	createdAt time.Time
+	attempts  int
+}
+
+// Poll publishes one batch and returns how many rows were published. Rows
+// are published in order and the batch stops at the first failure, so a
+// failing row is retried before any later one until it is parked.
+func (p *Poller) Poll(ctx context.Context) (int, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if p.cfg.Exclusive {
+		var locked bool
+		if err := tx.QueryRowContext(ctx, p.lockSQL).Scan(&locked); err != nil {
+			return 0, fmt.Errorf("failed to take the outbox lock: %w", err)
+		}
+		if !locked {
+			return 0, nil // Another instance is publishing
+		}
+	}
+
+	rows, err := p.claim(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var publishErr error
+	for _, r := range rows {
+		if publishErr = p.publish(ctx, r); publishErr != nil {
+			if err := p.fail(ctx, tx, r, publishErr); err != nil {
+				return 0, err
+			}
+			break
+		}
+		if _, err := tx.ExecContext(ctx, p.markSQL, time.Now().UTC(), r.id); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox row %d: %w", r.id, err)
+		}
+		if published == 0 {
+			lagSeconds.Set(time.Since(r.createdAt).Seconds())
+		}
+		publishedTotal.WithLabelValues(r.topic).Inc()
+		published++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox marks: %w", err)
+	}
+	if published > 0 {
+		p.log.Debugw("outbox rows published", "table", p.cfg.Table, "count", published)
+	}
+	return published, publishErr
+}
+
+// fail records a failed publish of r, parking it once it has used its
+// attempts or can never be published
+func (p *Poller) fail(ctx context.Context, tx *sql.Tx, r row, cause error) error {
+	if kafka.IsRetriable(cause) {
+		return nil // The row is fine; the brokers are not
+	}
+	maxAttempts := p.cfg.MaxAttempts
+	var bad *badHeadersError
+	if errors.As(cause, &bad) {
+		maxAttempts = 1
+	}
+	if _, err := tx.ExecContext(ctx, p.failSQL, maxAttempts, time.Now().UTC(), cause.Error(), r.id); err != nil {
+		return fmt.Errorf("failed to record the failure of outbox row %d: %w", r.id, err)
+	}
+	if r.attempts+1 >= maxAttempts {
+		parkedTotal.WithLabelValues(r.topic).Inc()
+		p.log.Errorw("outbox row parked", "table", p.cfg.Table, "id", r.id, "attempts", r.attempts+1, "error", cause)
+	}
+	return nil
+}
+
+// badHeadersError marks a row whose headers column can't be decoded
+type badHeadersError struct {
+	id  int64
+	err error
+}
+
+func (e *badHeadersError) Error() string {
+	return fmt.Sprintf("invalid headers on outbox row %d: %v", e.id, e.err)
+}
+
+func (e *badHeadersError) Unwrap() error { return e.err }
+
+func (p *Poller) claim(ctx context.Context, tx *sql.Tx) ([]row, error) {
+	rs, err := tx.QueryContext(ctx, p.selectSQL, p.cfg.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rs.Close()
+
+	var rows []row
+	for rs.Next() {
+		var r row
+		if err := rs.Scan(&r.id, &r.topic, &r.key, &r.payload, &r.headers, &r.createdAt, &r.attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox row: %w", err)
+		}
+		rows = append(rows, r)
+	}
+	return rows, rs.Err()
+}
+
+func (p *Poller) publish(ctx context.Context, r row) error {
+	msg := &kafka.Message{Topic: r.topic, Key: r.key, Value: r.payload}
+	if len(r.headers) > 0 {
+		var headers map[string]string
+		if err := json.Unmarshal(r.headers, &headers); err != nil {
+			return &badHeadersError{id: r.id, err: err}
+		}
+		for k, v := range headers {
+			msg.SetHeader(k, []byte(v))
+		}
+	}
+	msg.SetHeader(HeaderOutboxID, []byte(strconv.FormatInt(r.id, 10)))
+	if err := p.producer.Produce(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish outbox row %d: %w", r.id, err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package outbox
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/upendravikram5/upendra/kafka"
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// producer records messages, failing those whose key is in fail
+type producer struct {
+	msgs []*kafka.Message
+	fail map[string]error
+}
+
+func (p *producer) Produce(_ context.Context, msg *kafka.Message) error {
+	if err := p.fail[string(msg.Key)]; err != nil {
+		return err
+	}
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *producer) Close() error { return nil }
+
+// retriable is a produce error worth retrying, as while the brokers are down
+type retriable struct{}
+
+func (retriable) Error() string     { return "brokers unavailable" }
+func (retriable) IsRetriable() bool { return true }
+
+var columns = []string{"id", "topic", "key", "payload", "headers", "created_at", "attempts"}
+
+func TestPoll(t *testing.T) {
+	created := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name      string
+		rows      [][]driver.Value
+		fail      map[string]error
+		published int
+		failed    int64 // Row whose failure is recorded; 0 for none
+		parkAfter int   // Attempts that park it
+		wantErr   bool
+	}{
+		{
+			name: "batch",
+			rows: [][]driver.Value{
+				{1, "orders", []byte("a"), []byte("{}"), []byte(`{"trace":"t1"}`), created, 0},
+				{2, "orders", []byte("b"), []byte("{}"), nil, created, 0},
+			},
+			published: 2,
+		},
+		{
+			name: "stops at the first failure",
+			rows: [][]driver.Value{
+				{1, "orders", []byte("a"), []byte("{}"), nil, created, 0},
+				{2, "orders", []byte("b"), []byte("{}"), nil, created, 3},
+				{3, "orders", []byte("c"), []byte("{}"), nil, created, 0},
+			},
+			fail:      map[string]error{"b": errors.New("record too large")},
+			published: 1, failed: 2, parkAfter: 10, wantErr: true,
+		},
+		{
+			name:    "retriable failure not counted",
+			rows:    [][]driver.Value{{1, "orders", []byte("a"), []byte("{}"), nil, created, 0}},
+			fail:    map[string]error{"a": retriable{}},
+			wantErr: true,
+		},
+		{
+			name:   "bad headers parked at once",
+			rows:   [][]driver.Value{{1, "orders", []byte("a"), []byte("{}"), []byte("not json"), created, 0}},
+			failed: 1, parkAfter: 1, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			rows := sqlmock.NewRows(columns)
+			for _, r := range tt.rows {
+				rows.AddRow(r...)
+			}
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT id, topic, key, payload, headers, created_at, attempts FROM outbox").WithArgs(100).WillReturnRows(rows)
+			for i := 0; i < tt.published; i++ {
+				mock.ExpectExec("UPDATE outbox SET published_at").WithArgs(sqlmock.AnyArg(), tt.rows[i][0]).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tt.failed != 0 {
+				mock.ExpectExec("UPDATE outbox SET failed_at").WithArgs(tt.parkAfter, sqlmock.AnyArg(), sqlmock.AnyArg(), tt.failed).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			p := &producer{fail: tt.fail}
+			n, err := NewPoller(db, p, logger.Nop(), Config{}).Poll(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if n != tt.published || len(p.msgs) != tt.published {
+				t.Errorf("published %d (%d produced), want %d", n, len(p.msgs), tt.published)
+			}
+			for i, msg := range p.msgs {
+				if id, _ := msg.Header(HeaderOutboxID); string(id) != fmt.Sprint(tt.rows[i][0]) {
+					t.Errorf("message %d: outbox-id = %q, want %v", i, id, tt.rows[i][0])
+				}
+			}
+			if len(p.msgs) > 0 && tt.rows[0][4] != nil {
+				if v, _ := p.msgs[0].Header("trace"); string(v) != "t1" {
+					t.Errorf("trace header = %q, want the row's headers copied", v)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPollExclusive(t *testing.T) {
+	for _, locked := range []bool{true, false} {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(locked))
+		if locked {
+			mock.ExpectQuery("SELECT id").WillReturnRows(sqlmock.NewRows(columns))
+			mock.ExpectCommit()
+		} else {
+			mock.ExpectRollback() // Another instance is publishing
+		}
+
+		if _, err := NewPoller(db, &producer{}, logger.Nop(), Config{Exclusive: true}).Poll(context.Background()); err != nil {
+			t.Errorf("locked %v: %v", locked, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("locked %v: %v", locked, err)
+		}
+		db.Close()
+	}
+}
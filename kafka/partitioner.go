@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// Partitioner names accepted by Config.Partitioner
+const (
+	PartitionerMurmur2    = "murmur2"    // Key hash compatible with the Java client; keyless messages spread round-robin
+	PartitionerRoundRobin = "roundrobin" // Ignores keys and cycles through partitions
+	PartitionerSticky     = "sticky"     // Java client default: murmur2 for keys, keyless batches stick to one partition
+)
+
+// PartitionerFunc picks the partition, in [0, numPartitions), for msg
+type PartitionerFunc func(msg *Message, numPartitions int) int32
+
+// stickyBatch is how many keyless messages go to a partition before the
+// sticky partitioner moves on; it approximates the Java per-batch switch
+const stickyBatch = 100
+
+// partitioner resolves the configured partitioner; nil leaves the choice to the backend
+func (c *Config) partitioner() (PartitionerFunc, error) {
+	if c.PartitionFunc != nil {
+		return c.PartitionFunc, nil
+	}
+	switch c.Partitioner {
+	case "":
+		return nil, nil
+	case PartitionerMurmur2:
+		return Murmur2Partitioner(RoundRobinPartitioner()), nil
+	case PartitionerRoundRobin:
+		return RoundRobinPartitioner(), nil
+	case PartitionerSticky:
+		return StickyPartitioner(), nil
+	}
+	return nil, fmt.Errorf("unknown partitioner %q", c.Partitioner)
+}
+
+// Murmur2 is the hash the Java client uses for record keys
+func Murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// Murmur2Partitioner places keyed messages where the Java client would and
+// hands keyless ones to keyless
+func Murmur2Partitioner(keyless PartitionerFunc) PartitionerFunc {
+	return func(msg *Message, numPartitions int) int32 {
+		if msg.Key == nil {
+			return keyless(msg, numPartitions)
+		}
+		return (Murmur2(msg.Key) & 0x7fffffff) % int32(numPartitions)
+	}
+}
+
+// RoundRobinPartitioner cycles through the partitions regardless of key
+func RoundRobinPartitioner() PartitionerFunc {
+	var next atomic.Uint32
+	return func(_ *Message, numPartitions int) int32 {
+		return int32((next.Add(1) - 1) % uint32(numPartitions))
+	}
+}
+
+// StickyPartitioner hashes keys with murmur2 and sends keyless messages to
+// one partition per topic for stickyBatch messages, which keeps batches full
+func StickyPartitioner() PartitionerFunc {
+	type sticky struct {
+		partition int32
+		count     int
+	}
+	var (
+		mu     sync.Mutex
+		topics = make(map[string]*sticky)
+	)
+	keyless := func(msg *Message, numPartitions int) int32 {
+		mu.Lock()
+		defer mu.Unlock()
+		s, ok := topics[msg.Topic]
+		if !ok || s.count >= stickyBatch || int(s.partition) >= numPartitions {
+			s = &sticky{partition: int32(rand.Intn(numPartitions))}
+			topics[msg.Topic] = s
+		}
+		s.count++
+		return s.partition
+	}
+	return Murmur2Partitioner(keyless)
+}
@@ -0,0 +1,61 @@
+package kafka
+
+import "testing"
+
+// TestMurmur2 checks the hash against the vectors of the Java client's UtilsTest
+func TestMurmur2(t *testing.T) {
+	tests := []struct {
+		key  string
+		want int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+	for _, tt := range tests {
+		if got := Murmur2([]byte(tt.key)); got != tt.want {
+			t.Errorf("Murmur2(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMurmur2Partitioner(t *testing.T) {
+	keyless := func(*Message, int) int32 { return -1 }
+	p := Murmur2Partitioner(keyless)
+	tests := []struct {
+		name string
+		key  []byte
+		want int32
+	}{
+		{"keyed", []byte("foobar"), (-790332482 & 0x7fffffff) % 12},
+		{"negative hash", []byte("21"), (-973932308 & 0x7fffffff) % 12},
+		{"empty key is hashed", []byte{}, (Murmur2(nil) & 0x7fffffff) % 12},
+		{"keyless", nil, -1},
+	}
+	for _, tt := range tests {
+		if got := p(&Message{Key: tt.key}, 12); got != tt.want {
+			t.Errorf("%s: partition = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStickyPartitioner(t *testing.T) {
+	p := StickyPartitioner()
+	first := p(&Message{Topic: "orders"}, 6)
+	for i := 1; i < stickyBatch; i++ {
+		if got := p(&Message{Topic: "orders"}, 6); got != first {
+			t.Fatalf("keyless message %d went to partition %d, want %d for the whole batch", i, got, first)
+		}
+	}
+	if got, want := p(&Message{Topic: "orders", Key: []byte("foobar")}, 6), (Murmur2([]byte("foobar"))&0x7fffffff)%6; got != want {
+		t.Errorf("keyed message went to partition %d, want %d", got, want)
+	}
+	for i := 0; i < 1000; i++ {
+		if got := p(&Message{Topic: "orders"}, 2); got < 0 || got >= 2 {
+			t.Fatalf("partition %d out of range after the partition count shrank", got)
+		}
+	}
+}
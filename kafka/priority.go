@@ -0,0 +1,200 @@
+package kafka
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// consumePriority queues fetched messages per topic and lets Workers
+// goroutines take from the queues by weighted round robin, so a topic with
+// weight 10 gets ten messages handled for every one of a weight 1 topic
+// while both have a backlog. A partition is handled by one worker at a time,
+// which keeps its messages in order.
+func consumePriority(ctx, hctx context.Context, c Consumer, cfg *Config, handler Handler) error {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	s := newPriorityScheduler(cfg, laneBuffer*workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msg, ok := s.next()
+				if !ok {
+					return
+				}
+				if hctx.Err() == nil { // Past the drain timeout the rest stays uncommitted
+					handle(hctx, c, cfg, handler, msg)
+				}
+				s.done(msg)
+			}
+		}()
+	}
+	defer func() {
+		s.close() // Workers finish what is already queued
+		wg.Wait()
+	}()
+
+	down := newOutage(cfg.Outage, "consumer")
+	for {
+		msg, err := c.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := down.failed(ctx, err); err != nil {
+				return err
+			}
+			continue
+		}
+		down.ok()
+		if !s.push(ctx, msg) {
+			return ctx.Err() // Not queued, so neither handled nor committed
+		}
+	}
+}
+
+// topicQueue is the backlog of one topic with its round-robin state
+type topicQueue struct {
+	msgs    []*Message
+	weight  int
+	current int // Smooth weighted round-robin credit
+}
+
+type priorityScheduler struct {
+	weights  func(topic string) int
+	capacity int // Per topic
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queues map[string]*topicQueue
+	busy   map[TopicPartition]bool // Partitions with a message being handled
+	closed bool
+}
+
+func newPriorityScheduler(cfg *Config, capacity int) *priorityScheduler {
+	s := &priorityScheduler{
+		weights: func(topic string) int {
+			if w := cfg.Topics[topic].Weight; w > 0 {
+				return w
+			}
+			return 1
+		},
+		capacity: capacity,
+		queues:   make(map[string]*topicQueue),
+		busy:     make(map[TopicPartition]bool),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// push queues msg, waiting while its topic's queue is full
+func (s *priorityScheduler) push(ctx context.Context, msg *Message) bool {
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	topic := baseTopic(msg) // Retry tiers share the priority of their topic
+	q, ok := s.queues[topic]
+	if !ok {
+		q = &topicQueue{weight: s.weights(topic)}
+		s.queues[topic] = q
+	}
+	for len(q.msgs) >= s.capacity {
+		if ctx.Err() != nil {
+			return false
+		}
+		s.cond.Wait()
+	}
+	q.msgs = append(q.msgs, msg)
+	s.cond.Broadcast()
+	return true
+}
+
+// next blocks until a message of an idle partition is available, or returns
+// false once the scheduler is closed and drained
+func (s *priorityScheduler) next() (*Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if msg := s.pick(); msg != nil {
+			s.busy[TopicPartition{Topic: msg.Topic, Partition: msg.Partition}] = true
+			s.cond.Broadcast() // Room in the queue for the fetcher
+			return msg, true
+		}
+		if s.closed && s.empty() {
+			return nil, false
+		}
+		s.cond.Wait()
+	}
+}
+
+// pick removes the next message by smooth weighted round robin among the
+// topics that have a message of an idle partition; callers hold mu
+func (s *priorityScheduler) pick() *Message {
+	var best *topicQueue
+	bestIdx, total := -1, 0
+	for _, q := range s.queues {
+		i := s.ready(q)
+		if i < 0 {
+			continue
+		}
+		q.current += q.weight
+		total += q.weight
+		if best == nil || q.current > best.current {
+			best, bestIdx = q, i
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.current -= total
+	msg := best.msgs[bestIdx]
+	best.msgs = append(best.msgs[:bestIdx], best.msgs[bestIdx+1:]...)
+	return msg
+}
+
+// ready returns the index of the first queued message whose partition is
+// idle; earlier messages of a busy partition keep later ones waiting
+func (s *priorityScheduler) ready(q *topicQueue) int {
+	for i, msg := range q.msgs {
+		if !s.busy[TopicPartition{Topic: msg.Topic, Partition: msg.Partition}] {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *priorityScheduler) empty() bool {
+	for _, q := range s.queues {
+		if len(q.msgs) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// done releases the partition of a handled message
+func (s *priorityScheduler) done(msg *Message) {
+	s.mu.Lock()
+	delete(s.busy, TopicPartition{Topic: msg.Topic, Partition: msg.Partition})
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+func (s *priorityScheduler) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
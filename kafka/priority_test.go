@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPrioritySchedulerWeights(t *testing.T) {
+	cfg := &Config{Topics: map[string]TopicConfig{"payments": {Weight: 10}}}
+	s := newPriorityScheduler(cfg, 100)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		s.push(ctx, &Message{Topic: "payments", Offset: int64(i)})
+		s.push(ctx, &Message{Topic: "emails", Offset: int64(i)})
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 11; i++ {
+		msg, ok := s.next()
+		if !ok {
+			t.Fatal("scheduler closed early")
+		}
+		counts[msg.Topic]++
+		s.done(msg)
+	}
+	if counts["payments"] != 10 || counts["emails"] != 1 {
+		t.Errorf("handled %v in one round, want 10 payments for 1 email", counts)
+	}
+}
+
+func TestPrioritySchedulerPartitionOrder(t *testing.T) {
+	s := newPriorityScheduler(&Config{}, 100)
+	ctx := context.Background()
+	first := &Message{Topic: "orders", Partition: 0, Offset: 1}
+	second := &Message{Topic: "orders", Partition: 0, Offset: 2}
+	other := &Message{Topic: "orders.retry-1m", Partition: 1, Offset: 9, Headers: []Header{{HeaderOriginalTopic, []byte("orders")}}}
+	for _, msg := range []*Message{first, second, other} {
+		s.push(ctx, msg)
+	}
+
+	tests := []struct {
+		name string
+		done *Message // Released before taking the next message
+		want *Message
+	}{
+		{name: "oldest first", want: first},
+		{name: "busy partition skipped", want: other},
+		{name: "released partition", done: first, want: second},
+	}
+	for _, tt := range tests {
+		if tt.done != nil {
+			s.done(tt.done)
+		}
+		msg, ok := s.next()
+		if !ok || msg != tt.want {
+			t.Fatalf("%s: next = %v, want offset %d", tt.name, msg, tt.want.Offset)
+		}
+	}
+
+	s.close()
+	s.done(second)
+	s.done(other)
+	if msg, ok := s.next(); ok {
+		t.Errorf("next after close = offset %d, want none", msg.Offset)
+	}
+}
+
+func TestPrioritySchedulerFullQueue(t *testing.T) {
+	s := newPriorityScheduler(&Config{}, 1)
+	if !s.push(context.Background(), &Message{Topic: "orders"}) {
+		t.Fatal("push into an empty queue failed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if s.push(ctx, &Message{Topic: "orders"}) {
+		t.Error("push into a full queue succeeded")
+	}
+	if !s.push(context.Background(), &Message{Topic: "emails"}) {
+		t.Error("a full queue blocked another topic")
+	}
+}
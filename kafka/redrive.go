@@ -0,0 +1,143 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// HeaderRedrivenFrom records the dead-letter position a re-driven message
+// was copied from, as "topic[partition]@offset"
+const HeaderRedrivenFrom = "redriven-from"
+
+// defaultRedriveIdle is how long Redrive waits for another message before
+// treating the dead-letter topic as drained
+const defaultRedriveIdle = 10 * time.Second
+
+// RedriveConfig selects the dead-letter messages Redrive re-produces and how fast
+type RedriveConfig struct {
+	ErrorCodes  []string      // Only messages whose retry-error-code header is one of these; empty selects all
+	Since       time.Time     // Only messages with a record timestamp at or after Since; zero is unbounded
+	Until       time.Time     // Only messages with a record timestamp before Until; zero is unbounded
+	Topic       string        // Target topic; empty sends each message back to its retry-original-topic
+	Rate        float64       // Messages produced per second; 0 is unlimited
+	Limit       int           // Stop after re-producing this many; 0 is unlimited
+	IdleTimeout time.Duration // Stop when no message arrives for this long (default 10s)
+	DryRun      bool          // Report what would be re-produced without producing or committing
+}
+
+// RedriveStats counts what Redrive did with the messages it read
+type RedriveStats struct {
+	Read     int `json:"read"`
+	Redriven int `json:"redriven"` // Produced, or selected in a dry run
+	Skipped  int `json:"skipped"`  // Filtered out, or with no topic to send them to
+}
+
+// Redrive reads a dead-letter topic from dlq and re-produces the selected
+// messages to their original topic, with the retry headers removed so they
+// get the full retry chain again. It returns once dlq has had no message
+// for cfg.IdleTimeout, cfg.Limit is reached or ctx is done, and stops with
+// an error at the first message that fails to produce.
+//
+// Offsets are committed after each re-produced message, so a later run
+// with the same consumer group resumes where this one stopped, starting
+// with the failed message; messages filtered out before that point are
+// passed over for the group. A dry run commits nothing.
+func Redrive(ctx context.Context, dlq Consumer, producer Producer, cfg RedriveConfig) (RedriveStats, error) {
+	var stats RedriveStats
+	idle := cfg.IdleTimeout
+	if idle <= 0 {
+		idle = defaultRedriveIdle
+	}
+	var interval time.Duration
+	if cfg.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / cfg.Rate)
+	}
+	codes := make(map[string]bool, len(cfg.ErrorCodes))
+	for _, c := range cfg.ErrorCodes {
+		codes[c] = true
+	}
+
+	var next time.Time
+	for cfg.Limit <= 0 || stats.Redriven < cfg.Limit {
+		rctx, cancel := context.WithTimeout(ctx, idle)
+		msg, err := dlq.ReadMessage(rctx)
+		drained := rctx.Err() != nil
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return stats, ctx.Err()
+			}
+			if drained {
+				return stats, nil
+			}
+			log.Printf("Redrive consumer error: %v\n", err)
+			continue
+		}
+		stats.Read++
+
+		if !cfg.selects(msg, codes) {
+			stats.Skipped++
+			continue
+		}
+		out := redriveMessage(msg, cfg.Topic)
+		if out.Topic == "" {
+			log.Printf("Redrive skipped %s[%d]@%d: no %s header\n", msg.Topic, msg.Partition, msg.Offset, HeaderOriginalTopic)
+			stats.Skipped++
+			continue
+		}
+		if cfg.DryRun {
+			log.Printf("Redrive (dry run) would send %s[%d]@%d to %s\n", msg.Topic, msg.Partition, msg.Offset, out.Topic)
+			stats.Redriven++
+			continue
+		}
+
+		if interval > 0 {
+			if err := waitUntil(ctx, next); err != nil {
+				return stats, err
+			}
+			next = time.Now().Add(interval)
+		}
+		if err := producer.Produce(ctx, out); err != nil {
+			return stats, fmt.Errorf("failed to redrive %s[%d]@%d to %s: %w", msg.Topic, msg.Partition, msg.Offset, out.Topic, err)
+		}
+		stats.Redriven++
+		if err := dlq.CommitMessage(ctx, msg); err != nil {
+			log.Printf("Failed to commit offset: %v", err)
+		}
+	}
+	return stats, nil
+}
+
+// selects reports whether msg passes the error code and time filters
+func (cfg RedriveConfig) selects(msg *Message, codes map[string]bool) bool {
+	if len(codes) > 0 && !codes[msg.headerString(HeaderRetryErrorCode)] {
+		return false
+	}
+	if !cfg.Since.IsZero() && msg.Timestamp.Before(cfg.Since) {
+		return false
+	}
+	if !cfg.Until.IsZero() && !msg.Timestamp.Before(cfg.Until) {
+		return false
+	}
+	return true
+}
+
+// redriveMessage copies msg for its original topic, or topic when set,
+// dropping the headers of its earlier trip through the retry chain
+func redriveMessage(msg *Message, topic string) *Message {
+	if topic == "" {
+		topic = msg.headerString(HeaderOriginalTopic)
+	}
+	out := &Message{Topic: topic, Key: msg.Key, Value: msg.Value}
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case HeaderRetryCount, HeaderRetryNotBefore, HeaderRetryError, HeaderRetryErrorCode, HeaderOriginalTopic:
+			continue
+		}
+		out.Headers = append(out.Headers, h)
+	}
+	out.SetHeader(HeaderRedrivenFrom, []byte(fmt.Sprintf("%s[%d]@%d", msg.Topic, msg.Partition, msg.Offset)))
+	return out
+}
@@ -0,0 +1,299 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/upendravikram5/upendra/errcode"
+)
+
+// Headers written on messages moved to a retry or dead-letter topic
+const (
+	HeaderOriginalTopic  = "retry-original-topic"
+	HeaderRetryNotBefore = "retry-not-before"
+	HeaderRetryError     = "retry-error"
+	HeaderRetryErrorCode = "retry-error-code" // errcode code of the error, when it has one
+)
+
+// RetryTier is one delayed retry topic, e.g. {"orders.retry-5m", 5 * time.Minute}
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
+}
+
+// RetryConfig describes the tiered retry-topic chain. A message whose handler
+// fails is republished to the next tier; after the last tier it goes to
+// DLQTopic, or the error is returned when no DLQ is configured. Consume
+// leaves a message uncommitted when it ran out of tiers without a DLQ or
+// could not be republished.
+type RetryConfig struct {
+	Tiers     []RetryTier
+	DLQTopic  string
+	Overrides map[string]TopicConfig // Per-topic MaxRetries and DLQTopic, usually Config.Topics; Consume defaults it to Config.Topics
+	Producer  Producer               // Publishes to the tiers and the DLQ when set as Config.Retry
+}
+
+// enabled reports whether Consume applies the retry chain
+func (rc RetryConfig) enabled() bool {
+	return len(rc.Tiers) > 0 || rc.DLQTopic != ""
+}
+
+// limits returns the number of tiers to use and the DLQ for msg's topic
+func (rc RetryConfig) limits(msg *Message) (tiers int, dlq string) {
+	tiers, dlq = len(rc.Tiers), rc.DLQTopic
+	tc, ok := rc.Overrides[baseTopic(msg)]
+	if !ok {
+		return tiers, dlq
+	}
+	if tc.MaxRetries != nil && *tc.MaxRetries < tiers {
+		tiers = *tc.MaxRetries
+	}
+	if tc.DLQTopic != "" {
+		dlq = tc.DLQTopic
+	}
+	return tiers, dlq
+}
+
+// Topics returns the retry topics the consumer must subscribe to alongside the main topics
+func (rc RetryConfig) Topics() []string {
+	topics := make([]string, 0, len(rc.Tiers))
+	for _, t := range rc.Tiers {
+		topics = append(topics, t.Topic)
+	}
+	return topics
+}
+
+// tier returns the index of the retry tier that owns topic, or -1 for a main topic
+func (rc RetryConfig) tier(topic string) int {
+	for i, t := range rc.Tiers {
+		if t.Topic == topic {
+			return i
+		}
+	}
+	return -1
+}
+
+// Retrying wraps next with the retry-topic pattern, so failures back off on
+// the retry topics instead of blocking the main partition. Consume applies it
+// itself when Config.Retry is set, and then holds messages of a retry tier
+// with their partition paused until they are due. Used on its own, Retrying
+// waits for a message's scheduled time on the calling goroutine, stalling
+// every other partition of the consumer meanwhile; give each tier its own
+// consumer in that case.
+func Retrying(producer Producer, rc RetryConfig, next Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		tier := rc.tier(msg.Topic)
+		if tier >= 0 {
+			if err := waitUntil(ctx, retryNotBefore(msg, rc.Tiers[tier].Delay)); err != nil {
+				return err
+			}
+		}
+
+		handlerErr := next(ctx, msg)
+		if handlerErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return uncommitted{handlerErr} // Shutting down; the message is read again
+		}
+
+		retry := retryMessage(msg, handlerErr)
+		maxTiers, dlq := rc.limits(msg)
+		if nextTier := tier + 1; nextTier < maxTiers {
+			retry.Topic = rc.Tiers[nextTier].Topic
+			retry.SetHeader(HeaderRetryNotBefore, []byte(time.Now().Add(rc.Tiers[nextTier].Delay).UTC().Format(time.RFC3339Nano)))
+		} else if dlq != "" {
+			retry.Topic = dlq
+		} else {
+			return uncommitted{handlerErr} // Out of tiers with nowhere to park it
+		}
+
+		if err := producer.Produce(ctx, retry); err != nil {
+			return uncommitted{fmt.Errorf("failed to publish to %s after handler error %v: %w", retry.Topic, handlerErr, err)}
+		}
+		log.Printf("Handler failed for %s[%d]@%d, moved to %s: %v\n", msg.Topic, msg.Partition, msg.Offset, retry.Topic, handlerErr)
+		return nil
+	}
+}
+
+// retryMessage copies msg for republishing with an incremented retry count
+func retryMessage(msg *Message, cause error) *Message {
+	retry := &Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: append([]Header(nil), msg.Headers...),
+	}
+	if _, ok := msg.Header(HeaderOriginalTopic); !ok {
+		retry.SetHeader(HeaderOriginalTopic, []byte(msg.Topic))
+	}
+	retry.SetRetryCount(msg.RetryCount() + 1)
+	retry.SetHeader(HeaderRetryError, []byte(cause.Error()))
+	if code := errcode.Code(cause); code != "" {
+		retry.SetHeader(HeaderRetryErrorCode, []byte(code))
+	}
+	return retry
+}
+
+// retryNotBefore returns when a retried message becomes eligible again,
+// falling back to the record timestamp plus the tier delay
+func retryNotBefore(msg *Message, delay time.Duration) time.Time {
+	if v, ok := msg.Header(HeaderRetryNotBefore); ok {
+		if t, err := time.Parse(time.RFC3339Nano, string(v)); err == nil {
+			return t
+		}
+	}
+	return msg.Timestamp.Add(delay)
+}
+
+// Pauser is implemented by consumers that can stop and restart fetching from
+// assigned partitions while staying in the group
+type Pauser interface {
+	Pause(tps ...TopicPartition) error
+	Resume(tps ...TopicPartition) error
+}
+
+// heldMessage is a retry-tier message read before its scheduled time
+type heldMessage struct {
+	msg       *Message
+	notBefore time.Time
+}
+
+// retryGate delays the messages of retry tiers until their scheduled time
+// without blocking the other partitions: the partition of an early message is
+// paused and the message held while reads go on, then the partition is sought
+// past it and resumed once it is returned. Messages of the partition fetched
+// before the pause took effect are dropped, to be read again after the seek.
+// Backends without Pauser and Seeker get the message after an inline wait.
+type retryGate struct {
+	Consumer
+	rc     RetryConfig
+	pauser Pauser
+	seeker Seeker
+	held   map[TopicPartition]heldMessage
+}
+
+func newRetryGate(c Consumer, rc RetryConfig) *retryGate {
+	g := &retryGate{Consumer: c, rc: rc, held: make(map[TopicPartition]heldMessage)}
+	pauser, canPause := unwrapConsumer[Pauser](c)
+	seeker, canSeek := unwrapConsumer[Seeker](c)
+	if canPause && canSeek {
+		g.pauser, g.seeker = pauser, seeker
+	}
+	return g
+}
+
+// ReadMessage returns the next main-topic message or due retry message
+func (g *retryGate) ReadMessage(ctx context.Context) (*Message, error) {
+	for {
+		msg, err := g.release(ctx)
+		if msg != nil || err != nil {
+			return msg, err
+		}
+
+		readCtx, cancel := ctx, context.CancelFunc(func() {})
+		if at, ok := g.nextDue(); ok {
+			readCtx, cancel = context.WithDeadline(ctx, at)
+		}
+		msg, err = g.Consumer.ReadMessage(readCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && readCtx.Err() != nil {
+				continue // A held message is due
+			}
+			return nil, err
+		}
+
+		tier := g.rc.tier(msg.Topic)
+		if tier < 0 {
+			return msg, nil
+		}
+		tp := TopicPartition{Topic: msg.Topic, Partition: msg.Partition}
+		if _, ok := g.held[tp]; ok {
+			continue // Fetched before the pause; read again after the seek
+		}
+		notBefore := retryNotBefore(msg, g.rc.Tiers[tier].Delay)
+		if !time.Now().Before(notBefore) {
+			return msg, nil
+		}
+		if g.pauser == nil {
+			if err := waitUntil(ctx, notBefore); err != nil {
+				return nil, err
+			}
+			return msg, nil
+		}
+		if err := g.pauser.Pause(tp); err != nil {
+			return nil, fmt.Errorf("failed to pause %s[%d]: %w", tp.Topic, tp.Partition, err)
+		}
+		g.held[tp] = heldMessage{msg: msg, notBefore: notBefore}
+	}
+}
+
+// release returns the earliest held message that is due, repositioning and
+// resuming its partition, or nil when none is
+func (g *retryGate) release(ctx context.Context) (*Message, error) {
+	now := time.Now()
+	for tp, h := range g.held {
+		if now.Before(h.notBefore) {
+			continue
+		}
+		delete(g.held, tp)
+		if err := g.seeker.Seek(ctx, tp, h.msg.Offset+1); err != nil {
+			return nil, fmt.Errorf("failed to seek %s[%d] to %d: %w", tp.Topic, tp.Partition, h.msg.Offset+1, err)
+		}
+		if err := g.pauser.Resume(tp); err != nil {
+			return nil, fmt.Errorf("failed to resume %s[%d]: %w", tp.Topic, tp.Partition, err)
+		}
+		if !g.assigned(tp) {
+			continue // Revoked while held; the new owner reads it from the committed offset
+		}
+		return h.msg, nil
+	}
+	return nil, nil
+}
+
+// nextDue returns the earliest scheduled time of the held messages
+func (g *retryGate) nextDue() (time.Time, bool) {
+	var at time.Time
+	for _, h := range g.held {
+		if at.IsZero() || h.notBefore.Before(at) {
+			at = h.notBefore
+		}
+	}
+	return at, !at.IsZero()
+}
+
+// assigned reports whether tp is still assigned, assuming so when the
+// backend can't tell
+func (g *retryGate) assigned(tp TopicPartition) bool {
+	s, ok := unwrapConsumer[Stater](g.Consumer)
+	if !ok {
+		return true
+	}
+	for _, a := range s.Assigned() {
+		if a == tp {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the wrapped consumer
+func (g *retryGate) Unwrap() Consumer { return g.Consumer }
+
+// waitUntil sleeps until t or until ctx is done
+func waitUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProducer records produced messages, failing with err when set
+type fakeProducer struct {
+	mu   sync.Mutex
+	msgs []*Message
+	err  error
+}
+
+func (p *fakeProducer) Produce(_ context.Context, msg *Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+// fakeConsumer returns its queued messages in order, then blocks until ctx is
+// done. It records commits and implements Pauser and Seeker.
+type fakeConsumer struct {
+	mu        sync.Mutex
+	queue     []*Message
+	committed []int64
+	paused    []TopicPartition
+	resumed   []TopicPartition
+	seeks     []int64
+}
+
+func (c *fakeConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	c.mu.Lock()
+	if len(c.queue) > 0 {
+		msg := c.queue[0]
+		c.queue = c.queue[1:]
+		c.mu.Unlock()
+		return msg, nil
+	}
+	c.mu.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *fakeConsumer) CommitMessage(_ context.Context, msg *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.committed = append(c.committed, msg.Offset)
+	return nil
+}
+
+func (c *fakeConsumer) Close() error { return nil }
+
+func (c *fakeConsumer) Pause(tps ...TopicPartition) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = append(c.paused, tps...)
+	return nil
+}
+
+func (c *fakeConsumer) Resume(tps ...TopicPartition) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resumed = append(c.resumed, tps...)
+	return nil
+}
+
+func (c *fakeConsumer) Seek(_ context.Context, _ TopicPartition, offset int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seeks = append(c.seeks, offset)
+	return nil
+}
+
+func TestRetrying(t *testing.T) {
+	errHandler := errors.New("handler failed")
+	zero := 0
+	tiers := []RetryTier{{"orders.retry-1m", time.Minute}, {"orders.retry-5m", 5 * time.Minute}}
+	past := []Header{{HeaderRetryNotBefore, []byte(time.Now().Add(-time.Second).UTC().Format(time.RFC3339Nano))}}
+
+	tests := []struct {
+		name        string
+		rc          RetryConfig
+		msg         *Message
+		handlerErr  error
+		produceErr  error
+		cancel      bool
+		wantTopic   string // Topic republished to; "" for none
+		uncommitted bool
+	}{
+		{name: "success", rc: RetryConfig{Tiers: tiers, DLQTopic: "orders.dlq"}, msg: &Message{Topic: "orders"}},
+		{name: "first tier", rc: RetryConfig{Tiers: tiers, DLQTopic: "orders.dlq"}, msg: &Message{Topic: "orders"}, handlerErr: errHandler, wantTopic: "orders.retry-1m"},
+		{name: "next tier", rc: RetryConfig{Tiers: tiers, DLQTopic: "orders.dlq"}, msg: &Message{Topic: "orders.retry-1m", Headers: past}, handlerErr: errHandler, wantTopic: "orders.retry-5m"},
+		{name: "dead-lettered", rc: RetryConfig{Tiers: tiers, DLQTopic: "orders.dlq"}, msg: &Message{Topic: "orders.retry-5m", Headers: past}, handlerErr: errHandler, wantTopic: "orders.dlq"},
+		{name: "topic override", rc: RetryConfig{Tiers: tiers, DLQTopic: "orders.dlq", Overrides: map[string]TopicConfig{"orders": {MaxRetries: &zero, DLQTopic: "orders.parked"}}}, msg: &Message{Topic: "orders"}, handlerErr: errHandler, wantTopic: "orders.parked"},
+		{name: "no DLQ", rc: RetryConfig{Tiers: tiers}, msg: &Message{Topic: "orders.retry-5m", Headers: past}, handlerErr: errHandler, uncommitted: true},
+		{name: "publish failed", rc: RetryConfig{Tiers: tiers, DLQTopic: "orders.dlq"}, msg: &Message{Topic: "orders"}, handlerErr: errHandler, produceErr: errors.New("broker down"), uncommitted: true},
+		{name: "shutting down", rc: RetryConfig{Tiers: tiers, DLQTopic: "orders.dlq"}, msg: &Message{Topic: "orders"}, handlerErr: context.Canceled, cancel: true, uncommitted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := &fakeProducer{err: tt.produceErr}
+			h := Retrying(p, tt.rc, func(context.Context, *Message) error {
+				if tt.cancel {
+					cancel()
+				}
+				return tt.handlerErr
+			})
+
+			err := h(ctx, tt.msg)
+			var u uncommitted
+			if got := errors.As(err, &u); got != tt.uncommitted {
+				t.Errorf("error %v: uncommitted = %v, want %v", err, got, tt.uncommitted)
+			}
+			if tt.wantTopic == "" {
+				if len(p.msgs) > 0 {
+					t.Errorf("republished to %s, want nothing", p.msgs[0].Topic)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("error = %v, want nil once republished", err)
+			}
+			if len(p.msgs) != 1 {
+				t.Fatalf("republished %d messages, want 1", len(p.msgs))
+			}
+			retry := p.msgs[0]
+			if retry.Topic != tt.wantTopic {
+				t.Errorf("republished to %s, want %s", retry.Topic, tt.wantTopic)
+			}
+			if want := tt.msg.RetryCount() + 1; retry.RetryCount() != want {
+				t.Errorf("retry count = %d, want %d", retry.RetryCount(), want)
+			}
+			if v, _ := retry.Header(HeaderOriginalTopic); baseTopic(tt.msg) != string(v) {
+				t.Errorf("original topic header = %q, want %q", v, baseTopic(tt.msg))
+			}
+			if v, _ := retry.Header(HeaderRetryError); string(v) != tt.handlerErr.Error() {
+				t.Errorf("error header = %q, want %q", v, tt.handlerErr)
+			}
+		})
+	}
+}
+
+func TestRetryGate(t *testing.T) {
+	rc := RetryConfig{Tiers: []RetryTier{{"orders.retry-1m", time.Minute}}}
+	due := time.Now().Add(50 * time.Millisecond)
+	early := &Message{Topic: "orders.retry-1m", Partition: 2, Offset: 7, Headers: []Header{{HeaderRetryNotBefore, []byte(due.UTC().Format(time.RFC3339Nano))}}}
+	sibling := &Message{Topic: "orders.retry-1m", Partition: 2, Offset: 8} // Fetched before the pause took effect
+	main := &Message{Topic: "orders", Offset: 1}
+	c := &fakeConsumer{queue: []*Message{early, sibling, main}}
+	g := newRetryGate(c, rc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i, want := range []*Message{main, early} {
+		msg, err := g.ReadMessage(ctx)
+		if err != nil {
+			t.Fatalf("read %d: %v", i, err)
+		}
+		if msg != want {
+			t.Fatalf("read %d returned %s@%d, want %s@%d", i, msg.Topic, msg.Offset, want.Topic, want.Offset)
+		}
+	}
+	if time.Now().Before(due) {
+		t.Errorf("retry message returned before its scheduled time")
+	}
+	tp := TopicPartition{Topic: "orders.retry-1m", Partition: 2}
+	if len(c.paused) != 1 || c.paused[0] != tp || len(c.resumed) != 1 || c.resumed[0] != tp {
+		t.Errorf("paused %v and resumed %v, want %v once each", c.paused, c.resumed, tp)
+	}
+	if len(c.seeks) != 1 || c.seeks[0] != 8 {
+		t.Errorf("seeks = %v, want [8] so the dropped sibling is read again", c.seeks)
+	}
+}
@@ -0,0 +1,315 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Rotator creates producers and consumers that pick up new credentials
+// without a restart. When the credentials change, through SetCredentials
+// or a change to one of the files Watch follows, every client made by the
+// Rotator is replaced by a fresh one built from the updated configuration:
+//
+//	rot := kafka.NewRotator(cfg)
+//	producer, err := rot.NewProducer()
+//	consumer, err := rot.NewConsumer("orders")
+//	go rot.Watch(ctx, time.Minute) // SASL password file and TLS files
+//
+// A producer switches over once the new client is connected enough to be
+// created; calls in flight finish on the old client, which is closed
+// afterwards. A consumer closes its old client, leaving the consumer group,
+// and rejoins with the new one; offsets of messages read by the old client
+// and not yet committed are left uncommitted, so those messages are
+// delivered again. If the new client can't be created the old one is kept.
+// Closing a client made by the Rotator stops it from being rotated.
+type Rotator struct {
+	mu      sync.Mutex
+	cfg     Config
+	mtimes  map[string]time.Time // Modification times of the watched files
+	clients []rotatingClient
+}
+
+// rotatingClient is a producer or consumer that can replace its client
+type rotatingClient interface {
+	rebuild(cfg *Config) error
+}
+
+// NewRotator creates a rotator for clients configured by a copy of cfg
+func NewRotator(cfg *Config) *Rotator {
+	r := &Rotator{cfg: *cfg}
+	r.mtimes = r.fileTimes()
+	return r
+}
+
+// NewProducer creates a producer that is rebuilt when the credentials change
+func (r *Rotator) NewProducer() (Producer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, err := NewProducer(&r.cfg)
+	if err != nil {
+		return nil, err
+	}
+	rp := &rotatingProducer{rot: r, cur: p, inFlight: &sync.WaitGroup{}}
+	r.clients = append(r.clients, rp)
+	return rp, nil
+}
+
+// NewConsumer creates a consumer subscribed to topics that is rebuilt when
+// the credentials change. Its ReadMessage must not be called concurrently.
+func (r *Rotator) NewConsumer(topics ...string) (Consumer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, err := NewConsumer(&r.cfg, topics...)
+	if err != nil {
+		return nil, err
+	}
+	rc := &rotatingConsumer{rot: r, topics: topics, cur: c}
+	r.clients = append(r.clients, rc)
+	return rc, nil
+}
+
+// SetCredentials replaces the SASL username and password, e.g. from a
+// secret manager's renewal callback, and rebuilds the clients
+func (r *Rotator) SetCredentials(username, password string) error {
+	return r.Update(func(cfg *Config) {
+		cfg.SASLUsername, cfg.SASLPassword = username, password
+		cfg.SASLPasswordFile = "" // The password given wins over the file
+	})
+}
+
+// Update changes the configuration with fn and rebuilds the clients
+func (r *Rotator) Update(fn func(cfg *Config)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cfg := r.cfg
+	fn(&cfg)
+	r.cfg = cfg
+	return r.rebuild()
+}
+
+// Reload rebuilds the clients, re-reading the password and TLS files
+func (r *Rotator) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rebuild()
+}
+
+// remove stops rotating c once it is closed
+func (r *Rotator) remove(c rotatingClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients = slices.DeleteFunc(r.clients, func(x rotatingClient) bool { return x == c })
+}
+
+// rebuild replaces every client; callers hold r.mu
+func (r *Rotator) rebuild() error {
+	var errs []error
+	for _, c := range r.clients {
+		if err := c.rebuild(&r.cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to rotate kafka credentials, keeping the old client where rebuilding failed: %w", err)
+	}
+	log.Printf("Rotated kafka credentials for %d clients\n", len(r.clients))
+	return nil
+}
+
+// Watch checks the SASL password file and the TLS truststore, certificate
+// and key on the interval until ctx is done, and rebuilds the clients when
+// one of them has changed. A failed rebuild is retried on the next check.
+func (r *Rotator) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Rotator) check() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mtimes := r.fileTimes()
+	changed := false
+	for path, t := range mtimes {
+		if !t.Equal(r.mtimes[path]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := r.rebuild(); err != nil {
+		log.Printf("%v\n", err)
+		return
+	}
+	r.mtimes = mtimes
+}
+
+// fileTimes stats the credential files; a missing file, e.g. while a
+// secret mount is being updated, reads as the zero time
+func (r *Rotator) fileTimes() map[string]time.Time {
+	mtimes := make(map[string]time.Time)
+	for _, path := range []string{r.cfg.SASLPasswordFile, r.cfg.SSLTruststoreLocation, r.cfg.SSLCertLocation, r.cfg.SSLKeyLocation} {
+		if path == "" {
+			continue
+		}
+		var t time.Time
+		if info, err := os.Stat(path); err == nil {
+			t = info.ModTime()
+		}
+		mtimes[path] = t
+	}
+	return mtimes
+}
+
+// rotatingProducer sends through the current client
+type rotatingProducer struct {
+	rot *Rotator
+
+	mu       sync.RWMutex
+	cur      Producer
+	inFlight *sync.WaitGroup // Produce calls on cur
+}
+
+func (p *rotatingProducer) Produce(ctx context.Context, msg *Message) error {
+	p.mu.RLock()
+	cur, inFlight := p.cur, p.inFlight
+	inFlight.Add(1)
+	p.mu.RUnlock()
+	defer inFlight.Done()
+	return cur.Produce(ctx, msg)
+}
+
+func (p *rotatingProducer) Close() error {
+	p.rot.remove(p) // First, so no rebuild replaces the client being closed
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight.Wait()
+	return p.cur.Close()
+}
+
+func (p *rotatingProducer) rebuild(cfg *Config) error {
+	next, err := NewProducer(cfg)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	old, inFlight := p.cur, p.inFlight
+	p.cur, p.inFlight = next, &sync.WaitGroup{}
+	p.mu.Unlock()
+	go func() {
+		inFlight.Wait()
+		if err := old.Close(); err != nil {
+			log.Printf("Failed to close rotated producer: %v", err)
+		}
+	}()
+	return nil
+}
+
+// rotatingConsumer reads from the current client
+type rotatingConsumer struct {
+	rot    *Rotator
+	topics []string
+
+	mu        sync.Mutex
+	cur       Consumer
+	cancel    context.CancelFunc               // Interrupts the ReadMessage call on cur
+	revokeFns []func(revoked []TopicPartition) // Registered on every client in turn
+}
+
+func (c *rotatingConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	for {
+		c.mu.Lock()
+		cur := c.cur
+		rctx, cancel := context.WithCancel(ctx)
+		c.cancel = cancel
+		c.mu.Unlock()
+
+		msg, err := cur.ReadMessage(rctx)
+		cancel()
+		if err != nil {
+			c.mu.Lock()
+			rotated := c.cur != cur
+			c.mu.Unlock()
+			if rotated && ctx.Err() == nil {
+				continue // Interrupted by the rotation; read from the new client
+			}
+			return nil, err
+		}
+		msg.from = cur
+		return msg, nil
+	}
+}
+
+func (c *rotatingConsumer) CommitMessage(ctx context.Context, msg *Message) error {
+	c.mu.Lock()
+	cur := c.cur
+	c.mu.Unlock()
+	if msg.from != nil && msg.from != cur {
+		return nil // Read by a rotated client; the group delivers it again
+	}
+	return cur.CommitMessage(ctx, msg)
+}
+
+func (c *rotatingConsumer) Close() error {
+	c.rot.remove(c) // First, so no rebuild replaces the client being closed
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cur.Close()
+}
+
+// Unwrap returns the current client. Capabilities looked up through it,
+// such as Stater, belong to that client and not to the ones replacing it.
+func (c *rotatingConsumer) Unwrap() Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cur
+}
+
+// OnRevoke registers fn with the current client and every later one; the
+// partitions of a rotated client are reported as revoked when it leaves the
+// group, if its backend reports revocations at all
+func (c *rotatingConsumer) OnRevoke(fn func(revoked []TopicPartition)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revokeFns = append(c.revokeFns, fn)
+	if n, ok := unwrapConsumer[RevokeNotifier](c.cur); ok {
+		n.OnRevoke(fn)
+	}
+}
+
+func (c *rotatingConsumer) rebuild(cfg *Config) error {
+	next, err := NewConsumer(cfg, c.topics...)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if n, ok := unwrapConsumer[RevokeNotifier](next); ok {
+		for _, fn := range c.revokeFns {
+			n.OnRevoke(fn)
+		}
+	}
+	old := c.cur
+	c.cur = next
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Unlock()
+	if err := old.Close(); err != nil {
+		log.Printf("Failed to close rotated consumer: %v", err)
+	}
+	return nil
+}
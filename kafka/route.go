@@ -0,0 +1,77 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/upendravikram5/upendra/errcode"
+)
+
+// ErrNoRoute is returned for messages whose type has no route and no default
+var ErrNoRoute = errcode.New("kafka.no_route", errcode.CategoryInvalid, "no handler for message type")
+
+// RouteTypeFunc extracts the type a message is routed by
+type RouteTypeFunc func(msg *Message) string
+
+// TypeFromHeaders returns the message-type header or, for CloudEvents, the
+// event type from the ce_type header or the body of a structured event
+func TypeFromHeaders(msg *Message) string {
+	if t := msg.MessageType(); t != "" {
+		return t
+	}
+	for _, key := range []string{"ce_type", "ce-type"} {
+		if v, ok := msg.Header(key); ok {
+			return string(v)
+		}
+	}
+	if strings.HasPrefix(msg.ContentType(), "application/cloudevents") {
+		var e struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(msg.Value, &e) == nil {
+			return e.Type
+		}
+	}
+	return ""
+}
+
+// RouteConfig configures Route
+type RouteConfig struct {
+	Routes  map[string]Handler // Message type -> handler
+	Default Handler            // Handles types without a route; nil fails them with ErrNoRoute
+	Type    RouteTypeFunc      // Default TypeFromHeaders
+}
+
+// Route dispatches each message to the handler registered for its type, so
+// a service consuming a topic with several kinds of message registers one
+// handler per kind instead of switching on the type itself:
+//
+//	kafka.Route(kafka.RouteConfig{Routes: map[string]kafka.Handler{
+//		"order.created":   onCreated,
+//		"order.cancelled": onCancelled,
+//	}})
+//
+// Messages without a route fail with ErrNoRoute, so under Retrying they end
+// up in the DLQ, coded kafka.no_route; set Default to a handler returning
+// nil to skip them instead.
+func Route(cfg RouteConfig) Handler {
+	if cfg.Type == nil {
+		cfg.Type = TypeFromHeaders
+	}
+	routes := make(map[string]Handler, len(cfg.Routes))
+	for t, h := range cfg.Routes {
+		routes[t] = h
+	}
+	return func(ctx context.Context, msg *Message) error {
+		t := cfg.Type(msg)
+		if h, ok := routes[t]; ok {
+			return h(ctx, msg)
+		}
+		if cfg.Default != nil {
+			return cfg.Default(ctx, msg)
+		}
+		return fmt.Errorf("%s[%d]@%d has type %q: %w", msg.Topic, msg.Partition, msg.Offset, t, ErrNoRoute)
+	}
+}
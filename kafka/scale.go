@@ -0,0 +1,187 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+var (
+	scaleMetricsOnce sync.Once
+
+	consumerLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "lag",
+		Help:      "Messages behind the high watermark on the partitions assigned to this member.",
+	})
+
+	groupLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "group_lag",
+		Help:      "Messages behind the high watermark across all partitions of the consumer group, from its committed offsets.",
+	})
+
+	consumerRate = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "processing_rate",
+		Help:      "Messages read per second by this member, smoothed.",
+	})
+
+	desiredReplicas = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "desired_replicas",
+		Help:      "Replicas needed to drain the group lag within the target drain time.",
+	})
+)
+
+// GroupLagger is implemented by consumers that can measure the lag of the
+// whole consumer group, comparing its committed offsets with the end offsets
+type GroupLagger interface {
+	GroupLag(ctx context.Context) (int64, error)
+}
+
+// ScaleConfig sets the targets the desired replica count is derived from
+type ScaleConfig struct {
+	TargetLagPerReplica int64         // Lag one replica is allowed to hold (default 1000)
+	TargetDrainTime     time.Duration // Time in which the lag should be worked off (default 1m)
+	MinReplicas         int           // Lower bound (default 1)
+	MaxReplicas         int           // Upper bound, usually the partition count; 0 means unbounded
+}
+
+// Scale is the autoscaling signal. It is served as JSON for the KEDA
+// metrics-api scaler, e.g. with valueLocation "desiredReplicas".
+type Scale struct {
+	Lag             int64   `json:"lag"`                       // Lag of the whole group, or of this member without GroupLagger
+	GroupWide       bool    `json:"groupWide"`                 // Lag covers every partition of the group
+	Rate            float64 `json:"rate"`                      // Messages per second read by this member
+	DesiredReplicas int     `json:"desiredReplicas,omitempty"` // Only computed from the group lag
+}
+
+// ScaleSignal wraps a Consumer, measuring its processing rate, and derives
+// the replica count needed to keep up with the lag of the whole group, so
+// every member reports the same figure whichever partitions it owns. The
+// group lag comes from the backend's admin calls; backends without
+// GroupLagger publish only this member's lag and no replica count, and are
+// better scaled by KEDA's Kafka scaler, which reads the group lag itself.
+type ScaleSignal struct {
+	Consumer
+	cfg   ScaleConfig
+	reads atomic.Int64
+
+	mu        sync.Mutex
+	lastReads int64
+	lastAt    time.Time
+	rate      float64 // Exponentially smoothed messages per second
+}
+
+// NewScaleSignal wraps c; pass the returned value to Consume in place of c
+func NewScaleSignal(c Consumer, cfg ScaleConfig) *ScaleSignal {
+	if cfg.TargetLagPerReplica <= 0 {
+		cfg.TargetLagPerReplica = 1000
+	}
+	if cfg.TargetDrainTime <= 0 {
+		cfg.TargetDrainTime = time.Minute
+	}
+	if cfg.MinReplicas <= 0 {
+		cfg.MinReplicas = 1
+	}
+	scaleMetricsOnce.Do(func() { metrics.MustRegister(consumerLag, groupLag, consumerRate, desiredReplicas) })
+	return &ScaleSignal{Consumer: c, cfg: cfg, lastAt: time.Now()}
+}
+
+// ReadMessage reads from the wrapped consumer and counts the message
+func (s *ScaleSignal) ReadMessage(ctx context.Context) (*Message, error) {
+	msg, err := s.Consumer.ReadMessage(ctx)
+	if err == nil {
+		s.reads.Add(1)
+	}
+	return msg, err
+}
+
+// Unwrap returns the wrapped consumer
+func (s *ScaleSignal) Unwrap() Consumer { return s.Consumer }
+
+// Signal measures lag and rate and computes the desired replica count
+func (s *ScaleSignal) Signal(ctx context.Context) (Scale, error) {
+	var memberLag int64
+	if st, ok := unwrapConsumer[Stater](s.Consumer); ok {
+		lags, err := st.Lag(ctx)
+		if err != nil {
+			return Scale{}, err
+		}
+		for _, l := range lags {
+			if l.Lag > 0 {
+				memberLag += l.Lag
+			}
+		}
+	}
+	consumerLag.Set(float64(memberLag))
+
+	s.mu.Lock()
+	now, reads := time.Now(), s.reads.Load()
+	if elapsed := now.Sub(s.lastAt).Seconds(); elapsed > 0 {
+		current := float64(reads-s.lastReads) / elapsed
+		s.rate = 0.5*s.rate + 0.5*current
+	}
+	s.lastReads, s.lastAt = reads, now
+	rate := s.rate
+	s.mu.Unlock()
+
+	consumerRate.Set(rate)
+
+	gl, ok := unwrapConsumer[GroupLagger](s.Consumer)
+	if !ok {
+		return Scale{Lag: memberLag, Rate: rate}, nil
+	}
+	lag, err := gl.GroupLag(ctx)
+	if err != nil {
+		return Scale{}, fmt.Errorf("group lag: %w", err)
+	}
+	scale := Scale{Lag: lag, GroupWide: true, Rate: rate, DesiredReplicas: s.desired(lag, rate)}
+	groupLag.Set(float64(lag))
+	desiredReplicas.Set(float64(scale.DesiredReplicas))
+	return scale, nil
+}
+
+// desired takes the larger of the replicas needed to stay under the lag
+// target and those needed to drain the group lag in time, each replica
+// reading at this member's measured rate
+func (s *ScaleSignal) desired(lag int64, rate float64) int {
+	n := int(math.Ceil(float64(lag) / float64(s.cfg.TargetLagPerReplica)))
+	if rate > 0 {
+		if byRate := int(math.Ceil(float64(lag) / (rate * s.cfg.TargetDrainTime.Seconds()))); byRate > n {
+			n = byRate
+		}
+	}
+	if n < s.cfg.MinReplicas {
+		n = s.cfg.MinReplicas
+	}
+	if s.cfg.MaxReplicas > 0 && n > s.cfg.MaxReplicas {
+		n = s.cfg.MaxReplicas
+	}
+	return n
+}
+
+// ServeHTTP writes the current Scale as JSON
+func (s *ScaleSignal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	scale, err := s.Signal(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scale)
+}
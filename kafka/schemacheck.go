@@ -0,0 +1,307 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// Subject compatibility levels of Schema Registry
+const (
+	CompatibilityBackward           = "BACKWARD"
+	CompatibilityBackwardTransitive = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            = "FORWARD"
+	CompatibilityForwardTransitive  = "FORWARD_TRANSITIVE"
+	CompatibilityFull               = "FULL"
+	CompatibilityFullTransitive     = "FULL_TRANSITIVE"
+	CompatibilityNone               = "NONE"
+)
+
+// SchemaCheckConfig enables validating produced messages against Schema
+// Registry. A message carrying a schema-id header is sent only when the
+// schema is registered under the topic's subject, the subject's
+// compatibility level is accepted and, for Avro, the value decodes with the
+// schema. Values of JSON Schema and Protobuf subjects are not decoded.
+type SchemaCheckConfig struct {
+	RegistryURL     string                    `env:"KAFKA_SCHEMA_REGISTRY_URL" flag:"kafka.schema-registry-url"` // Schema Registry base URL; empty disables the check
+	Username        string                    `env:"KAFKA_SCHEMA_REGISTRY_USERNAME" flag:"kafka.schema-registry-username"`
+	Password        string                    `env:"KAFKA_SCHEMA_REGISTRY_PASSWORD"`
+	Compatibility   []string                  `env:"KAFKA_SCHEMA_COMPATIBILITY" flag:"kafka.schema-compatibility"` // Accepted compatibility levels; default any but NONE
+	RequireSchemaID bool                      `env:"KAFKA_SCHEMA_REQUIRE_ID" flag:"kafka.schema-require-id"`       // Reject messages without a schema-id header
+	Subject         func(topic string) string // Subject of a topic's values (default topic + "-value")
+	CacheTTL        time.Duration             // How long subject lookups are reused (default 5m)
+	Client          *http.Client              // Default has a 10s timeout
+}
+
+// SchemaError is returned by Produce when a message fails the schema check.
+// Sending it again cannot succeed.
+type SchemaError struct {
+	Topic    string
+	Subject  string
+	SchemaID int // 0 when the message has no schema ID
+	Err      error
+}
+
+func (e *SchemaError) Error() string {
+	if e.SchemaID == 0 {
+		return fmt.Sprintf("schema check for %s failed: %v", e.Topic, e.Err)
+	}
+	return fmt.Sprintf("schema check for %s (subject %s, schema %d) failed: %v", e.Topic, e.Subject, e.SchemaID, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error { return e.Err }
+
+// CheckCompatibilityLevel returns an error unless level is a Schema Registry compatibility level, in any case
+func CheckCompatibilityLevel(level string) error {
+	switch strings.ToUpper(level) {
+	case CompatibilityBackward, CompatibilityBackwardTransitive, CompatibilityForward,
+		CompatibilityForwardTransitive, CompatibilityFull, CompatibilityFullTransitive, CompatibilityNone:
+		return nil
+	}
+	return fmt.Errorf("unknown compatibility level %q", level)
+}
+
+// SchemaCheckedProducer wraps a Producer with the check configured by
+// SchemaCheckConfig. NewProducer installs it when Config.SchemaCheck has a
+// registry URL.
+type SchemaCheckedProducer struct {
+	Producer
+	cfg SchemaCheckConfig
+
+	mu       sync.Mutex
+	schemas  map[int]registeredSchema
+	subjects map[subjectKey]subjectCheck
+}
+
+type registeredSchema struct {
+	avro avro.Schema // Nil for JSON Schema and Protobuf
+}
+
+type subjectKey struct {
+	subject string
+	id      int
+}
+
+// subjectCheck is the cached outcome of the registry lookups for a schema ID under a subject
+type subjectCheck struct {
+	err     error
+	expires time.Time
+}
+
+// NewSchemaCheckedProducer wraps p
+func NewSchemaCheckedProducer(p Producer, cfg SchemaCheckConfig) *SchemaCheckedProducer {
+	cfg.RegistryURL = strings.TrimSuffix(cfg.RegistryURL, "/")
+	if cfg.Subject == nil {
+		cfg.Subject = func(topic string) string { return topic + "-value" }
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &SchemaCheckedProducer{
+		Producer: p,
+		cfg:      cfg,
+		schemas:  make(map[int]registeredSchema),
+		subjects: make(map[subjectKey]subjectCheck),
+	}
+}
+
+// Produce checks msg and sends it when it passes. Registry lookup failures
+// are returned as retriable ProduceErrors, failed checks as SchemaErrors.
+func (p *SchemaCheckedProducer) Produce(ctx context.Context, msg *Message) error {
+	if err := p.check(ctx, msg); err != nil {
+		return err
+	}
+	return p.Producer.Produce(ctx, msg)
+}
+
+func (p *SchemaCheckedProducer) check(ctx context.Context, msg *Message) error {
+	subject := p.cfg.Subject(msg.Topic)
+	id, ok := msg.SchemaID()
+	if !ok {
+		if p.cfg.RequireSchemaID {
+			return &SchemaError{Topic: msg.Topic, Subject: subject, Err: fmt.Errorf("message has no valid %s header", HeaderSchemaID)}
+		}
+		return nil
+	}
+	fail := func(err error) error {
+		return &SchemaError{Topic: msg.Topic, Subject: subject, SchemaID: id, Err: err}
+	}
+
+	schema, err := p.schema(ctx, id)
+	if err == nil {
+		err = p.subject(ctx, subject, id)
+	}
+	if err != nil {
+		if _, ok := err.(*registryError); ok {
+			return &ProduceError{Topic: msg.Topic, Err: err, Retriable: true}
+		}
+		return fail(err)
+	}
+	if schema.avro != nil {
+		var v interface{}
+		if err := avro.Unmarshal(schema.avro, msg.Value, &v); err != nil {
+			return fail(fmt.Errorf("value does not match the schema: %w", err))
+		}
+	}
+	return nil
+}
+
+// schema returns a registered schema; schemas never change once registered, so they are cached for good
+func (p *SchemaCheckedProducer) schema(ctx context.Context, id int) (registeredSchema, error) {
+	p.mu.Lock()
+	s, ok := p.schemas[id]
+	p.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	var resp struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	found, err := p.get(ctx, fmt.Sprintf("/schemas/ids/%d", id), &resp)
+	if err != nil {
+		return s, err
+	}
+	if !found {
+		return s, fmt.Errorf("schema is not registered")
+	}
+	if resp.SchemaType == "" || resp.SchemaType == "AVRO" {
+		parsed, err := avro.Parse(resp.Schema)
+		if err != nil {
+			return s, fmt.Errorf("failed to parse schema %d: %w", id, err)
+		}
+		s.avro = parsed
+	}
+	p.mu.Lock()
+	p.schemas[id] = s
+	p.mu.Unlock()
+	return s, nil
+}
+
+// subject checks that id is registered under subject and that the
+// subject's compatibility level is accepted; outcomes are cached for CacheTTL
+func (p *SchemaCheckedProducer) subject(ctx context.Context, subject string, id int) error {
+	key := subjectKey{subject: subject, id: id}
+	p.mu.Lock()
+	c, ok := p.subjects[key]
+	p.mu.Unlock()
+	if ok && time.Now().Before(c.expires) {
+		return c.err
+	}
+
+	err := p.lookupSubject(ctx, subject, id)
+	if _, ok := err.(*registryError); ok {
+		return err // Not cached, the next message asks again
+	}
+	p.mu.Lock()
+	p.subjects[key] = subjectCheck{err: err, expires: time.Now().Add(p.cfg.CacheTTL)}
+	p.mu.Unlock()
+	return err
+}
+
+func (p *SchemaCheckedProducer) lookupSubject(ctx context.Context, subject string, id int) error {
+	var versions []struct {
+		Subject string `json:"subject"`
+		Version int    `json:"version"`
+	}
+	if _, err := p.get(ctx, fmt.Sprintf("/schemas/ids/%d/versions", id), &versions); err != nil {
+		return err
+	}
+	registered := false
+	for _, v := range versions {
+		if v.Subject == subject {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return fmt.Errorf("schema is not registered under the subject")
+	}
+
+	level, err := p.compatibility(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if len(p.cfg.Compatibility) == 0 {
+		if level == CompatibilityNone {
+			return fmt.Errorf("subject compatibility is %s", level)
+		}
+		return nil
+	}
+	for _, accepted := range p.cfg.Compatibility {
+		if strings.EqualFold(accepted, level) {
+			return nil
+		}
+	}
+	return fmt.Errorf("subject compatibility is %s, accepted are %s", level, strings.Join(p.cfg.Compatibility, ", "))
+}
+
+// compatibility returns the subject's level, falling back to the global one
+// on registries that don't know defaultToGlobal
+func (p *SchemaCheckedProducer) compatibility(ctx context.Context, subject string) (string, error) {
+	var resp struct {
+		CompatibilityLevel string `json:"compatibilityLevel"`
+	}
+	found, err := p.get(ctx, "/config/"+url.PathEscape(subject)+"?defaultToGlobal=true", &resp)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		if _, err := p.get(ctx, "/config", &resp); err != nil {
+			return "", err
+		}
+	}
+	return resp.CompatibilityLevel, nil
+}
+
+// registryError is a failed registry request, as opposed to a failed check
+type registryError struct {
+	path string
+	err  error
+}
+
+func (e *registryError) Error() string {
+	return fmt.Sprintf("schema registry request %s failed: %v", e.path, e.err)
+}
+
+func (e *registryError) Unwrap() error { return e.err }
+
+// get decodes the registry response for path into v; found is false on 404
+func (p *SchemaCheckedProducer) get(ctx context.Context, path string, v interface{}) (found bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.RegistryURL+path, nil)
+	if err != nil {
+		return false, &registryError{path: path, err: err}
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if p.cfg.Username != "" {
+		req.SetBasicAuth(p.cfg.Username, p.cfg.Password)
+	}
+	resp, err := p.cfg.Client.Do(req)
+	if err != nil {
+		return false, &registryError{path: path, err: err}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, &registryError{path: path, err: fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, &registryError{path: path, err: err}
+	}
+	return true, nil
+}
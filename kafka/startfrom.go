@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StartFrom values. The position applies to the first assignment of the
+// process only; partitions picked up in later rebalances resume from their
+// committed offsets. Remove the setting once the replay has been deployed,
+// or every restart replays again.
+const (
+	StartFromCommitted = "committed" // Committed offsets, falling back to AutoOffsetReset (default)
+	StartFromEarliest  = "earliest"  // The start of each partition
+	StartFromLatest    = "latest"    // The end of each partition, skipping the backlog
+	StartFromTimestamp = "timestamp" // "timestamp=2024-05-01T12:00:00Z" or Unix milliseconds
+)
+
+// startPosition is a parsed StartFrom value
+type startPosition struct {
+	mode string
+	at   time.Time // For StartFromTimestamp
+}
+
+// committed reports whether the usual committed offsets apply
+func (p startPosition) committed() bool {
+	return p.mode == StartFromCommitted
+}
+
+// startFrom parses StartFrom
+func (c *Config) startFrom() (startPosition, error) {
+	v := strings.TrimSpace(c.StartFrom)
+	switch v {
+	case "", StartFromCommitted:
+		return startPosition{mode: StartFromCommitted}, nil
+	case StartFromEarliest, StartFromLatest:
+		return startPosition{mode: v}, nil
+	}
+	ts, ok := strings.CutPrefix(v, StartFromTimestamp+"=")
+	if !ok {
+		return startPosition{}, fmt.Errorf("unknown start position %q (use committed, earliest, latest or timestamp=...)", c.StartFrom)
+	}
+	if ms, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		return startPosition{mode: StartFromTimestamp, at: time.UnixMilli(ms)}, nil
+	}
+	at, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return startPosition{}, fmt.Errorf("invalid start timestamp %q: want RFC 3339 or Unix milliseconds", ts)
+	}
+	return startPosition{mode: StartFromTimestamp, at: at}, nil
+}
+
+// CheckStartFrom returns an error when v is not a valid StartFrom value
+func CheckStartFrom(v string) error {
+	_, err := (&Config{StartFrom: v}).startFrom()
+	return err
+}
@@ -0,0 +1,234 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// PartitionState is the processing position of one partition
+type PartitionState struct {
+	Topic         string    `json:"topic"`
+	Partition     int32     `json:"partition"`
+	LastRead      int64     `json:"lastRead"`      // -1 until a message was read
+	LastCommitted int64     `json:"lastCommitted"` // Offset of the last committed message, -1 if none
+	InFlight      int       `json:"inFlight"`      // Read but not yet committed
+	Retries       int       `json:"retries"`       // In-flight messages that are redeliveries from a retry tier
+	Lag           int64     `json:"lag"`           // -1 when the backend does not report it
+	LastReadAt    time.Time `json:"lastReadAt"`
+}
+
+// State is a snapshot of a consumer's processing state
+type State struct {
+	Time       time.Time        `json:"time"`
+	Assigned   []TopicPartition `json:"assigned,omitempty"` // Nil when the backend does not report assignments
+	Partitions []PartitionState `json:"partitions"`
+	InFlight   int              `json:"inFlight"`
+	Retries    int              `json:"retries"`
+}
+
+// StateTracker wraps a Consumer and records what it read and committed, for
+// debugging stuck pipelines. It serves the snapshot as JSON, e.g. mounted with
+// admin.Server.Handle("/kafka/state", tracker), and as a component logs it
+// on an interval. Under EnableAutoCommit, which Consume reports to the
+// tracker, nothing is committed through it, so in-flight messages and the
+// committed position are not tracked.
+type StateTracker struct {
+	Consumer
+	log        logger.FieldLogger
+	interval   time.Duration
+	cancel     context.CancelFunc
+	done       chan struct{}
+	autoCommit atomic.Bool // Offsets are committed by the client; pending is left empty
+
+	mu    sync.Mutex
+	parts map[TopicPartition]*partitionState
+}
+
+type partitionState struct {
+	PartitionState
+	pending map[int64]bool // Uncommitted offsets; true for retry redeliveries
+}
+
+// NewStateTracker wraps c, logging through log every interval (default 1m)
+// once started; pass the returned value to Consume in place of c
+func NewStateTracker(c Consumer, log logger.FieldLogger, interval time.Duration) *StateTracker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &StateTracker{Consumer: c, log: log, interval: interval, parts: make(map[TopicPartition]*partitionState)}
+}
+
+func (t *StateTracker) partition(tp TopicPartition) *partitionState {
+	p, ok := t.parts[tp]
+	if !ok {
+		p = &partitionState{
+			PartitionState: PartitionState{Topic: tp.Topic, Partition: tp.Partition, LastRead: -1, LastCommitted: -1, Lag: -1},
+			pending:        make(map[int64]bool),
+		}
+		t.parts[tp] = p
+	}
+	return p
+}
+
+// ReadMessage reads from the wrapped consumer and records the message as in flight
+func (t *StateTracker) ReadMessage(ctx context.Context) (*Message, error) {
+	msg, err := t.Consumer.ReadMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	p := t.partition(TopicPartition{Topic: msg.Topic, Partition: msg.Partition})
+	p.LastRead = msg.Offset
+	p.LastReadAt = time.Now()
+	if !t.autoCommit.Load() {
+		p.pending[msg.Offset] = msg.RetryCount() > 0
+	}
+	t.mu.Unlock()
+	return msg, nil
+}
+
+// autoCommitted stops tracking offsets that will never be committed through the tracker
+func (t *StateTracker) autoCommitted() {
+	t.autoCommit.Store(true)
+	t.mu.Lock()
+	for _, p := range t.parts {
+		clear(p.pending)
+	}
+	t.mu.Unlock()
+}
+
+// CommitMessage commits msg and records the committed position
+func (t *StateTracker) CommitMessage(ctx context.Context, msg *Message) error {
+	if err := t.Consumer.CommitMessage(ctx, msg); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	p := t.partition(TopicPartition{Topic: msg.Topic, Partition: msg.Partition})
+	p.LastCommitted = msg.Offset
+	for o := range p.pending {
+		if o <= msg.Offset {
+			delete(p.pending, o)
+		}
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// Unwrap returns the wrapped consumer
+func (t *StateTracker) Unwrap() Consumer { return t.Consumer }
+
+// Snapshot returns the current state, with assignments and lag when the backend reports them
+func (t *StateTracker) Snapshot(ctx context.Context) State {
+	st := State{Time: time.Now(), Partitions: []PartitionState{}}
+	lags := make(map[TopicPartition]int64)
+	if s, ok := unwrapConsumer[Stater](t.Consumer); ok {
+		st.Assigned = s.Assigned()
+		if pl, err := s.Lag(ctx); err == nil {
+			for _, l := range pl {
+				lags[l.TopicPartition] = l.Lag
+			}
+		}
+	}
+
+	t.mu.Lock()
+	for tp, p := range t.parts {
+		ps := p.PartitionState
+		ps.InFlight = len(p.pending)
+		for _, retry := range p.pending {
+			if retry {
+				ps.Retries++
+			}
+		}
+		if lag, ok := lags[tp]; ok {
+			ps.Lag = lag
+		}
+		st.InFlight += ps.InFlight
+		st.Retries += ps.Retries
+		st.Partitions = append(st.Partitions, ps)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(st.Partitions, func(i, j int) bool {
+		a, b := st.Partitions[i], st.Partitions[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+	return st
+}
+
+// ServeHTTP writes the snapshot as JSON
+func (t *StateTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t.Snapshot(r.Context()))
+}
+
+// Start begins logging the snapshot in the background
+func (t *StateTracker) Start(ctx context.Context) error {
+	ctx, t.cancel = context.WithCancel(context.Background())
+	t.done = make(chan struct{})
+	go t.run(ctx)
+	return nil
+}
+
+// Stop ends logging
+func (t *StateTracker) Stop(ctx context.Context) error {
+	if t.cancel == nil {
+		return nil
+	}
+	t.cancel()
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *StateTracker) run(ctx context.Context) {
+	defer close(t.done)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.Report(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Report logs one snapshot: totals plus one entry per partition with work in flight
+func (t *StateTracker) Report(ctx context.Context) {
+	st := t.Snapshot(ctx)
+	t.log.Infow("kafka consumer state",
+		"assigned", len(st.Assigned),
+		"partitions", len(st.Partitions),
+		"in_flight", st.InFlight,
+		"retries", st.Retries,
+	)
+	for _, p := range st.Partitions {
+		if p.InFlight == 0 {
+			continue
+		}
+		t.log.Infow("kafka partition state",
+			"topic", p.Topic,
+			"partition", p.Partition,
+			"last_read", p.LastRead,
+			"last_committed", p.LastCommitted,
+			"in_flight", p.InFlight,
+			"retries", p.Retries,
+			"lag", p.Lag,
+			"last_read_at", p.LastReadAt,
+		)
+	}
+}
@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+// ErrHandlerTimeout is returned when a handler runs past its deadline
+var ErrHandlerTimeout = errors.New("handler timed out")
+
+var (
+	timeoutMetricsOnce sync.Once
+
+	handlerTimeoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "handler_timeouts_total",
+		Help:      "Messages whose handler exceeded the processing deadline, by topic.",
+	}, []string{"topic"})
+
+	handlersAbandoned = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "handlers_abandoned",
+		Help:      "Timed-out handlers still running after their message was given up on.",
+	}, func() float64 { return float64(abandoned.Load()) })
+)
+
+// maxAbandoned bounds the timed-out handlers left running; past it Timeout
+// waits for the handler to return, so handlers ignoring their context stall
+// the consumer instead of piling up goroutines
+const maxAbandoned = 64
+
+// abandoned counts the timed-out handlers still running
+var abandoned atomic.Int64
+
+// Timeout gives next at most d per message. On expiry the handler's context
+// is cancelled and ErrHandlerTimeout is returned without waiting for next to
+// notice, so a stuck handler can't stall its partition. The abandoned call
+// keeps running until it returns, possibly alongside the next messages of
+// its partition, so handlers should stop when their context is done; once
+// maxAbandoned calls are left running Timeout waits for the handler instead.
+// A handler abandoned because the consumer is shutting down is treated the
+// same way and its message left uncommitted.
+// Consume wraps its timeout inside Retrying when Config.Retry is set, so
+// timeouts count toward the retry and dead-letter policy.
+func Timeout(d time.Duration, next Handler) Handler {
+	if d <= 0 {
+		return next
+	}
+	timeoutMetricsOnce.Do(func() { metrics.MustRegister(handlerTimeoutsTotal, handlersAbandoned) })
+
+	return func(ctx context.Context, msg *Message) error {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		done := make(chan error, 1) // Buffered so an abandoned handler can still finish
+		go func() { done <- next(ctx, msg) }()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+			if timedOut {
+				handlerTimeoutsTotal.WithLabelValues(msg.Topic).Inc()
+				log.Printf("event=handler_timeout topic=%s partition=%d offset=%d timeout=%s", msg.Topic, msg.Partition, msg.Offset, d)
+			}
+			if abandoned.Add(1) > maxAbandoned {
+				<-done // Too many handlers left running already
+				abandoned.Add(-1)
+			} else {
+				go func() {
+					<-done
+					abandoned.Add(-1)
+				}()
+			}
+			if !timedOut {
+				// Consumer shutting down: not a handler failure, but the message wasn't handled either
+				return uncommitted{ctx.Err()}
+			}
+			return fmt.Errorf("%w after %s", ErrHandlerTimeout, d)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     Handler
+		cancel      bool // Cancel the parent context, as shutdown does
+		wantErr     error
+		uncommitted bool
+	}{
+		{name: "in time", handler: func(context.Context, *Message) error { return nil }},
+		{name: "handler error", handler: func(context.Context, *Message) error { return errors.New("failed") }},
+		{name: "timed out", handler: blockUntilDone, wantErr: ErrHandlerTimeout},
+		{name: "shutting down", handler: blockUntilDone, cancel: true, wantErr: context.Canceled, uncommitted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			d := 20 * time.Millisecond
+			if tt.cancel {
+				d = time.Minute
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+			err := Timeout(d, tt.handler)(ctx, &Message{Topic: "orders"})
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			var u uncommitted
+			if got := errors.As(err, &u); got != tt.uncommitted {
+				t.Errorf("error %v: uncommitted = %v, want %v", err, got, tt.uncommitted)
+			}
+		})
+	}
+}
+
+// TestTimeoutAbandoned checks that handlers given up on count as abandoned
+// until they return, whether they timed out or the consumer shut down
+func TestTimeoutAbandoned(t *testing.T) {
+	release := make(chan struct{})
+	handler := func(context.Context, *Message) error {
+		<-release // Ignores its context
+		return nil
+	}
+	waitAbandoned(t, 0) // Handlers of other tests returning
+
+	_ = Timeout(10*time.Millisecond, handler)(context.Background(), &Message{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = Timeout(time.Minute, handler)(ctx, &Message{})
+	if n := abandoned.Load(); n != 2 {
+		t.Errorf("abandoned = %d, want 2", n)
+	}
+	close(release)
+	waitAbandoned(t, 0)
+}
+
+// waitAbandoned waits for the abandoned handler count to reach n
+func waitAbandoned(t *testing.T, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for abandoned.Load() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("abandoned = %d, want %d", abandoned.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHandleCommits(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		autoCommit bool
+		commit     bool
+	}{
+		{name: "handled", commit: true},
+		{name: "handler error", err: errors.New("failed"), commit: true},
+		{name: "timed out", err: ErrHandlerTimeout},
+		{name: "uncommitted", err: uncommitted{errors.New("no DLQ")}},
+		{name: "wrapped uncommitted", err: errors.Join(errors.New("retrying"), uncommitted{context.Canceled})},
+		{name: "auto commit", autoCommit: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeConsumer{}
+			handle(context.Background(), c, &Config{EnableAutoCommit: tt.autoCommit}, func(context.Context, *Message) error { return tt.err }, &Message{Offset: 5})
+			if got := len(c.committed) == 1; got != tt.commit {
+				t.Errorf("committed = %v, want %v", got, tt.commit)
+			}
+		})
+	}
+}
+
+func blockUntilDone(ctx context.Context, _ *Message) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TopicConfig overrides consumer settings for one topic, since a single
+// consumer often handles topics with very different reliability needs.
+// Zero values fall back to the consumer-wide settings. Messages on retry
+// tiers use the overrides of the topic they were first consumed from.
+type TopicConfig struct {
+	MaxRetries     *int          // Retry tiers used before the DLQ; 0 dead-letters on the first failure
+	DLQTopic       string        // Replaces RetryConfig.DLQTopic
+	HandlerTimeout time.Duration // Replaces Config.HandlerTimeout
+	Codec          string        // Content type assumed for messages without a content-type header, e.g. "avro/binary"
+	Weight         int           // Share of the workers under "priority" concurrency (default 1)
+}
+
+// UnmarshalJSON reads the KAFKA_TOPIC_OVERRIDES form, with the timeout as a duration string:
+//
+//	{"payments": {"maxRetries": 5, "dlqTopic": "payments.dlq", "handlerTimeout": "30s"}}
+func (tc *TopicConfig) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		MaxRetries     *int   `json:"maxRetries"`
+		DLQTopic       string `json:"dlqTopic"`
+		HandlerTimeout string `json:"handlerTimeout"`
+		Codec          string `json:"codec"`
+		Weight         int    `json:"weight"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*tc = TopicConfig{MaxRetries: raw.MaxRetries, DLQTopic: raw.DLQTopic, Codec: raw.Codec, Weight: raw.Weight}
+	if raw.HandlerTimeout != "" {
+		d, err := time.ParseDuration(raw.HandlerTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid handlerTimeout: %w", err)
+		}
+		tc.HandlerTimeout = d
+	}
+	return nil
+}
+
+// baseTopic is the topic a message was first consumed from, looking through retry tiers
+func baseTopic(msg *Message) string {
+	if v, ok := msg.Header(HeaderOriginalTopic); ok {
+		return string(v)
+	}
+	return msg.Topic
+}
+
+// topicHandler applies the per-topic handler timeout and default codec
+func (c *Config) topicHandler(next Handler) Handler {
+	if len(c.Topics) == 0 {
+		return Timeout(c.HandlerTimeout, next)
+	}
+	fallback := Timeout(c.HandlerTimeout, next)
+	handlers := make(map[string]Handler, len(c.Topics))
+	for topic, tc := range c.Topics {
+		handlers[topic] = fallback
+		if tc.HandlerTimeout > 0 {
+			handlers[topic] = Timeout(tc.HandlerTimeout, next)
+		}
+	}
+	return func(ctx context.Context, msg *Message) error {
+		topic := baseTopic(msg)
+		h, ok := handlers[topic]
+		if !ok {
+			return fallback(ctx, msg)
+		}
+		if codec := c.Topics[topic].Codec; codec != "" && msg.ContentType() == "" {
+			msg.SetContentType(codec)
+		}
+		return h(ctx, msg)
+	}
+}
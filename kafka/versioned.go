@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/upendravikram5/upendra/errcode"
+)
+
+// ErrUnknownVersion is returned for messages whose schema matches no handler and there is no default
+var ErrUnknownVersion = errcode.New("kafka.unknown_version", errcode.CategoryInvalid, "no handler for schema version")
+
+// VersionConfig configures Versioned
+type VersionConfig struct {
+	SchemaIDs map[int]Handler // Schema registry ID (schema-id header) -> handler, checked first
+	Versions  map[int]Handler // Contract version (schema-version header) -> handler
+	Default   Handler         // Handles messages matching neither; nil fails them with ErrUnknownVersion
+}
+
+// Versioned dispatches each message to the handler for its schema, so a
+// consumer can accept v1 and v2 of an event while producers roll over from
+// one to the other. Usually each handler decodes its version with Decoded
+// and upgrades it to the current shape:
+//
+//	kafka.Versioned(kafka.VersionConfig{Versions: map[int]kafka.Handler{
+//		1: kafka.Decoded(json.Unmarshal, func(ctx context.Context, msg *kafka.Message, o OrderV1) error {
+//			return handleOrder(ctx, msg, o.Upgrade())
+//		}),
+//		2: kafka.Decoded(json.Unmarshal, handleOrder),
+//	}})
+//
+// A message with a schema-id header is dispatched by it when SchemaIDs has
+// that ID, otherwise by its schema-version header.
+func Versioned(cfg VersionConfig) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		if id, ok := msg.SchemaID(); ok {
+			if h, ok := cfg.SchemaIDs[id]; ok {
+				return h(ctx, msg)
+			}
+		}
+		version, ok := msg.SchemaVersion()
+		if ok {
+			if h, ok := cfg.Versions[version]; ok {
+				return h(ctx, msg)
+			}
+		}
+		if cfg.Default != nil {
+			return cfg.Default(ctx, msg)
+		}
+		if !ok {
+			return fmt.Errorf("%s[%d]@%d has no %s: %w", msg.Topic, msg.Partition, msg.Offset, HeaderSchemaVersion, ErrUnknownVersion)
+		}
+		return fmt.Errorf("%s[%d]@%d has schema version %d: %w", msg.Topic, msg.Partition, msg.Offset, version, ErrUnknownVersion)
+	}
+}
+
+// Decoded adapts a handler taking a decoded value to Handler; unmarshal is
+// the codec of that version, such as json.Unmarshal
+func Decoded[T any](unmarshal func(data []byte, v interface{}) error, next func(ctx context.Context, msg *Message, v T) error) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		var v T
+		if err := unmarshal(msg.Value, &v); err != nil {
+			return fmt.Errorf("failed to decode %s[%d]@%d as %T: %w", msg.Topic, msg.Partition, msg.Offset, v, err)
+		}
+		return next(ctx, msg, v)
+	}
+}
@@ -0,0 +1,229 @@
+package kafka
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/logger/fields"
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+var (
+	windowMetricsOnce sync.Once
+
+	windowRate = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "window_messages_per_second",
+		Help:      "Messages handled per second over the last minute.",
+	})
+
+	windowLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "window_handler_latency_seconds",
+		Help:      "Handler latency quantiles over the last minute.",
+	}, []string{"quantile"})
+
+	windowErrorRatio = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "window_error_ratio",
+		Help:      "Share of handler calls that failed over the last minute.",
+	})
+)
+
+const (
+	windowSeconds = 60
+	// Latency buckets grow by 25% from 100µs, covering up to about 2 minutes
+	latencyBuckets = 64
+	latencyBase    = 100 * time.Microsecond
+	latencyGrowth  = 1.25
+)
+
+// windowBucket holds the handler calls completed within one second
+type windowBucket struct {
+	second  int64 // Unix second the bucket holds, to detect stale buckets
+	count   int64
+	errors  int64
+	latency [latencyBuckets]int64
+}
+
+// WindowStats is the consumer's throughput and latency over the last minute
+type WindowStats struct {
+	Rate       float64 // Messages per second
+	P50        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	ErrorRatio float64
+	Count      int64
+}
+
+// WindowReporter measures handler throughput, latency and errors over a
+// sliding one minute window, and as a component logs and exports them on
+// an interval so there is a view of the consumer before dashboards exist
+type WindowReporter struct {
+	log      logger.FieldLogger
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+	created  time.Time // Rates divide by the time since, until a full window has passed
+
+	mu      sync.Mutex
+	buckets [windowSeconds]windowBucket
+}
+
+// NewWindowReporter creates a reporter logging through log every interval (default 1m)
+func NewWindowReporter(log logger.FieldLogger, interval time.Duration) *WindowReporter {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	windowMetricsOnce.Do(func() { metrics.MustRegister(windowRate, windowLatency, windowErrorRatio) })
+	return &WindowReporter{log: log, interval: interval, created: time.Now()}
+}
+
+// Handler wraps next, recording each call when it completes, so a call
+// slower than the window still counts towards the current one
+func (r *WindowReporter) Handler(next Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		start := time.Now()
+		err := next(ctx, msg)
+		end := time.Now()
+		r.record(end, end.Sub(start), err != nil)
+		return err
+	}
+}
+
+func (r *WindowReporter) record(at time.Time, d time.Duration, failed bool) {
+	sec := at.Unix()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := &r.buckets[sec%windowSeconds]
+	if b.second != sec {
+		*b = windowBucket{second: sec}
+	}
+	b.count++
+	if failed {
+		b.errors++
+	}
+	b.latency[latencyBucket(d)]++
+}
+
+func latencyBucket(d time.Duration) int {
+	if d <= latencyBase {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(latencyBase)) / math.Log(latencyGrowth)))
+	return min(i, latencyBuckets-1)
+}
+
+// latencyBound is the upper bound of bucket i
+func latencyBound(i int) time.Duration {
+	return time.Duration(float64(latencyBase) * math.Pow(latencyGrowth, float64(i)))
+}
+
+// Stats returns the figures of the last minute
+func (r *WindowReporter) Stats() WindowStats {
+	now := time.Now().Unix()
+	elapsed := min(time.Since(r.created).Seconds(), windowSeconds)
+	var hist [latencyBuckets]int64
+	var st WindowStats
+	var errors int64
+
+	r.mu.Lock()
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		if now-b.second >= windowSeconds {
+			continue // Older than the window
+		}
+		st.Count += b.count
+		errors += b.errors
+		for j, n := range b.latency {
+			hist[j] += n
+		}
+	}
+	r.mu.Unlock()
+
+	if st.Count == 0 {
+		return st
+	}
+	st.Rate = float64(st.Count) / max(elapsed, 1)
+	st.ErrorRatio = float64(errors) / float64(st.Count)
+	st.P50 = quantile(hist, st.Count, 0.50)
+	st.P95 = quantile(hist, st.Count, 0.95)
+	st.P99 = quantile(hist, st.Count, 0.99)
+	return st
+}
+
+// quantile returns the upper bound of the bucket holding quantile q
+func quantile(hist [latencyBuckets]int64, total int64, q float64) time.Duration {
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for i, n := range hist {
+		seen += n
+		if seen >= rank {
+			return latencyBound(i)
+		}
+	}
+	return latencyBound(latencyBuckets - 1)
+}
+
+// Start begins reporting in the background
+func (r *WindowReporter) Start(ctx context.Context) error {
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.done = make(chan struct{})
+	go r.run(ctx)
+	return nil
+}
+
+// Stop ends reporting
+func (r *WindowReporter) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *WindowReporter) run(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Report()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Report logs and exports one set of window figures
+func (r *WindowReporter) Report() {
+	st := r.Stats()
+	windowRate.Set(st.Rate)
+	windowLatency.WithLabelValues("0.5").Set(st.P50.Seconds())
+	windowLatency.WithLabelValues("0.95").Set(st.P95.Seconds())
+	windowLatency.WithLabelValues("0.99").Set(st.P99.Seconds())
+	windowErrorRatio.Set(st.ErrorRatio)
+
+	r.log.Infow("kafka consumer window",
+		"messages", st.Count,
+		"messages_per_second", st.Rate,
+		fields.DurationMS("latency_p50", st.P50),
+		fields.DurationMS("latency_p95", st.P95),
+		fields.DurationMS("latency_p99", st.P99),
+		"error_ratio", st.ErrorRatio,
+	)
+}
@@ -0,0 +1,81 @@
+package lifecycle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/upendravikram5/upendra/buildinfo"
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Lifecycle event names, logged as the "event" field
+const (
+	EventStarting = "service.starting"
+	EventReady    = "service.ready"
+	EventStopping = "service.stopping"
+	EventStopped  = "service.stopped"
+)
+
+// events logs the standard lifecycle events
+type events struct {
+	log         logger.Logger
+	fingerprint string
+	startedAt   time.Time
+}
+
+// LogEvents makes Run log service.starting, service.ready, service.stopping
+// and service.stopped through log. config is hashed into a fingerprint so
+// deploys that changed configuration can be told apart; it may be nil.
+func (o *Orchestrator) LogEvents(log logger.Logger, config interface{}) {
+	o.events = &events{log: log, fingerprint: Fingerprint(config)}
+}
+
+// Fingerprint returns a short stable hash of the JSON encoding of config
+func Fingerprint(config interface{}) string {
+	if config == nil {
+		return ""
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:6])
+}
+
+func (e *events) starting(components int) {
+	if e == nil {
+		return
+	}
+	e.startedAt = time.Now()
+	fields := []interface{}{"event", EventStarting, "components", components, "config_fingerprint", e.fingerprint}
+	e.log.Infow(EventStarting, append(fields, buildinfo.Get().Fields()...)...)
+}
+
+func (e *events) ready() {
+	if e == nil {
+		return
+	}
+	e.log.Infow(EventReady, "event", EventReady, "startup_ms", time.Since(e.startedAt).Milliseconds())
+}
+
+func (e *events) stopping(reason string) {
+	if e == nil {
+		return
+	}
+	e.log.Infow(EventStopping, "event", EventStopping, "reason", reason, "uptime_s", int64(time.Since(e.startedAt).Seconds()))
+}
+
+func (e *events) stopped(shutdown time.Duration, err error) {
+	if e == nil {
+		return
+	}
+	fields := []interface{}{"event", EventStopped, "shutdown_ms", shutdown.Milliseconds()}
+	if err != nil {
+		e.log.Errorw(EventStopped, append(fields, "error", err)...)
+		return
+	}
+	e.log.Infow(EventStopped, fields...)
+}
@@ -0,0 +1,95 @@
+// Package lifecycle starts the service components in order, waits for a
+// shutdown signal, and stops them in reverse order within a timeout.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Component is a long-running part of the service. Start must not block;
+// components that serve in the background start their own goroutine.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+type namedComponent struct {
+	name string
+	Component
+}
+
+// Orchestrator owns the component lifecycle
+type Orchestrator struct {
+	components  []namedComponent
+	stopTimeout time.Duration
+	events      *events // Set by LogEvents
+}
+
+// New creates an orchestrator that gives Stop calls stopTimeout in total
+func New(stopTimeout time.Duration) *Orchestrator {
+	if stopTimeout <= 0 {
+		stopTimeout = 30 * time.Second
+	}
+	return &Orchestrator{stopTimeout: stopTimeout}
+}
+
+// Add registers a component; components start in the order they were added
+func (o *Orchestrator) Add(name string, c Component) {
+	o.components = append(o.components, namedComponent{name: name, Component: c})
+}
+
+// Run starts every component, blocks until ctx is done or SIGINT/SIGTERM is
+// received, then stops the started components in reverse order.
+func (o *Orchestrator) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	o.events.starting(len(o.components))
+	started, err := o.start(ctx)
+	reason := "start failed"
+	if err == nil {
+		o.events.ready()
+		<-ctx.Done()
+		log.Println("Shutdown signal received...")
+		reason = "shutdown requested"
+		if cause := context.Cause(ctx); cause != context.Canceled {
+			reason = cause.Error() // e.g. the signal name on newer Go versions
+		}
+	}
+	o.events.stopping(reason)
+
+	stopStart := time.Now()
+	stopCtx, cancel := context.WithTimeout(context.Background(), o.stopTimeout)
+	defer cancel()
+	err = errors.Join(err, o.stop(stopCtx, started))
+	o.events.stopped(time.Since(stopStart), err)
+	return err
+}
+
+// start starts the components in order, returning those that started
+func (o *Orchestrator) start(ctx context.Context) ([]namedComponent, error) {
+	for i, c := range o.components {
+		if err := c.Start(ctx); err != nil {
+			return o.components[:i], fmt.Errorf("failed to start %s: %w", c.name, err)
+		}
+	}
+	return o.components, nil
+}
+
+// stop stops components in reverse order, continuing past failures
+func (o *Orchestrator) stop(ctx context.Context, started []namedComponent) error {
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,232 @@
+package logger
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Alert field convention: an entry logged with alert="page" or alert="ticket"
+// is forwarded by the alert hook, e.g.
+//
+//	log.Errorw("payments database unreachable", logger.AlertField, logger.AlertPage)
+//
+// An optional alert_key field groups entries into one incident; without it
+// the logger name and message are used.
+const (
+	AlertField    = "alert"
+	AlertKeyField = "alert_key"
+	AlertPage     = "page"
+	AlertTicket   = "ticket"
+)
+
+// Webhook payload formats
+const (
+	AlertFormatPagerDuty = "pagerduty" // PagerDuty Events API v2
+	AlertFormatSlack     = "slack"     // Slack incoming webhook
+)
+
+// AlertConfig configures forwarding of alert entries to a webhook
+type AlertConfig struct {
+	URL         string        // Webhook endpoint
+	Format      string        // pagerduty (default) or slack
+	RoutingKey  string        // PagerDuty integration key
+	Source      string        // Reported as the alert source, usually the service name
+	Severities  []string      // Alert values forwarded (default page and ticket)
+	DedupWindow time.Duration // Repeats of a dedup key within it are dropped (default 10m)
+	RateLimit   int           // Alerts sent per minute at most (default 10)
+	Client      *http.Client  // Default has a 5s timeout
+}
+
+// Alerter forwards alert entries to a webhook in the background so logging
+// never blocks on the network
+type Alerter struct {
+	cfg   AlertConfig
+	queue chan alert
+	done  chan struct{}
+
+	mu          sync.Mutex
+	closed      bool                 // Set by Close; the queue is closed and entries are no longer picked
+	seen        map[string]time.Time // Dedup key to last sent time
+	windowStart time.Time
+	sent        int
+}
+
+type alert struct {
+	severity string
+	key      string
+	ent      zapcore.Entry
+	details  map[string]interface{}
+}
+
+// NewAlerter starts an alerter; add its Hook with WithHooks and Close it on shutdown
+func NewAlerter(cfg AlertConfig) *Alerter {
+	if cfg.Format == "" {
+		cfg.Format = AlertFormatPagerDuty
+	}
+	if len(cfg.Severities) == 0 {
+		cfg.Severities = []string{AlertPage, AlertTicket}
+	}
+	if cfg.DedupWindow <= 0 {
+		cfg.DedupWindow = 10 * time.Minute
+	}
+	if cfg.RateLimit <= 0 {
+		cfg.RateLimit = 10
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	a := &Alerter{
+		cfg:   cfg,
+		queue: make(chan alert, 64),
+		done:  make(chan struct{}),
+		seen:  make(map[string]time.Time),
+	}
+	go a.run()
+	return a
+}
+
+// Hook returns the hook that picks alert entries off the log stream. It
+// never drops or modifies entries.
+func (a *Alerter) Hook() Hook {
+	return func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+		severity, key := alertFields(fields)
+		if severity == "" || !slices.Contains(a.cfg.Severities, severity) {
+			return ent, fields, true
+		}
+		if key == "" {
+			key = ent.LoggerName + ":" + ent.Message
+		}
+		if !a.allow(key, ent.Time) {
+			return ent, fields, true
+		}
+
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range fields {
+			f.AddTo(enc)
+		}
+		a.enqueue(alert{severity: severity, key: key, ent: ent, details: enc.Fields})
+		return ent, fields, true
+	}
+}
+
+// enqueue hands al to the sender unless the queue is full or the alerter closed
+func (a *Alerter) enqueue(al alert) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return
+	}
+	select {
+	case a.queue <- al:
+	default: // Queue full; the entry itself is still logged
+	}
+}
+
+// Close stops the alerter after sending queued alerts. Alert entries logged
+// afterwards are not forwarded; closing again only waits for the sender.
+func (a *Alerter) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	<-a.done
+	return nil
+}
+
+func alertFields(fields []zapcore.Field) (severity, key string) {
+	for _, f := range fields {
+		if f.Type != zapcore.StringType {
+			continue
+		}
+		switch f.Key {
+		case AlertField:
+			severity = f.String
+		case AlertKeyField:
+			key = f.String
+		}
+	}
+	return severity, key
+}
+
+// allow applies the dedup window and the per-minute rate limit
+func (a *Alerter) allow(key string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if last, ok := a.seen[key]; ok && now.Sub(last) < a.cfg.DedupWindow {
+		return false
+	}
+	if now.Sub(a.windowStart) >= time.Minute {
+		a.windowStart, a.sent = now, 0
+		for k, last := range a.seen { // Forget expired keys once a minute
+			if now.Sub(last) >= a.cfg.DedupWindow {
+				delete(a.seen, k)
+			}
+		}
+	}
+	if a.sent >= a.cfg.RateLimit {
+		return false
+	}
+	a.sent++
+	a.seen[key] = now
+	return true
+}
+
+func (a *Alerter) run() {
+	defer close(a.done)
+	for al := range a.queue {
+		if err := a.send(al); err != nil {
+			_ = fallback.Write(zapcore.Entry{
+				Level:   zapcore.WarnLevel,
+				Time:    time.Now(),
+				Message: "failed to send log alert",
+			}, []zapcore.Field{zap.Error(err), zap.String("alert_key", al.key)})
+		}
+	}
+}
+
+func (a *Alerter) send(al alert) error {
+	return postJSON(a.cfg.Client, a.cfg.URL, a.payload(al))
+}
+
+func (a *Alerter) payload(al alert) interface{} {
+	if a.cfg.Format == AlertFormatSlack {
+		return map[string]interface{}{
+			"text": fmt.Sprintf("[%s] %s: %s", al.severity, a.cfg.Source, al.ent.Message),
+		}
+	}
+
+	// Pages are critical; tickets land as low urgency warnings
+	severity := "warning"
+	if al.severity == AlertPage {
+		severity = "critical"
+	}
+	return map[string]interface{}{
+		"routing_key":  a.cfg.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    dedupKey(a.cfg.Source, al.key),
+		"payload": map[string]interface{}{
+			"summary":        al.ent.Message,
+			"source":         a.cfg.Source,
+			"severity":       severity,
+			"timestamp":      al.ent.Time.UTC().Format(time.RFC3339),
+			"component":      al.ent.LoggerName,
+			"custom_details": al.details,
+		},
+	}
+}
+
+// dedupKey hashes the key so PagerDuty groups repeats across restarts
+func dedupKey(source, key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(source + "\x00" + key))
+	return fmt.Sprintf("%s-%016x", source, h.Sum64())
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The benchmarks below and those in logger_test.go are the performance
+// gate: make bench-check compares them with testdata/bench-baseline.txt.
+
+func BenchmarkSugared(b *testing.B) {
+	l := New(WithSink(discard))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Infow("message consumed", "topic", "orders", "partition", 3, "offset", int64(i))
+	}
+}
+
+func BenchmarkRedaction(b *testing.B) {
+	l := New(WithSink(discard), WithRedaction(RedactionConfig{Keys: []string{"password", "card_number"}}))
+	user := map[string]interface{}{"name": "a", "password": "b", "payment": map[string]interface{}{"card_number": "4111"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Infow("user updated", "user", user, "password", "secret")
+	}
+}
+
+// BenchmarkAsync logs to a buffered sink flushed in the background, which
+// takes the write out of the calling goroutine
+func BenchmarkAsync(b *testing.B) {
+	ws := &zapcore.BufferedWriteSyncer{WS: discard, FlushInterval: time.Second}
+	defer ws.Stop()
+	l := New(WithSink(ws))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("message consumed", zap.String("topic", "orders"), zap.Int64("offset", int64(i)))
+	}
+}
+
+func BenchmarkFanout(b *testing.B) {
+	l := New(
+		WithSink(discard),
+		WithEncodedSink("console", discard, zapcore.InfoLevel),
+		WithEncodedSink("msgpack", discard, zapcore.InfoLevel),
+	)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("message consumed", zap.String("topic", "orders"), zap.Int64("offset", int64(i)))
+	}
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithCanonicalLine is WithRequestFields in canonical log line mode: while
+// the request runs, info and debug entries of loggers from Ctx(ctx) are not
+// written but folded into the completion entry, which becomes one wide
+// event with every field, those added with With on the folded loggers
+// included, the timings from AddRequestTiming and the number of entries it
+// replaced as log.suppressed. Warnings and errors are still written as they
+// happen, and so is everything logged after the completion entry was built. A field logged on several lines keeps the last
+// value, so log ones that vary under distinct keys.
+func WithCanonicalLine(ctx context.Context) context.Context {
+	ctx = WithRequestFields(ctx)
+	rf := ctx.Value(requestFieldsKey{}).(*requestFields)
+	rf.mu.Lock()
+	rf.canonical = true
+	rf.mu.Unlock()
+	return ctx
+}
+
+// timing is the total time spent in one named part of a request
+type timing struct {
+	name string
+	d    time.Duration
+}
+
+// AddRequestTiming adds d to the time spent on name, e.g. "db", in ctx's
+// request; the totals go on the completion entry as timing.<name>_ms
+func AddRequestTiming(ctx context.Context, name string, d time.Duration) {
+	rf, ok := ctx.Value(requestFieldsKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	for i := range rf.timings {
+		if rf.timings[i].name == name {
+			rf.timings[i].d += d
+			return
+		}
+	}
+	rf.timings = append(rf.timings, timing{name, d})
+}
+
+// canonicalRequest returns the fields of ctx's request when it is in canonical mode
+func canonicalRequest(ctx context.Context) *requestFields {
+	rf, ok := ctx.Value(requestFieldsKey{}).(*requestFields)
+	if !ok {
+		return nil
+	}
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if !rf.canonical {
+		return nil
+	}
+	return rf
+}
+
+// canonicalCore folds entries below warn level into a canonical request
+// until its completion entry is built; later entries are written as usual
+type canonicalCore struct {
+	zapcore.Core
+	rf      *requestFields
+	context []zapcore.Field // Fields added through With, folded in with each entry
+}
+
+func canonicalOption(rf *requestFields) zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core { return &canonicalCore{Core: c, rf: rf} })
+}
+
+func (c *canonicalCore) With(fields []zapcore.Field) zapcore.Core {
+	return &canonicalCore{
+		Core:    c.Core.With(fields),
+		rf:      c.rf,
+		context: append(c.context[:len(c.context):len(c.context)], fields...),
+	}
+}
+
+func (c *canonicalCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.WarnLevel || c.rf.completed() {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *canonicalCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.rf.mu.Lock()
+	if c.rf.done { // Completed between Check and Write
+		c.rf.mu.Unlock()
+		return writeThrough(c.Core, ent, fields)
+	}
+	defer c.rf.mu.Unlock()
+	c.rf.suppressed++
+	for _, f := range c.context {
+		c.rf.kv = append(c.rf.kv, f)
+	}
+	for _, f := range fields {
+		c.rf.kv = append(c.rf.kv, f)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCanonicalLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     func(l Logger)
+		written []string               // Messages written while the request runs
+		want    map[string]interface{} // Fields on the completion entry
+	}{
+		{
+			name: "info folded",
+			log: func(l Logger) {
+				l.Infow("cache miss", "cache", "orders")
+				l.Debugw("query", "rows", 3)
+			},
+			want: map[string]interface{}{"cache": "orders", "rows": float64(3), "log.suppressed": float64(2)},
+		},
+		{
+			name: "warnings written",
+			log: func(l Logger) {
+				l.Warnw("slow query", "rows", 3)
+				l.Errorw("query failed", "table", "orders")
+			},
+			written: []string{"slow query", "query failed"},
+			want:    map[string]interface{}{"log.suppressed": float64(0)},
+		},
+		{
+			name: "With fields folded",
+			log: func(l Logger) {
+				l.With("order_id", "o1").Info("order loaded")
+			},
+			want: map[string]interface{}{"order_id": "o1", "log.suppressed": float64(1)},
+		},
+		{
+			name: "last value kept",
+			log: func(l Logger) {
+				l.Infow("attempt", "attempt", 1)
+				l.Infow("attempt", "attempt", 2)
+			},
+			want: map[string]interface{}{"attempt": float64(2), "log.suppressed": float64(2)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := New(WithSink(zapcore.AddSync(&buf)), WithLevel(zapcore.DebugLevel))
+			ctx := WithCanonicalLine(context.Background())
+			AddRequestTiming(ctx, "db", 5*time.Millisecond)
+			tt.log(l.Ctx(ctx))
+
+			lines := entries(t, &buf)
+			if len(lines) != len(tt.written) {
+				t.Fatalf("%d entries written during the request, want %d: %v", len(lines), len(tt.written), lines)
+			}
+			for i, msg := range tt.written {
+				if lines[i]["msg"] != msg {
+					t.Errorf("entry %d = %q, want %q", i, lines[i]["msg"], msg)
+				}
+			}
+
+			l.Ctx(ctx).Infow("request completed", RequestFields(ctx)...)
+			lines = entries(t, &buf)
+			if len(lines) != 1 {
+				t.Fatalf("%d completion entries, want 1", len(lines))
+			}
+			tt.want["timing.db_ms"] = float64(5)
+			for k, v := range tt.want {
+				if got := lines[0][k]; got != v {
+					t.Errorf("%s = %v, want %v", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+// TestCanonicalLineAfterDone checks that entries from goroutines outliving the
+// request are written once the completion entry was built
+func TestCanonicalLineAfterDone(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithSink(zapcore.AddSync(&buf)))
+	ctx := WithCanonicalLine(context.Background())
+	late := l.Ctx(ctx).With("job", "email")
+	late.Info("folded")
+	l.Infow("request completed", RequestFields(ctx)...)
+	buf.Reset()
+
+	late.Info("sent after the response")
+	lines := entries(t, &buf)
+	if len(lines) != 1 || lines[0]["msg"] != "sent after the response" || lines[0]["job"] != "email" {
+		t.Errorf("entries after completion = %v, want the one written with its With fields", lines)
+	}
+}
+
+// entries decodes and resets the JSON lines in buf
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var ent map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &ent); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		out = append(out, ent)
+	}
+	buf.Reset()
+	return out
+}
@@ -0,0 +1,268 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Capture limits
+const (
+	captureEntries = 1000             // Entries kept per ID
+	captureMaxIDs  = 100              // IDs captured at once
+	captureTTL     = 15 * time.Minute // Default capture time
+	captureMaxTTL  = 24 * time.Hour
+)
+
+// captureKeys are the fields correlating an entry with a capture
+var captureKeys = []string{"trace_id", "request_id"}
+
+// Capture records, at debug level whatever the logger's level, the entries
+// correlated with trace or request IDs registered at runtime, so an
+// operator can follow one failing request in full detail without turning
+// on debug logging for all traffic:
+//
+//	curl -X POST 'localhost:6060/debug/capture?id=4bf92f3577b34da6a3ce929d0e0e4736&ttl=10m'
+//	curl 'localhost:6060/debug/capture?id=4bf92f3577b34da6a3ce929d0e0e4736'
+//
+// An entry is correlated when its trace_id or request_id field, usually
+// added by Ctx or the request middleware, equals the ID. While no capture
+// is active debug entries cost nothing extra; while one is, every debug
+// entry is checked for the IDs.
+type Capture struct {
+	mu     sync.Mutex
+	ids    map[string]*captured
+	active atomic.Int32
+}
+
+type captured struct {
+	ring    *entryRing
+	expires time.Time
+}
+
+// NewCapture creates an empty capture
+func NewCapture() *Capture {
+	return &Capture{ids: make(map[string]*captured)}
+}
+
+// WithCapture records the entries correlated with the IDs started on c
+func WithCapture(c *Capture) Option {
+	core := &captureCore{capture: c, enc: newEncoder("json")}
+	return func(o *options) { o.extraCores = append(o.extraCores, namedCore{"capture", core}) }
+}
+
+// Start captures the entries for id for ttl (default 15 minutes, at most a
+// day), keeping the last 1000; starting an ID again extends it. It reports
+// false when 100 IDs are already being captured.
+func (c *Capture) Start(id string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		ttl = captureTTL
+	}
+	if ttl > captureMaxTTL {
+		ttl = captureMaxTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire()
+	time.AfterFunc(ttl, func() { // Turns debug entries off again once nothing is captured
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.expire()
+	})
+	if cp, ok := c.ids[id]; ok {
+		cp.expires = time.Now().Add(ttl)
+		return true
+	}
+	if len(c.ids) >= captureMaxIDs {
+		return false
+	}
+	c.ids[id] = &captured{ring: newEntryRing(captureEntries), expires: time.Now().Add(ttl)}
+	c.active.Store(int32(len(c.ids)))
+	return true
+}
+
+// Stop ends the capture for id and discards its entries
+func (c *Capture) Stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids, id)
+	c.active.Store(int32(len(c.ids)))
+}
+
+// IDs returns the IDs being captured
+func (c *Capture) IDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire()
+	ids := make([]string, 0, len(c.ids))
+	for id := range c.ids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Dump writes the entries captured for id as JSON lines, oldest first; it
+// reports false when id is not being captured
+func (c *Capture) Dump(w io.Writer, id string) (bool, error) {
+	c.mu.Lock()
+	c.expire()
+	cp, ok := c.ids[id]
+	c.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	for _, line := range cp.ring.snapshot() {
+		if _, err := w.Write(append(line[:len(line):len(line)], '\n')); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// expire drops the captures past their time; callers hold c.mu
+func (c *Capture) expire() {
+	now := time.Now()
+	for id, cp := range c.ids {
+		if !now.Before(cp.expires) {
+			delete(c.ids, id)
+		}
+	}
+	c.active.Store(int32(len(c.ids)))
+}
+
+// lookup returns the ring for id, if it is being captured
+func (c *Capture) lookup(id string) *entryRing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp, ok := c.ids[id]
+	if !ok || time.Now().After(cp.expires) {
+		return nil
+	}
+	return cp.ring
+}
+
+// ServeHTTP starts a capture on POST ?id=&ttl=, dumps it on GET ?id=, stops
+// it on DELETE ?id= and lists the active IDs on GET without an ID
+func (c *Capture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string][]string{"ids": c.IDs()})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		var ttl time.Duration
+		if s := r.URL.Query().Get("ttl"); s != "" {
+			var err error
+			if ttl, err = time.ParseDuration(s); err != nil || ttl <= 0 {
+				http.Error(w, "ttl must be a positive duration, e.g. 10m", http.StatusBadRequest)
+				return
+			}
+		}
+		if !c.Start(id, ttl) {
+			http.Error(w, "too many captures, at most "+strconv.Itoa(captureMaxIDs), http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if ok, _ := c.Dump(w, id); !ok {
+			http.Error(w, "no capture for "+id, http.StatusNotFound)
+		}
+	case http.MethodDelete:
+		c.Stop(id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// captureCore writes correlated entries into the capture's rings
+type captureCore struct {
+	capture *Capture
+	enc     zapcore.Encoder // Carries the fields added with With
+	ids     []string        // Correlation IDs among the With fields
+}
+
+// Enabled turns debug entries on only while something is being captured
+func (c *captureCore) Enabled(zapcore.Level) bool {
+	return c.capture.active.Load() > 0
+}
+
+func (c *captureCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &captureCore{capture: c.capture, enc: enc, ids: appendCaptureIDs(c.ids[:len(c.ids):len(c.ids)], fields)}
+}
+
+func (c *captureCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *captureCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var line []byte
+	for _, id := range appendCaptureIDs(c.ids[:len(c.ids):len(c.ids)], fields) {
+		ring := c.capture.lookup(id)
+		if ring == nil {
+			continue
+		}
+		if line == nil {
+			buf, err := c.enc.EncodeEntry(ent, fields)
+			if err != nil {
+				return err
+			}
+			line = append([]byte(nil), buf.Bytes()...)
+			buf.Free()
+			if n := len(line); n > 0 && line[n-1] == '\n' {
+				line = line[:n-1]
+			}
+		}
+		ring.add(line)
+	}
+	return nil
+}
+
+func (c *captureCore) Sync() error { return nil }
+
+// appendCaptureIDs appends the values of the correlation fields among fields
+func appendCaptureIDs(ids []string, fields []zapcore.Field) []string {
+	for _, f := range fields {
+		if f.Type != zapcore.StringType {
+			continue
+		}
+		for _, k := range captureKeys {
+			if f.Key == k && f.String != "" {
+				ids = append(ids, f.String)
+			}
+		}
+	}
+	return ids
+}
+
+// captures is the capture of the shared logger
+var captures = NewCapture()
+
+// CaptureHandler serves the shared logger's capture, see Capture.ServeHTTP
+func CaptureHandler() http.Handler {
+	return captures
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// baggageKeys are the baggage entries copied onto entries by Ctx, set from Config.BaggageKeys
+var baggageKeys []string
+
+// Ctx returns a logger carrying the trace_id and span_id of the active span
+// in ctx and the configured baggage keys, so cross-service context shows up
+// in every entry. Under WithCanonicalLine its info and debug entries are
+// folded into the request's completion entry instead of being written.
+func (l Logger) Ctx(ctx context.Context) Logger {
+	var fields []interface{}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+	if len(baggageKeys) > 0 {
+		b := baggage.FromContext(ctx)
+		for _, key := range baggageKeys {
+			if v := b.Member(key).Value(); v != "" {
+				fields = append(fields, key, v)
+			}
+		}
+	}
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		l.span = span
+	}
+	s := l.SugaredLogger
+	if len(fields) > 0 {
+		s = s.With(fields...)
+	}
+	// Folded in after the trace fields, which the completion entry carries already
+	if rf := canonicalRequest(ctx); rf != nil {
+		s = s.WithOptions(canonicalOption(rf))
+	}
+	if s == l.SugaredLogger {
+		return l
+	}
+	return wrap(s, l.span)
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/buildinfo"
+)
+
+// CrashDumpConfig configures the bundle written when a Fatal or Panic entry
+// is logged, for postmortems when central logging lagged behind the crash.
+// The bundle is one JSON document holding the crash entry, the last Entries
+// entries before it, a dump of every goroutine and the build info.
+type CrashDumpConfig struct {
+	Dir     string        // Directory the bundle is written to; defaults to the temp dir when Upload is unset
+	Entries int           // Recent entries kept for the bundle (default 100)
+	Service string        // Used in the bundle name, crash-<service>-<time>-<pid>.json
+	Timeout time.Duration // Bound for Upload (default 10s)
+
+	// Upload optionally stores the bundle elsewhere, e.g. in object storage
+	Upload func(ctx context.Context, name string, bundle []byte) error
+}
+
+// CrashBundle is the document written by the crash dump
+type CrashBundle struct {
+	Time       time.Time         `json:"time"`
+	Level      string            `json:"level"`
+	Message    string            `json:"message"`
+	Build      buildinfo.Info    `json:"build"`
+	Entries    []json.RawMessage `json:"entries"` // Oldest first, ending with the crash entry
+	Goroutines string            `json:"goroutines"`
+}
+
+// WithCrashDump keeps the recent entries, at the logger's level, and writes
+// the bundle described by cfg before a Fatal entry exits the process
+func WithCrashDump(cfg CrashDumpConfig) Option {
+	if cfg.Entries <= 0 {
+		cfg.Entries = 100
+	}
+	if cfg.Dir == "" && cfg.Upload == nil {
+		cfg.Dir = os.TempDir()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	d := &crashDumper{cfg: cfg, ring: newEntryRing(cfg.Entries)}
+	return func(o *options) {
+		core := &ringCore{LevelEnabler: o.level, ring: d.ring, enc: newEncoder("json"), crash: d}
+		o.extraCores = append(o.extraCores, namedCore{"crash dump", core})
+	}
+}
+
+// crashDumper writes the bundle for the ring core it is attached to
+type crashDumper struct {
+	cfg  CrashDumpConfig
+	ring *entryRing
+	once sync.Once
+}
+
+// dump writes the bundle for the first crash of the process; failures are
+// reported to stderr since the logger is about to stop
+func (d *crashDumper) dump(ent zapcore.Entry) {
+	d.once.Do(func() { d.write(ent) })
+}
+
+func (d *crashDumper) write(ent zapcore.Entry) {
+	bundle := CrashBundle{
+		Time:       ent.Time,
+		Level:      ent.Level.String(),
+		Message:    ent.Message,
+		Build:      buildinfo.Get(),
+		Goroutines: goroutineDump(),
+	}
+	for _, line := range d.ring.snapshot() {
+		bundle.Entries = append(bundle.Entries, json.RawMessage(line))
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		reportCrashDumpError(err, ent)
+		return
+	}
+
+	prefix := "crash-"
+	if d.cfg.Service != "" {
+		prefix += d.cfg.Service + "-"
+	}
+	name := fmt.Sprintf("%s%s-%d.json", prefix, ent.Time.UTC().Format("20060102T150405Z"), os.Getpid())
+	if d.cfg.Dir != "" {
+		if err := os.WriteFile(filepath.Join(d.cfg.Dir, name), data, 0o600); err != nil {
+			reportCrashDumpError(err, ent)
+		}
+	}
+	if d.cfg.Upload != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), d.cfg.Timeout)
+		defer cancel()
+		if err := d.cfg.Upload(ctx, name, data); err != nil {
+			reportCrashDumpError(err, ent)
+		}
+	}
+}
+
+// goroutineDump returns the stacks of all goroutines, growing the buffer
+// up to 64 MiB until they fit
+func goroutineDump() string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 64<<20 {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func reportCrashDumpError(err error, ent zapcore.Entry) {
+	_ = fallback.Write(zapcore.Entry{
+		Level:   zapcore.ErrorLevel,
+		Time:    time.Now(),
+		Message: "failed to write crash dump",
+	}, []zapcore.Field{zap.Error(err), zap.String("entry_message", ent.Message)})
+}
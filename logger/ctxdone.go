@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WatchContext logs a warning if ctx ends before the returned stop function
+// is called, naming the operation, the time since WatchContext and the cause
+// recorded with context.WithCancelCause or context.WithTimeoutCause:
+//
+//	defer logger.WatchContext(ctx, log, "charge card")()
+//
+// The entry is logged from another goroutine as soon as ctx is done, so it
+// shows up even when the operation itself is stuck.
+func WatchContext(ctx context.Context, log FieldLogger, operation string) (stop func()) {
+	start := time.Now()
+	stopFn := context.AfterFunc(ctx, func() { logContextDone(ctx, log, operation, start) })
+	return func() { stopFn() }
+}
+
+// LogContextDone logs the same entry as WatchContext when ctx has already
+// ended and reports whether it did; call it after an operation failed to
+// tell timeouts apart from other errors
+func LogContextDone(ctx context.Context, log FieldLogger, operation string, start time.Time) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	logContextDone(ctx, log, operation, start)
+	return true
+}
+
+func logContextDone(ctx context.Context, log FieldLogger, operation string, start time.Time) {
+	reason := "canceled"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = "deadline_exceeded"
+	}
+	kv := []interface{}{
+		"operation", operation,
+		"reason", reason,
+		"elapsed_ms", time.Since(start).Milliseconds(),
+	}
+	if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() {
+		kv = append(kv, "cause", cause.Error())
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		kv = append(kv, "deadline", deadline, "budget_ms", deadline.Sub(start).Milliseconds())
+	}
+	if l, ok := log.(Logger); ok {
+		log = l.Ctx(ctx) // Attach the trace of the operation
+	}
+	log.Warnw("context done", kv...)
+}
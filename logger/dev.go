@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithDevMode switches to the development setup: colored console lines with
+// fields pretty-printed below them, caller paths relative to the working
+// directory, and DPanic entries that panic
+func WithDevMode() Option {
+	return func(o *options) { o.devMode = true }
+}
+
+var devPool = buffer.NewPool()
+
+// devEncoder renders the entry line with the console encoder and the fields
+// as indented JSON underneath, which keeps nested objects readable
+type devEncoder struct {
+	*zapcore.MapObjectEncoder // Fields added through With
+	line                      zapcore.Encoder
+}
+
+func newDevEncoder() zapcore.Encoder {
+	cfg := zap.NewDevelopmentEncoderConfig()
+	cfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	cfg.EncodeTime = zapcore.TimeEncoderOfLayout("15:04:05.000")
+	cfg.EncodeCaller = relativeCallerEncoder(workingDir())
+	return &devEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), line: zapcore.NewConsoleEncoder(cfg)}
+}
+
+func (e *devEncoder) Clone() zapcore.Encoder {
+	clone := &devEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), line: e.line}
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+func (e *devEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line, err := e.line.EncodeEntry(ent, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer line.Free()
+
+	buf := devPool.Get()
+	buf.Write([]byte(strings.TrimRight(line.String(), "\n")))
+
+	m := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		m.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+	if len(m.Fields) > 0 {
+		pretty, err := json.MarshalIndent(m.Fields, "\t", "  ")
+		if err != nil {
+			pretty = []byte(err.Error())
+		}
+		buf.AppendString("\n\t")
+		buf.Write(pretty)
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// relativeCallerEncoder prints callers relative to dir, e.g. internal/app/app.go:42
+func relativeCallerEncoder(dir string) zapcore.CallerEncoder {
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		if dir != "" {
+			if rel, err := filepath.Rel(dir, caller.File); err == nil && !strings.HasPrefix(rel, "..") {
+				enc.AppendString(rel + ":" + strconv.Itoa(caller.Line))
+				return
+			}
+		}
+		enc.AppendString(caller.TrimmedPath())
+	}
+}
+
+func workingDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return dir
+}
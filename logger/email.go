@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// EmailConfig configures a sink that mails Fatal entries immediately and,
+// with a digest interval, rolls Error entries up into periodic summaries.
+// Production typically enables both, staging only the digest.
+type EmailConfig struct {
+	Addr     string   // SMTP server host:port
+	Username string   // Optional PLAIN auth
+	Password string   // Optional PLAIN auth
+	From     string   // Sender address
+	To       []string // Recipients
+	Service  string   // Used in the subject line
+
+	Immediate      bool          // Mail DPanic, Panic and Fatal entries as they happen
+	DigestInterval time.Duration // Roll Error entries up into a digest this often; 0 disables digests
+	DigestMax      int           // Entries kept per digest, extras are only counted (default 100)
+	Timeout        time.Duration // Bound on connecting to the server and sending one mail (default 10s)
+}
+
+// WithEmail adds the SMTP sink. Pending digest entries are mailed on Sync,
+// so call Sync before exiting.
+func WithEmail(cfg EmailConfig) Option {
+	if cfg.DigestMax <= 0 {
+		cfg.DigestMax = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	m := &mailer{cfg: cfg, send: smtpSender(cfg)}
+	if cfg.DigestInterval > 0 {
+		go m.run()
+	}
+	core := &emailCore{m: m, enc: zapcore.NewMapObjectEncoder()}
+	return func(o *options) { o.extraCores = append(o.extraCores, namedCore{"email sink", core}) }
+}
+
+// smtpSender sends one plain text mail
+func smtpSender(cfg EmailConfig) func(subject, body string) error {
+	host, _, _ := net.SplitHostPort(cfg.Addr)
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return func(subject, body string) error {
+		var msg bytes.Buffer
+		fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+		fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+		fmt.Fprintf(&msg, "Subject: %s\r\n", mailSubject(subject))
+		fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+		return sendMail(cfg, host, auth, msg.Bytes())
+	}
+}
+
+// mailSubject keeps a subject built from log messages on one header line:
+// line breaks become spaces, so a message can't inject headers, and anything
+// outside printable ASCII is RFC 2047 encoded
+func mailSubject(s string) string {
+	s = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(s)
+	return mime.QEncoding.Encode("utf-8", s)
+}
+
+// sendMail is smtp.SendMail bounded by cfg.Timeout, so an unresponsive
+// server can't hang the Fatal path or the digest loop
+func sendMail(cfg EmailConfig, host string, auth smtp.Auth, msg []byte) error {
+	conn, err := (&net.Dialer{Timeout: cfg.Timeout}).Dial("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(cfg.Timeout)); err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// mailer is shared by an email core and its With children
+type mailer struct {
+	cfg  EmailConfig
+	send func(subject, body string) error
+
+	mu      sync.Mutex
+	digest  []string // Formatted entries since the last digest
+	dropped int      // Entries beyond DigestMax
+	since   time.Time
+}
+
+func (m *mailer) run() {
+	ticker := time.NewTicker(m.cfg.DigestInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := m.flush(); err != nil {
+			reportNotifyError(err, zapcore.Entry{Message: "email digest"})
+		}
+	}
+}
+
+func (m *mailer) add(line string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.digest) == 0 && m.dropped == 0 {
+		m.since = at
+	}
+	if len(m.digest) >= m.cfg.DigestMax {
+		m.dropped++
+		return
+	}
+	m.digest = append(m.digest, line)
+}
+
+// flush mails the pending digest, if any
+func (m *mailer) flush() error {
+	m.mu.Lock()
+	entries, dropped, since := m.digest, m.dropped, m.since
+	m.digest, m.dropped = nil, 0
+	m.mu.Unlock()
+	if len(entries) == 0 && dropped == 0 {
+		return nil
+	}
+
+	total := len(entries) + dropped
+	subject := fmt.Sprintf("[%s] %d errors since %s", m.cfg.Service, total, since.UTC().Format("15:04 MST"))
+	body := strings.Join(entries, "\n\n")
+	if dropped > 0 {
+		body += fmt.Sprintf("\n\n... and %d more", dropped)
+	}
+	return m.send(subject, body)
+}
+
+type emailCore struct {
+	m   *mailer
+	enc *zapcore.MapObjectEncoder // Fields added with With
+}
+
+func (c *emailCore) Enabled(l zapcore.Level) bool {
+	if l >= zapcore.DPanicLevel {
+		return c.m.cfg.Immediate || c.m.cfg.DigestInterval > 0
+	}
+	return l == zapcore.ErrorLevel && c.m.cfg.DigestInterval > 0
+}
+
+func (c *emailCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.enc.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &emailCore{m: c.m, enc: enc}
+}
+
+func (c *emailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *emailCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.enc.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	text := formatMailEntry(ent, enc.Fields)
+
+	if ent.Level >= zapcore.DPanicLevel && c.m.cfg.Immediate {
+		subject := fmt.Sprintf("[%s] %s: %s", c.m.cfg.Service, ent.Level.CapitalString(), ent.Message)
+		return c.m.send(subject, text) // The process may be about to exit
+	}
+	c.m.add(text, ent.Time)
+	if ent.Level >= zapcore.DPanicLevel {
+		return c.m.flush() // Send the digest early rather than lose it on exit
+	}
+	return nil
+}
+
+// Sync mails the pending digest
+func (c *emailCore) Sync() error {
+	return c.m.flush()
+}
+
+func formatMailEntry(ent zapcore.Entry, fields map[string]interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", ent.Time.UTC().Format(time.RFC3339), ent.Level.CapitalString(), ent.Message)
+	if ent.Caller.Defined {
+		fmt.Fprintf(&b, "\n  at %s", ent.Caller.TrimmedPath())
+	}
+	if len(fields) > 0 {
+		if j, err := json.Marshal(fields); err == nil {
+			fmt.Fprintf(&b, "\n  %s", j)
+		}
+	}
+	if ent.Stack != "" {
+		fmt.Fprintf(&b, "\n%s", ent.Stack)
+	}
+	return b.String()
+}
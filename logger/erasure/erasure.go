@@ -0,0 +1,300 @@
+// Package erasure removes a data subject from retained log files, for
+// right-to-be-forgotten requests. Files are rewritten in place, either
+// dropping every entry that mentions the subject or replacing the subject's
+// values with the hash logger.RedactionConfig.HashKeys would have logged,
+// and the run is summed up in an audit record that holds a hash of the
+// subject, never the subject itself. Lines that are not JSON, such as
+// console output, are matched on their text.
+//
+// Run it on rotated files, or with the service stopped: a file is replaced
+// by a new one, so entries still being appended to the old one are lost.
+package erasure
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Erasure modes
+const (
+	ModeRemove = "remove" // Drop matching entries
+	ModeHash   = "hash"   // Keep matching entries with the subject's values hashed
+)
+
+// Request describes one erasure
+type Request struct {
+	ID      string   // Reference of the erasure request, copied to the record
+	Subject string   // Identifier to erase, e.g. a user ID or email address
+	Keys    []string // Keys holding the identifier, at any depth; empty matches it in any string value
+	Mode    string   // ModeRemove (default) or ModeHash
+	Salt    string   // HMAC key for ModeHash and the record; use the logger's RedactionConfig.Salt
+}
+
+// FileResult is the outcome for one file
+type FileResult struct {
+	Path    string `json:"path"`
+	Entries int    `json:"entries"`
+	Matched int    `json:"matched"` // Entries removed or rewritten
+	Error   string `json:"error,omitempty"`
+}
+
+// Record is the audit record of an erasure
+type Record struct {
+	RequestID   string       `json:"request_id"`
+	SubjectHash string       `json:"subject_hash"`
+	Mode        string       `json:"mode"`
+	Keys        []string     `json:"keys,omitempty"`
+	Started     time.Time    `json:"started"`
+	Finished    time.Time    `json:"finished"`
+	Files       []FileResult `json:"files"`
+	Matched     int          `json:"matched"`
+}
+
+// Erase applies req to every file in paths; files ending in .gz are
+// decompressed and compressed again. A file that fails is left untouched and
+// its error noted in the record, whose Files then shows what was done; the
+// returned error lists the failed files.
+func Erase(req Request, paths ...string) (*Record, error) {
+	if req.Subject == "" {
+		return nil, fmt.Errorf("erasure subject must be set")
+	}
+	switch req.Mode {
+	case "":
+		req.Mode = ModeRemove
+	case ModeRemove, ModeHash:
+	default:
+		return nil, fmt.Errorf("unknown erasure mode %q", req.Mode)
+	}
+
+	e := newEraser(req)
+	rec := &Record{
+		RequestID:   req.ID,
+		SubjectHash: logger.HashValue([]byte(req.Salt), req.Subject),
+		Mode:        req.Mode,
+		Keys:        req.Keys,
+		Started:     time.Now().UTC(),
+	}
+	var failed []string
+	for _, path := range paths {
+		res, err := e.file(path)
+		if err != nil {
+			res.Error = err.Error()
+			failed = append(failed, path)
+		}
+		rec.Files = append(rec.Files, res)
+		rec.Matched += res.Matched
+	}
+	rec.Finished = time.Now().UTC()
+	if len(failed) > 0 {
+		return rec, fmt.Errorf("erasure failed for %s", strings.Join(failed, ", "))
+	}
+	return rec, nil
+}
+
+type eraser struct {
+	req  Request
+	keys map[string]bool
+	hash string
+}
+
+func newEraser(req Request) *eraser {
+	e := &eraser{req: req, keys: make(map[string]bool), hash: logger.HashValue([]byte(req.Salt), req.Subject)}
+	for _, k := range req.Keys {
+		e.keys[strings.ToLower(k)] = true
+	}
+	return e
+}
+
+// file rewrites one file through a temporary file renamed over it, so a
+// failure leaves the original as it was
+func (e *eraser) file(path string) (FileResult, error) {
+	res := FileResult{Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return res, err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".erase-*")
+	if err != nil {
+		return res, err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	var r io.Reader = in
+	var w io.Writer = tmp
+	var zw *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			tmp.Close()
+			return res, err
+		}
+		defer zr.Close()
+		r = zr
+		zw = gzip.NewWriter(tmp)
+		w = zw
+	}
+
+	err = e.copy(r, w, &res)
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || res.Matched == 0 {
+		return res, err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return res, err
+	}
+	return res, os.Rename(tmp.Name(), path)
+}
+
+func (e *eraser) copy(r io.Reader, w io.Writer, res *FileResult) error {
+	br := bufio.NewReaderSize(r, 64<<10)
+	bw := bufio.NewWriterSize(w, 64<<10)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			res.Entries++
+			out, matched := e.entry(line)
+			if matched {
+				res.Matched++
+			}
+			if out != nil {
+				if _, werr := bw.Write(out); werr != nil {
+					return werr
+				}
+			}
+		}
+		if err == io.EOF {
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// entry returns the line to write in place of line, nil to drop it, and
+// whether it mentioned the subject
+func (e *eraser) entry(line []byte) ([]byte, bool) {
+	body := bytes.TrimRight(line, "\r\n")
+	prefix := []byte(nil)
+	if len(body) > 0 && body[0] == 0x1e { // json-seq framing
+		prefix, body = body[:1], body[1:]
+	}
+
+	var out bytes.Buffer
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	matched, err := e.value(dec, &out, "")
+	if err != nil || dec.More() {
+		// Not a JSON entry, e.g. console output; match the text
+		if !bytes.Contains(body, []byte(e.req.Subject)) {
+			return line, false
+		}
+		if e.req.Mode == ModeRemove {
+			return nil, true
+		}
+		out.Reset()
+		out.Write(bytes.ReplaceAll(body, []byte(e.req.Subject), []byte(e.hash)))
+	} else if !matched {
+		return line, false
+	} else if e.req.Mode == ModeRemove {
+		return nil, true
+	}
+	rewritten := make([]byte, 0, len(line)+len(e.hash))
+	rewritten = append(rewritten, prefix...)
+	rewritten = append(rewritten, out.Bytes()...)
+	return append(rewritten, line[len(prefix)+len(body):]...), true
+}
+
+// value copies one JSON value from dec to out, hashing the subject's values,
+// and reports whether the subject was found; key is the enclosing object key
+func (e *eraser) value(dec *json.Decoder, out *bytes.Buffer, key string) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		end := byte('}')
+		if t == '[' {
+			end = ']'
+		}
+		out.WriteByte(byte(t))
+		matched := false
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			child := key // Array elements belong to the array's key
+			if t == '{' {
+				kt, err := dec.Token()
+				if err != nil {
+					return false, err
+				}
+				child, _ = kt.(string)
+				writeString(out, child)
+				out.WriteByte(':')
+			}
+			m, err := e.value(dec, out, child)
+			if err != nil {
+				return false, err
+			}
+			matched = matched || m
+		}
+		if _, err := dec.Token(); err != nil {
+			return false, err
+		}
+		out.WriteByte(end)
+		return matched, nil
+	case string:
+		switch {
+		case len(e.keys) > 0 && e.keys[strings.ToLower(key)] && t == e.req.Subject:
+			writeString(out, e.hash)
+			return true, nil
+		case len(e.keys) == 0 && strings.Contains(t, e.req.Subject):
+			writeString(out, strings.ReplaceAll(t, e.req.Subject, e.hash))
+			return true, nil
+		}
+		writeString(out, t)
+	case json.Number:
+		if e.keys[strings.ToLower(key)] && t.String() == e.req.Subject {
+			writeString(out, e.hash)
+			return true, nil
+		}
+		out.WriteString(t.String())
+	case bool:
+		fmt.Fprint(out, t)
+	case nil:
+		out.WriteString("null")
+	}
+	return false, nil
+}
+
+func writeString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	out.Truncate(out.Len() - 1) // Encode appends a newline
+}
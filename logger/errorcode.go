@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/errcode"
+)
+
+// errorCodeCore adds <key>.code, <key>.category and <key>.retryable next to
+// every error field holding an errcode.Error, directly or wrapped, so alerts
+// can match on error.code rather than on message text
+type errorCodeCore struct {
+	zapcore.Core
+}
+
+func newErrorCodeCore(core zapcore.Core) zapcore.Core {
+	return &errorCodeCore{Core: core}
+}
+
+func (c *errorCodeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &errorCodeCore{Core: c.Core.With(appendErrorCodes(fields))}
+}
+
+func (c *errorCodeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorCodeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, appendErrorCodes(fields))
+}
+
+// appendErrorCodes returns fields with the code fields of coded errors
+// added; fields is returned as is when it holds none
+func appendErrorCodes(fields []zapcore.Field) []zapcore.Field {
+	out := fields
+	for _, f := range fields {
+		if f.Type != zapcore.ErrorType {
+			continue
+		}
+		err, _ := f.Interface.(error)
+		e, ok := errcode.From(err)
+		if !ok {
+			continue
+		}
+		out = append(out[:len(out):len(out)],
+			zap.String(f.Key+".code", e.Code),
+			zap.String(f.Key+".category", string(e.Category)),
+			zap.Bool(f.Key+".retryable", e.Retryable),
+		)
+	}
+	return out
+}
@@ -0,0 +1,205 @@
+// Package events defines the common log events, so every team logs an HTTP
+// request or a database query with the same field names and types and one
+// dashboard works for all services. Each event is a type whose fields are
+// the event's attributes; Log writes it with the event name as message and
+// "event" field and the attributes under the event's prefix:
+//
+//	events.Log(log, events.DBQuery{System: "postgres", Operation: "SELECT", Table: "orders", Rows: n, Duration: time.Since(start), Err: err})
+//	// msg=db.query event=db.query db.system=postgres db.operation=SELECT db.table=orders db.rows=3 db.duration_ms=4.2
+//
+// Durations are logged with fields.DurationMS and sizes with fields.Bytes.
+// Empty optional attributes are left out. Events that failed are logged at
+// error level with an "error" field, the others at info.
+package events
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/logger/fields"
+)
+
+// Event names
+const (
+	NameHTTPRequest  = "http.request"
+	NameDBQuery      = "db.query"
+	NameCacheHit     = "cache.hit"
+	NameCacheMiss    = "cache.miss"
+	NameExternalCall = "external.call"
+	NameJobRun       = "job.run"
+)
+
+// Event is one of the event types of this package
+type Event interface {
+	// Name returns the event name, e.g. "http.request"
+	Name() string
+	// Fields returns the attributes as key-value pairs for the sugared API
+	Fields() []interface{}
+	// failed returns the error to log, if the event failed
+	failed() error
+}
+
+// Log writes e to l
+func Log(l logger.FieldLogger, e Event) {
+	if zl, ok := l.(logger.Logger); ok {
+		l = zl.CallerSkip(1) // Report the caller of Log
+	}
+	kv := append([]interface{}{"event", e.Name()}, e.Fields()...)
+	if err := e.failed(); err != nil {
+		l.Errorw(e.Name(), append(kv, "error", err)...)
+		return
+	}
+	l.Infow(e.Name(), kv...)
+}
+
+// HTTPRequest is a request served by this service
+type HTTPRequest struct {
+	Method        string
+	Route         string // Route pattern, e.g. "/orders/{id}", not the raw path
+	Status        int
+	Duration      time.Duration
+	RequestBytes  int64
+	ResponseBytes int64
+	ClientIP      string
+}
+
+func (e HTTPRequest) Name() string { return NameHTTPRequest }
+
+func (e HTTPRequest) Fields() []interface{} {
+	kv := []interface{}{
+		zap.String("http.method", e.Method),
+		zap.String("http.route", e.Route),
+		zap.Int("http.status_code", e.Status),
+		fields.DurationMS("http.duration", e.Duration),
+		fields.Bytes("http.request", e.RequestBytes),
+		fields.Bytes("http.response", e.ResponseBytes),
+	}
+	if e.ClientIP != "" {
+		kv = append(kv, zap.String("http.client_ip", e.ClientIP))
+	}
+	return kv
+}
+
+// failed treats server errors as failures; client errors are the client's
+func (e HTTPRequest) failed() error {
+	if e.Status >= 500 {
+		return httpError(e.Status)
+	}
+	return nil
+}
+
+type httpError int
+
+func (e httpError) Error() string { return http.StatusText(int(e)) }
+
+// DBQuery is a database query or statement
+type DBQuery struct {
+	System    string // e.g. "postgres", "redis"
+	Operation string // e.g. "SELECT", "INSERT"
+	Table     string
+	Rows      int64 // Rows returned or affected
+	Duration  time.Duration
+	Err       error
+}
+
+func (e DBQuery) Name() string { return NameDBQuery }
+
+func (e DBQuery) Fields() []interface{} {
+	kv := []interface{}{
+		zap.String("db.system", e.System),
+		zap.String("db.operation", e.Operation),
+	}
+	if e.Table != "" {
+		kv = append(kv, zap.String("db.table", e.Table))
+	}
+	return append(kv,
+		zap.Int64("db.rows", e.Rows),
+		fields.DurationMS("db.duration", e.Duration),
+	)
+}
+
+func (e DBQuery) failed() error { return e.Err }
+
+// CacheHit is a cache lookup; it is named cache.hit or cache.miss after Hit
+type CacheHit struct {
+	Cache    string // Cache name, e.g. "sessions"
+	Hit      bool
+	Duration time.Duration
+}
+
+func (e CacheHit) Name() string {
+	if e.Hit {
+		return NameCacheHit
+	}
+	return NameCacheMiss
+}
+
+func (e CacheHit) Fields() []interface{} {
+	return []interface{}{
+		zap.String("cache.name", e.Cache),
+		zap.Bool("cache.hit", e.Hit),
+		fields.DurationMS("cache.duration", e.Duration),
+	}
+}
+
+func (e CacheHit) failed() error { return nil }
+
+// ExternalCall is a call to another service or a third-party API
+type ExternalCall struct {
+	Service   string // Called service, e.g. "payments"
+	Operation string // e.g. "POST /charges" or an RPC method
+	Status    int    // HTTP or RPC status code; 0 when there was no response
+	Attempt   int    // 1 for the first try
+	Duration  time.Duration
+	Err       error
+}
+
+func (e ExternalCall) Name() string { return NameExternalCall }
+
+func (e ExternalCall) Fields() []interface{} {
+	kv := []interface{}{
+		zap.String("external.service", e.Service),
+		zap.String("external.operation", e.Operation),
+	}
+	if e.Status != 0 {
+		kv = append(kv, zap.Int("external.status_code", e.Status))
+	}
+	if e.Attempt > 0 {
+		kv = append(kv, zap.Int("external.attempt", e.Attempt))
+	}
+	return append(kv, fields.DurationMS("external.duration", e.Duration))
+}
+
+func (e ExternalCall) failed() error { return e.Err }
+
+// JobRun is one run of a scheduled or background job
+type JobRun struct {
+	Job      string // Job name, e.g. "nightly-settlement"
+	RunID    string
+	Items    int64 // Items processed
+	Duration time.Duration
+	Err      error
+}
+
+func (e JobRun) Name() string { return NameJobRun }
+
+func (e JobRun) Fields() []interface{} {
+	status := "succeeded"
+	if e.Err != nil {
+		status = "failed"
+	}
+	kv := []interface{}{zap.String("job.name", e.Job)}
+	if e.RunID != "" {
+		kv = append(kv, zap.String("job.run_id", e.RunID))
+	}
+	return append(kv,
+		zap.String("job.status", status),
+		zap.Int64("job.items", e.Items),
+		fields.DurationMS("job.duration", e.Duration),
+	)
+}
+
+func (e JobRun) failed() error { return e.Err }
@@ -0,0 +1,70 @@
+// Package fields builds log fields for measured quantities with a fixed unit
+// and key suffix, so dashboards can rely on every duration being logged as
+// float milliseconds in a *_ms field, every size as bytes in *_bytes and
+// every share as 0..100 in *_pct, whichever package logs them:
+//
+//	log.Infow("request served",
+//		fields.DurationMS("latency", elapsed),    // latency_ms: 12.5
+//		fields.Bytes("response", n),              // response_bytes: 5120
+//		fields.Percent("cache_hit", hits, total), // cache_hit_pct: 87.5
+//	)
+//
+// A key that already ends in the suffix is kept as is. The fields are typed
+// zap fields, usable with the sugared and the typed API alike.
+package fields
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Key suffixes
+const (
+	SuffixMS      = "_ms"
+	SuffixBytes   = "_bytes"
+	SuffixPercent = "_pct"
+)
+
+// DurationMS logs d as float milliseconds under key_ms
+func DurationMS(key string, d time.Duration) zap.Field {
+	return zap.Float64(Key(key, SuffixMS), float64(d)/float64(time.Millisecond))
+}
+
+// Integer is the set of types Bytes accepts
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Bytes logs the size n under key_bytes
+func Bytes[T Integer](key string, n T) zap.Field {
+	return zap.Int64(Key(key, SuffixBytes), int64(n))
+}
+
+// Percent logs part as a share of whole, from 0 to 100, under key_pct. A
+// zero whole logs 0 rather than NaN.
+func Percent(key string, part, whole float64) zap.Field {
+	pct := 0.0
+	if whole != 0 {
+		pct = part / whole * 100
+	}
+	return zap.Float64(Key(key, SuffixPercent), pct)
+}
+
+// Ratio logs a 0..1 ratio as a percentage under key_pct
+func Ratio(key string, ratio float64) zap.Field {
+	if math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+		ratio = 0
+	}
+	return zap.Float64(Key(key, SuffixPercent), ratio*100)
+}
+
+// Key returns key with suffix appended unless it already ends with it
+func Key(key, suffix string) string {
+	if strings.HasSuffix(key, suffix) {
+		return key
+	}
+	return key + suffix
+}
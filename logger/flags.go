@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"container/list"
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"go.uber.org/zap/zapcore"
+)
+
+// FlagEvaluator is the part of an OpenFeature client the logger uses;
+// *openfeature.Client implements it
+type FlagEvaluator interface {
+	StringValue(ctx context.Context, flag string, defaultValue string, evalCtx openfeature.EvaluationContext, options ...openfeature.Option) (string, error)
+	FloatValue(ctx context.Context, flag string, defaultValue float64, evalCtx openfeature.EvaluationContext, options ...openfeature.Option) (float64, error)
+	BooleanValue(ctx context.Context, flag string, defaultValue bool, evalCtx openfeature.EvaluationContext, options ...openfeature.Option) (bool, error)
+}
+
+// FlagConfig names the feature flags that tune logging at runtime. Flags
+// are evaluated with the service and, when the logger carries a tenant field
+// (e.g. added by Ctx from baggage), the tenant as targeting key, so a single
+// tenant can be switched to debug logging.
+type FlagConfig struct {
+	Service    string        // Sent as the "service" evaluation attribute
+	LevelFlag  string        // String flag holding a level name; empty or invalid keeps the configured level
+	SampleFlag string        // Float flag with the share of entries below error to keep, 0..1
+	RedactFlag string        // Boolean flag that masks RedactKeys when true
+	RedactKeys []string      // Keys masked while RedactFlag is on, at any depth as with WithRedaction
+	TenantKey  string        // Field identifying the tenant (default "tenant")
+	CacheTTL   time.Duration // How long evaluations are served before being refreshed (default 30s)
+	MaxTenants int           // Tenants whose evaluations are kept, least recently used dropped first (default 1000)
+}
+
+// WithFlags makes verbosity, sampling and redaction follow feature flags.
+// The configured level stays the default when a flag is unset. Flags are
+// evaluated in the background and never on the logging path: entries use
+// the last evaluation, a stale one triggers a refresh, and a tenant seen for
+// the first time follows the service-wide evaluation until its own arrives.
+func WithFlags(client FlagEvaluator, cfg FlagConfig) Option {
+	if cfg.TenantKey == "" {
+		cfg.TenantKey = "tenant"
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 30 * time.Second
+	}
+	if cfg.MaxTenants <= 0 {
+		cfg.MaxTenants = 1000
+	}
+	return func(o *options) { o.flags = &flagOptions{client: client, cfg: cfg} }
+}
+
+type flagOptions struct {
+	client FlagEvaluator
+	cfg    FlagConfig
+}
+
+// flagRules is one evaluation of the flags
+type flagRules struct {
+	level   *zapcore.Level
+	sample  float64
+	redact  bool
+	expires time.Time
+}
+
+// flagEntry holds the latest evaluation for one tenant. Cores keep the entry
+// of their tenant, so logging reads it without touching the cache.
+type flagEntry struct {
+	tenant     string
+	rules      atomic.Pointer[flagRules] // nil until the first evaluation
+	refreshing atomic.Bool
+}
+
+// flagCache shares evaluations between a core and its With children
+type flagCache struct {
+	*flagOptions
+	redactor *redactor
+	service  *flagEntry // The service as a whole, the fallback of new tenants
+
+	mu      sync.Mutex
+	order   *list.List // Tenant entries, front most recently used
+	tenants map[string]*list.Element
+}
+
+func newFlagCache(flags *flagOptions) *flagCache {
+	return &flagCache{
+		flagOptions: flags,
+		redactor:    newRedactor(RedactionConfig{Keys: flags.cfg.RedactKeys}),
+		service:     &flagEntry{},
+		order:       list.New(),
+		tenants:     make(map[string]*list.Element),
+	}
+}
+
+// entry returns the entry of tenant, adding it and dropping the least
+// recently used one past MaxTenants
+func (c *flagCache) entry(tenant string) *flagEntry {
+	if tenant == "" {
+		return c.service
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.tenants[tenant]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*flagEntry)
+	}
+	e := &flagEntry{tenant: tenant}
+	c.tenants[tenant] = c.order.PushFront(e)
+	if c.order.Len() > c.cfg.MaxTenants {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.tenants, oldest.Value.(*flagEntry).tenant) // Cores holding it keep using it
+	}
+	return e
+}
+
+// rules returns the latest evaluation for e without blocking, starting a
+// background refresh when it is missing or stale
+func (c *flagCache) rules(e *flagEntry) *flagRules {
+	r := e.rules.Load()
+	if (r == nil || time.Now().After(r.expires)) && e.refreshing.CompareAndSwap(false, true) {
+		go func() {
+			defer e.refreshing.Store(false)
+			e.rules.Store(c.evaluate(e.tenant))
+		}()
+	}
+	if r != nil {
+		return r
+	}
+	if r = c.service.rules.Load(); r != nil && e != c.service {
+		return r
+	}
+	return defaultFlagRules
+}
+
+// defaultFlagRules applies until the first evaluation: the configured level, no sampling
+var defaultFlagRules = &flagRules{sample: 1}
+
+func (c *flagCache) evaluate(tenant string) *flagRules {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	key := tenant
+	if key == "" {
+		key = c.cfg.Service
+	}
+	attrs := map[string]interface{}{"service": c.cfg.Service}
+	if tenant != "" {
+		attrs["tenant"] = tenant
+	}
+	evalCtx := openfeature.NewEvaluationContext(key, attrs)
+
+	r := &flagRules{sample: 1, expires: time.Now().Add(c.cfg.CacheTTL)}
+	if c.cfg.LevelFlag != "" {
+		if v, err := c.client.StringValue(ctx, c.cfg.LevelFlag, "", evalCtx); err == nil && v != "" {
+			if l, err := zapcore.ParseLevel(v); err == nil {
+				r.level = &l
+			}
+		}
+	}
+	if c.cfg.SampleFlag != "" {
+		if v, err := c.client.FloatValue(ctx, c.cfg.SampleFlag, 1, evalCtx); err == nil && v >= 0 && v <= 1 {
+			r.sample = v
+		}
+	}
+	if c.cfg.RedactFlag != "" {
+		r.redact, _ = c.client.BooleanValue(ctx, c.cfg.RedactFlag, false, evalCtx)
+	}
+	return r
+}
+
+// flagCore gates entries by flag-controlled level and redacts them. The
+// cores below it are opened to all levels, so the configured level is
+// enforced here as the fallback.
+type flagCore struct {
+	zapcore.Core
+	cache    *flagCache
+	fallback zapcore.LevelEnabler
+	entry    *flagEntry // Of the tenant named by the core's fields, or the service
+}
+
+// newFlagCore returns the flag core over core, wrapped in the SampleFlag
+// sampler when one is configured; entries matching exempt skip the sampler
+func newFlagCore(core zapcore.Core, flags *flagOptions, fallback zapcore.LevelEnabler, exempt []SamplingExemption) zapcore.Core {
+	cache := newFlagCache(flags)
+	c := &flagCore{Core: core, cache: cache, fallback: fallback, entry: cache.service}
+	if flags.cfg.SampleFlag == "" {
+		return c
+	}
+	return newSampleCore(c, flagSampler{c}, exempt)
+}
+
+// Enabled applies the level in force for the core's tenant
+func (c *flagCore) Enabled(l zapcore.Level) bool {
+	return c.enabled(c.cache.rules(c.entry), l)
+}
+
+func (c *flagCore) enabled(r *flagRules, l zapcore.Level) bool {
+	if r.level != nil {
+		return l >= *r.level
+	}
+	return c.fallback.Enabled(l)
+}
+
+func (c *flagCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	for _, f := range fields {
+		if f.Key == c.cache.cfg.TenantKey && f.Type == zapcore.StringType {
+			clone.entry = c.cache.entry(f.String)
+		}
+	}
+	// Context fields are encoded now, so they are masked by the rules in force now
+	clone.Core = c.Core.With(c.redact(c.cache.rules(clone.entry), fields))
+	return &clone
+}
+
+func (c *flagCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	r := c.cache.rules(c.entry)
+	if !c.enabled(r, ent.Level) {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+func (c *flagCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, c.redact(c.cache.rules(c.entry), fields))
+}
+
+func (c *flagCore) redact(r *flagRules, fields []zapcore.Field) []zapcore.Field {
+	if !r.redact {
+		return fields
+	}
+	return c.cache.redactor.fields(fields)
+}
+
+// flagSampler keeps the share of entries below error that SampleFlag sets
+// for the tenant of the flag core it wraps
+type flagSampler struct {
+	*flagCore
+}
+
+func (c flagSampler) With(fields []zapcore.Field) zapcore.Core {
+	return flagSampler{c.flagCore.With(fields).(*flagCore)}
+}
+
+func (c flagSampler) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r := c.cache.rules(c.entry); r.sample < 1 && ent.Level < zapcore.ErrorLevel && rand.Float64() >= r.sample {
+		return ce
+	}
+	return c.flagCore.Check(ent, ce)
+}
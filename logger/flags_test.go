@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"go.uber.org/zap/zapcore"
+)
+
+// slowFlags answers "debug" for the acme tenant after a delay
+type slowFlags struct {
+	delay time.Duration
+	calls atomic.Int64
+}
+
+func (f *slowFlags) StringValue(_ context.Context, _ string, def string, evalCtx openfeature.EvaluationContext, _ ...openfeature.Option) (string, error) {
+	f.calls.Add(1)
+	time.Sleep(f.delay)
+	if evalCtx.TargetingKey() == "acme" {
+		return "debug", nil
+	}
+	return def, nil
+}
+
+func (f *slowFlags) FloatValue(_ context.Context, _ string, def float64, _ openfeature.EvaluationContext, _ ...openfeature.Option) (float64, error) {
+	return def, nil
+}
+
+func (f *slowFlags) BooleanValue(_ context.Context, _ string, def bool, _ openfeature.EvaluationContext, _ ...openfeature.Option) (bool, error) {
+	return def, nil
+}
+
+func TestFlagsEvaluateInBackground(t *testing.T) {
+	flags := &slowFlags{delay: 200 * time.Millisecond}
+	rec := &writeRecorder{}
+	l := New(WithSink(rec), WithFlags(flags, FlagConfig{LevelFlag: "log-level", CacheTTL: time.Hour}))
+	acme := l.With("tenant", "acme")
+
+	start := time.Now()
+	acme.Debugw("before the evaluation")
+	if d := time.Since(start); d >= flags.delay {
+		t.Fatalf("logging waited %s for the flag evaluation", d)
+	}
+	core := acme.Typed().Core()
+	if !core.Enabled(zapcore.InfoLevel) || core.Enabled(zapcore.DebugLevel) {
+		t.Errorf("the configured info level should apply until the evaluation arrives")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !core.Enabled(zapcore.DebugLevel) {
+		if time.Now().After(deadline) {
+			t.Fatal("tenant level flag never applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	acme.Debugw("after the evaluation")
+	l.Debugw("other tenant")
+
+	var out []string
+	for _, w := range rec.take() {
+		out = append(out, string(w))
+	}
+	if got := strings.Join(out, ""); strings.Contains(got, "before the evaluation") || !strings.Contains(got, "after the evaluation") || strings.Contains(got, "other tenant") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+	if n := flags.calls.Load(); n > 2 {
+		t.Errorf("flags evaluated %d times within the TTL, want one per tenant", n)
+	}
+}
+
+func TestFlagsTenantsBounded(t *testing.T) {
+	cache := newFlagCache(&flagOptions{client: &slowFlags{}, cfg: FlagConfig{MaxTenants: 10}})
+	first := cache.entry("tenant-0")
+	for i := 1; i < 100; i++ {
+		cache.entry(fmt.Sprintf("tenant-%d", i))
+	}
+	if n := cache.order.Len(); n != 10 || len(cache.tenants) != 10 {
+		t.Errorf("%d tenants cached, want 10", n)
+	}
+	if cache.entry("tenant-0") == first {
+		t.Error("least recently used tenant was kept")
+	}
+	if cache.entry("tenant-99") != cache.entry("tenant-99") {
+		t.Error("recent tenant not reused")
+	}
+}
+
+// noSample sets the SampleFlag share to 0, dropping every entry below error
+type noSample struct{ slowFlags }
+
+func (f *noSample) FloatValue(context.Context, string, float64, openfeature.EvaluationContext, ...openfeature.Option) (float64, error) {
+	return 0, nil
+}
+
+func TestFlagSamplingExemptions(t *testing.T) {
+	rec := &writeRecorder{}
+	l := New(WithSink(rec),
+		WithFlags(&noSample{}, FlagConfig{SampleFlag: "log-sample", CacheTTL: time.Hour}),
+		WithSamplingExemptions(SamplingExemption{Key: "audit", Value: "true"}),
+	)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.Infow("probe")
+		if len(rec.take()) == 0 {
+			break // The evaluation arrived
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("sample flag never applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tests := []struct {
+		name string
+		log  func()
+		kept bool
+	}{
+		{"sampled", func() { l.Infow("order viewed") }, false},
+		{"exempt field", func() { l.Infow("order refunded", "audit", true) }, true},
+		{"exempt context", func() { l.With("audit", true).Infow("order refunded") }, true},
+		{"other value", func() { l.Infow("order viewed", "audit", false) }, false},
+		{"error", func() { l.Errorw("order failed") }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.log()
+			if kept := len(rec.take()) == 1; kept != tt.kept {
+				t.Errorf("kept = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
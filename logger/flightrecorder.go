@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FlightRecorder keeps the most recent entries in memory, debug ones
+// included even when the logger's level drops them, and dumps them on
+// demand. It gives the detail of debug logging around an incident without
+// shipping debug volume all the time; the cost is that every debug call
+// is encoded.
+type FlightRecorder struct {
+	ring *entryRing
+}
+
+// NewFlightRecorder creates a recorder keeping the last n entries (default 1000)
+func NewFlightRecorder(n int) *FlightRecorder {
+	if n <= 0 {
+		n = 1000
+	}
+	return &FlightRecorder{ring: newEntryRing(n)}
+}
+
+// WithFlightRecorder records every entry, at any level, in r
+func WithFlightRecorder(r *FlightRecorder) Option {
+	core := &ringCore{LevelEnabler: zapcore.DebugLevel, ring: r.ring, enc: newEncoder("json")}
+	return func(o *options) { o.extraCores = append(o.extraCores, namedCore{"flight recorder", core}) }
+}
+
+// Dump writes the recorded entries as JSON lines, oldest first; n > 0 limits
+// it to the newest n
+func (r *FlightRecorder) Dump(w io.Writer, n int) error {
+	lines := r.ring.snapshot()
+	if n > 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		if _, err := w.Write(append(line[:len(line):len(line)], '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP dumps the entries, e.g. curl localhost:6060/debug/logs?n=200
+func (r *FlightRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	n := 0
+	if s := req.URL.Query().Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n < 0 {
+			http.Error(w, "n must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	_ = r.Dump(w, n)
+}
+
+// DumpOnSignal writes the entries to a new file in dir each time one of sigs
+// arrives, e.g. kill -USR1 <pid> with syscall.SIGUSR1, until stop is called
+func (r *FlightRecorder) DumpOnSignal(dir string, sigs ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				r.dumpFile(dir)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+func (r *FlightRecorder) dumpFile(dir string) {
+	f, err := os.CreateTemp(dir, "flight-"+time.Now().UTC().Format("20060102T150405Z")+"-*.jsonl")
+	if err == nil {
+		err = r.Dump(f, 0)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		_ = fallback.Write(zapcore.Entry{
+			Level:   zapcore.ErrorLevel,
+			Time:    time.Now(),
+			Message: "failed to dump flight recorder",
+		}, []zapcore.Field{zap.Error(err)})
+	}
+}
+
+// recorder is the flight recorder of the shared logger, set from Config.FlightRecorder
+var recorder *FlightRecorder
+
+// FlightRecorderHandler serves the shared logger's flight recorder, or 404
+// when Config.FlightRecorder is not set
+func FlightRecorderHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if recorder == nil {
+			http.Error(w, "flight recorder not enabled", http.StatusNotFound)
+			return
+		}
+		recorder.ServeHTTP(w, req)
+	})
+}
+
+// SharedFlightRecorder returns the shared logger's flight recorder, or nil
+func SharedFlightRecorder() *FlightRecorder {
+	return recorder
+}
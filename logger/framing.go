@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Framing modes for line-oriented collectors; they apply to JSON outputs only
+const (
+	FramingNewline = "newline"  // Encoder output as is (default)
+	FramingStrict  = "strict"   // Exactly one line per entry; stray control characters escaped
+	FramingJSONSeq = "json-seq" // RFC 7464 JSON text sequences: RS <entry> LF
+)
+
+const recordSeparator = 0x1E
+
+// WithFraming hardens how entries are delimited on the output, see the Framing* modes
+func WithFraming(mode string) Option {
+	return func(o *options) { o.framing = mode }
+}
+
+// framedWriter re-frames each encoded entry and writes it with a single
+// Write call, so concurrent writers to a pipe can't interleave partial lines
+type framedWriter struct {
+	zapcore.WriteSyncer
+	seq  bool
+	pool sync.Pool
+}
+
+func newFramedWriter(ws zapcore.WriteSyncer, mode string) zapcore.WriteSyncer {
+	if mode != FramingStrict && mode != FramingJSONSeq {
+		return ws
+	}
+	return &framedWriter{
+		WriteSyncer: ws,
+		seq:         mode == FramingJSONSeq,
+		pool:        sync.Pool{New: func() interface{} { return new(bytes.Buffer) }},
+	}
+}
+
+func (w *framedWriter) Write(p []byte) (int, error) {
+	buf := w.pool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		w.pool.Put(buf)
+	}()
+
+	if w.seq {
+		buf.WriteByte(recordSeparator)
+	}
+	body := bytes.TrimRight(p, "\r\n")
+	for _, b := range body {
+		if b >= 0x20 || b == '\t' {
+			buf.WriteByte(b)
+			continue
+		}
+		// Raw control bytes (including interior newlines from custom
+		// encoders or marshalers) would split or corrupt the record
+		switch b {
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			const hex = "0123456789abcdef"
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[b>>4])
+			buf.WriteByte(hex[b&0xF])
+		}
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.WriteSyncer.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
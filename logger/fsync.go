@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FsyncPolicy controls how often file outputs are flushed to stable storage.
+// The zero value never fsyncs outside Sync, leaving it to the OS, which is
+// fastest; audit logs that must survive a power loss fsync every entry with
+// Entries: 1. Both limits may be set; whichever is reached first fsyncs.
+type FsyncPolicy struct {
+	Entries  int           // Fsync after this many entries; 0 disables
+	Interval time.Duration // Fsync this often while entries are pending; 0 disables
+}
+
+func (p FsyncPolicy) enabled() bool { return p.Entries > 0 || p.Interval > 0 }
+
+// fsyncWriter fsyncs a file output according to a policy
+type fsyncWriter struct {
+	zapcore.WriteSyncer
+	name   string
+	fsync  func() error
+	policy FsyncPolicy
+
+	mu      sync.Mutex
+	pending int           // Entries written since the last fsync
+	closed  bool          // Close was called
+	stop    chan struct{} // Closed by Close to end the interval fsyncs
+}
+
+// NewFsyncWriter writes to f and fsyncs it according to p. Closing the
+// returned writer, which implements io.Closer, closes f and stops the
+// interval fsyncs.
+func NewFsyncWriter(f *os.File, p FsyncPolicy) zapcore.WriteSyncer {
+	return newFsyncWriter(f.Name(), f, f.Sync, p)
+}
+
+// newFsyncWriter wraps ws, calling fsync to make its writes durable
+func newFsyncWriter(name string, ws zapcore.WriteSyncer, fsync func() error, p FsyncPolicy) zapcore.WriteSyncer {
+	if !p.enabled() {
+		return ws
+	}
+	w := &fsyncWriter{WriteSyncer: ws, name: name, fsync: fsync, policy: p, stop: make(chan struct{})}
+	if p.Interval > 0 {
+		go w.run()
+	}
+	return w
+}
+
+func (w *fsyncWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	if err != nil {
+		return n, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending++
+	if w.policy.Entries > 0 && w.pending >= w.policy.Entries {
+		return n, w.flush()
+	}
+	return n, nil
+}
+
+func (w *fsyncWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// Close fsyncs the pending entries, stops the interval fsyncs and closes
+// the wrapped output if it is an io.Closer
+func (w *fsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stop)
+	err := w.flush()
+	w.mu.Unlock()
+	if c, ok := w.WriteSyncer.(io.Closer); ok {
+		err = errors.Join(err, c.Close())
+	}
+	return err
+}
+
+func (w *fsyncWriter) run() {
+	ticker := time.NewTicker(w.policy.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		w.mu.Lock()
+		if w.pending > 0 {
+			if err := w.flush(); err != nil {
+				reportSink("log file fsync failed", zap.String("sink", w.name), zap.Error(err))
+			}
+		}
+		w.mu.Unlock()
+	}
+}
+
+// flush fsyncs the file; callers hold w.mu
+func (w *fsyncWriter) flush() error {
+	w.pending = 0
+	return w.fsync()
+}
@@ -0,0 +1,232 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fuzzSecret is logged under redacted keys and must never reach the output
+const fuzzSecret = "s3cr3t-marker"
+
+// writeRecorder collects the writes of one entry
+type writeRecorder struct {
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (w *writeRecorder) Sync() error { return nil }
+
+func (w *writeRecorder) take() [][]byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	writes := w.writes
+	w.writes = nil
+	return writes
+}
+
+// fuzzTargets are the encodings and framings checked, with their validators
+var fuzzTargets = []struct {
+	encoding string
+	framing  string
+	valid    func([]byte) error
+}{
+	{"json", FramingNewline, validJSONLine},
+	{"json", FramingStrict, validJSONLine},
+	{"json", FramingJSONSeq, validJSONSeq},
+	{"logfmt", "", validLogfmt},
+	{"gelf", FramingNewline, validGELF},
+}
+
+// FuzzEncoders logs arbitrary messages, keys and values, redacted or not,
+// and checks that each entry comes out as exactly one valid record without
+// the redacted value:
+//
+//	go test -run '^$' -fuzz FuzzEncoders -fuzztime 1m ./logger
+func FuzzEncoders(f *testing.F) {
+	f.Add("message consumed", "topic", "orders", []byte("raw"), int64(42), 1.5)
+	f.Add("", "", "", []byte{}, int64(0), 0.0)
+	f.Add("line\nbreak\r\n", "key with spaces", "a=b \"quoted\"", []byte{0x00, 0x1e, 0xff}, int64(math.MinInt64), math.NaN())
+	f.Add("\xc3\x28 invalid", "\xff", "\xe2\x80\xa8", []byte("</script>"), int64(-1), math.Inf(-1))
+	f.Add("password", "PassWord", "card_number", []byte("password"), int64(1), -0.0)
+	f.Add("{\"a\":", "id", "]", []byte("%s"), int64(math.MaxInt64), math.MaxFloat64)
+
+	loggers := make([]*zap.Logger, len(fuzzTargets))
+	recorders := make([]*writeRecorder, len(fuzzTargets))
+	for i, t := range fuzzTargets {
+		recorders[i] = &writeRecorder{}
+		loggers[i] = New(
+			WithSink(recorders[i]),
+			WithEncoding(t.encoding),
+			WithFraming(t.framing),
+			WithRedaction(RedactionConfig{Keys: []string{"password"}, MaxDepth: 6}),
+		).Typed().WithOptions(zap.AddStacktrace(zapcore.FatalLevel))
+	}
+
+	f.Fuzz(func(t *testing.T, msg, key, value string, raw []byte, n int64, x float64) {
+		for _, in := range []string{msg, key, value, string(raw)} {
+			if strings.Contains(in, fuzzSecret) {
+				t.Skip("the marker is only logged under redacted keys")
+			}
+		}
+		fields := []zap.Field{
+			zap.String(key, value),
+			zap.ByteString(key+".bytes", raw),
+			zap.Binary("binary", raw),
+			zap.Int64(value, n),
+			zap.Float64("float", x),
+			zap.Complex128("complex", complex(x, math.Inf(1))),
+			zap.Any("list", []interface{}{value, x, n}),
+			zap.Any("nested", map[string]interface{}{key: map[string]interface{}{value: string(raw), "PassWord": fuzzSecret}}),
+			zap.String("password", fuzzSecret+value),
+		}
+		for i, target := range fuzzTargets {
+			loggers[i].Info(msg, fields...)
+			writes := recorders[i].take()
+			if len(writes) != 1 {
+				t.Fatalf("%s/%s: entry written in %d writes, want 1", target.encoding, target.framing, len(writes))
+			}
+			out := writes[0]
+			if bytes.Contains(out, []byte(fuzzSecret)) {
+				t.Fatalf("%s/%s: redacted value in output: %q", target.encoding, target.framing, out)
+			}
+			if err := target.valid(out); err != nil {
+				t.Fatalf("%s/%s: %v\noutput: %q", target.encoding, target.framing, err, out)
+			}
+		}
+	})
+}
+
+// singleLine returns the record without its terminating newline
+func singleLine(out []byte) ([]byte, error) {
+	body, ok := bytes.CutSuffix(out, []byte("\n"))
+	if !ok {
+		return nil, fmt.Errorf("record not terminated by a newline")
+	}
+	if bytes.ContainsAny(body, "\r\n") {
+		return nil, fmt.Errorf("record spans several lines")
+	}
+	return body, nil
+}
+
+func validJSONLine(out []byte) error {
+	body, err := singleLine(out)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("invalid JSON")
+	}
+	return nil
+}
+
+func validJSONSeq(out []byte) error {
+	body, ok := bytes.CutPrefix(out, []byte{recordSeparator})
+	if !ok {
+		return fmt.Errorf("json-seq record without RS")
+	}
+	return validJSONLine(body)
+}
+
+// validLogfmt parses key=value pairs separated by single spaces, values bare
+// or quoted as strconv.Quote does
+func validLogfmt(out []byte) error {
+	body, err := singleLine(out)
+	if err != nil {
+		return err
+	}
+	s := string(body)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return fmt.Errorf("pair without key at %q", s)
+		}
+		if k := s[:eq]; strings.ContainsAny(k, " \"") || !isPrintable(k) {
+			return fmt.Errorf("invalid key %q", k)
+		}
+		s = s[eq+1:]
+		if strings.HasPrefix(s, `"`) {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return fmt.Errorf("bad quoted value at %q: %v", s, err)
+			}
+			s = s[len(quoted):]
+		} else if i := strings.IndexByte(s, ' '); i >= 0 {
+			if v := s[:i]; v == "" || strings.ContainsAny(v, "\"=") {
+				return fmt.Errorf("invalid bare value %q", v)
+			}
+			s = s[i:]
+		} else {
+			s = ""
+		}
+		if s != "" {
+			if !strings.HasPrefix(s, " ") || strings.HasPrefix(s, "  ") {
+				return fmt.Errorf("pairs not separated by one space at %q", s)
+			}
+			s = s[1:]
+		}
+	}
+	return nil
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if !strconv.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+var gelfAdditional = regexp.MustCompile(`^_[\w.\-]*$`)
+
+// validGELF checks the GELF 1.1 required fields and the additional field rules
+func validGELF(out []byte) error {
+	body, err := singleLine(out)
+	if err != nil {
+		return err
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	if msg["version"] != "1.1" {
+		return fmt.Errorf("version = %v, want 1.1", msg["version"])
+	}
+	for _, k := range []string{"host", "short_message"} {
+		if s, _ := msg[k].(string); s == "" {
+			return fmt.Errorf("%s missing or empty", k)
+		}
+	}
+	for k, v := range msg {
+		switch k {
+		case "version", "host", "short_message", "full_message", "timestamp", "level":
+			continue
+		}
+		if !gelfAdditional.MatchString(k) || k == "_id" {
+			return fmt.Errorf("invalid additional field name %q", k)
+		}
+		switch v.(type) {
+		case string, float64:
+		default:
+			return fmt.Errorf("additional field %q is %T, want a string or number", k, v)
+		}
+	}
+	return nil
+}
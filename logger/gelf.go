@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var gelfPool = buffer.NewPool()
+
+// gelfEncoder writes each entry as a GELF 1.1 message, one JSON object per
+// line, for Graylog inputs reading files or newline-delimited TCP. Fields
+// become additional fields: prefixed with '_', nested keys joined with dots,
+// characters outside [A-Za-z0-9_.-] replaced, and values other than strings
+// and numbers written as strings, which is all GELF accepts.
+type gelfEncoder struct {
+	*zapcore.MapObjectEncoder // Fields added through With
+	host                      string
+}
+
+// NewGELFEncoder creates a GELF encoder reporting the machine's host name
+func NewGELFEncoder() zapcore.Encoder {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return &gelfEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), host: host}
+}
+
+func (e *gelfEncoder) Clone() zapcore.Encoder {
+	clone := &gelfEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), host: e.host}
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+func (e *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	m := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		m.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+	flat := make(map[string]interface{}, len(m.Fields))
+	flatten(flat, "", m.Fields)
+
+	msg := make(map[string]interface{}, len(flat)+8)
+	for k, v := range flat {
+		msg[gelfKey(k)] = gelfValue(v)
+	}
+	msg["version"] = "1.1"
+	msg["host"] = e.host
+	msg["short_message"] = ent.Message
+	if msg["short_message"] == "" {
+		msg["short_message"] = "-" // GELF requires a non-empty short message
+	}
+	if ent.Stack != "" {
+		msg["full_message"] = ent.Message + "\n" + ent.Stack
+	}
+	msg["timestamp"] = float64(ent.Time.UnixNano()) / 1e9
+	msg["level"] = syslogLevel(ent.Level)
+	msg["_level_name"] = ent.Level.String()
+	if ent.LoggerName != "" {
+		msg["_logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		msg["_caller"] = ent.Caller.TrimmedPath()
+	}
+
+	buf := gelfPool.Get()
+	if err := json.NewEncoder(buf).Encode(msg); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// gelfKey turns a field name into a valid additional field name; "_id" is
+// reserved by GELF, so an id field becomes "__id"
+func gelfKey(k string) string {
+	var b strings.Builder
+	b.WriteByte('_')
+	for _, r := range k {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.String() == "_id" {
+		return "__id"
+	}
+	return b.String()
+}
+
+// gelfValue keeps strings and finite numbers and formats anything else as a string
+func gelfValue(v interface{}) interface{} {
+	switch x := encodable(v, true).(type) {
+	case string, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return x
+	default:
+		return logfmtString(x)
+	}
+}
+
+// syslogLevel maps zap levels onto the syslog severities GELF uses
+func syslogLevel(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2
+	}
+	return 1 // Fatal
+}
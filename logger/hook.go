@@ -0,0 +1,45 @@
+package logger
+
+import "go.uber.org/zap/zapcore"
+
+// Hook runs on every entry before it is encoded. It may rewrite the entry
+// and its fields; returning false drops the entry. Fields attached earlier
+// with With are not passed in, only those of the call itself.
+type Hook func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool)
+
+// WithHooks appends hooks to the chain; they run in the order given, ahead
+// of schema checks and truncation, so enriched fields are validated too
+func WithHooks(hooks ...Hook) Option {
+	return func(o *options) { o.hooks = append(o.hooks, hooks...) }
+}
+
+type hookCore struct {
+	zapcore.Core
+	hooks []Hook
+}
+
+func newHookCore(core zapcore.Core, hooks []Hook) zapcore.Core {
+	return &hookCore{Core: core, hooks: hooks}
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{Core: c.Core.With(fields), hooks: c.hooks}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	for i, h := range c.hooks {
+		var ok bool
+		if ent, fields, ok = runHook(h, i, ent, fields); !ok {
+			return nil
+		}
+	}
+	// The level may have been changed by a hook, so re-check against the sinks
+	return writeThrough(c.Core, ent, fields)
+}
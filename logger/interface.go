@@ -0,0 +1,70 @@
+package logger
+
+import "go.uber.org/zap"
+
+// FieldLogger is the small logging interface application packages should
+// take as a parameter. Logger implements it; tests can pass logtest.New or a
+// mock. Child loggers are made on the concrete Logger, whose With and Named
+// return a Logger, or with AddFields on any FieldLogger.
+type FieldLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+var _ FieldLogger = Logger{}
+
+// With returns a child logger carrying the key-value pairs on every entry
+func (l Logger) With(keysAndValues ...interface{}) Logger {
+	return wrap(l.SugaredLogger.With(keysAndValues...), l.span)
+}
+
+// Named returns a child logger with name appended to the logger name
+func (l Logger) Named(name string) Logger {
+	return wrap(l.SugaredLogger.Named(name), l.span)
+}
+
+// AddFields returns l carrying the key-value pairs on every entry: l.With
+// for a Logger, which keeps it a Logger, and a wrapper adding them to each
+// call for any other FieldLogger
+func AddFields(l FieldLogger, keysAndValues ...interface{}) FieldLogger {
+	switch x := l.(type) {
+	case Logger:
+		return x.With(keysAndValues...)
+	case fieldsLogger:
+		return fieldsLogger{next: x.next, kv: append(x.kv[:len(x.kv):len(x.kv)], keysAndValues...)}
+	}
+	return fieldsLogger{next: l, kv: keysAndValues}
+}
+
+// fieldsLogger prepends kv to the pairs of every call on next
+type fieldsLogger struct {
+	next FieldLogger
+	kv   []interface{}
+}
+
+func (l fieldsLogger) with(kv []interface{}) []interface{} {
+	return append(l.kv[:len(l.kv):len(l.kv)], kv...)
+}
+
+func (l fieldsLogger) Debugw(msg string, kv ...interface{}) { l.next.Debugw(msg, l.with(kv)...) }
+func (l fieldsLogger) Infow(msg string, kv ...interface{})  { l.next.Infow(msg, l.with(kv)...) }
+func (l fieldsLogger) Warnw(msg string, kv ...interface{})  { l.next.Warnw(msg, l.with(kv)...) }
+func (l fieldsLogger) Errorw(msg string, kv ...interface{}) { l.next.Errorw(msg, l.with(kv)...) }
+
+// CallerSkip returns a logger reporting the caller n frames further up, for
+// helpers that log on behalf of their caller
+func (l Logger) CallerSkip(n int) Logger {
+	return wrap(l.SugaredLogger.WithOptions(zap.AddCallerSkip(n)), l.span)
+}
+
+// FromZap wraps an existing zap logger
+func FromZap(l *zap.Logger) Logger {
+	return wrap(l.Sugar(), nil)
+}
+
+// Nop returns a logger that discards everything
+func Nop() Logger {
+	return FromZap(zap.NewNop())
+}
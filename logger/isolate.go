@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fallback reports failures of the logger itself; it never goes through
+// user hooks or sinks so it keeps working when they don't
+var fallback = zapcore.NewCore(
+	zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+	zapcore.Lock(os.Stderr),
+	zapcore.DebugLevel,
+)
+
+// reportPanic writes a recovered panic from a hook or sink to stderr
+func reportPanic(source string, r interface{}, ent zapcore.Entry) {
+	_ = fallback.Write(zapcore.Entry{
+		Level:   zapcore.ErrorLevel,
+		Time:    time.Now(),
+		Message: "logger " + source + " panicked",
+	}, []zapcore.Field{
+		zap.String("panic", fmt.Sprint(r)),
+		zap.String("entry_message", ent.Message),
+		zap.String("entry_level", ent.Level.String()),
+	})
+}
+
+// isolatedCore recovers panics from a single sink so the other sinks in the
+// tee still receive the entry
+type isolatedCore struct {
+	zapcore.Core
+	name string
+}
+
+func isolate(core zapcore.Core, name string) zapcore.Core {
+	return &isolatedCore{Core: core, name: name}
+}
+
+func (c *isolatedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &isolatedCore{Core: c.Core.With(fields), name: c.name}
+}
+
+func (c *isolatedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *isolatedCore) Write(ent zapcore.Entry, fields []zapcore.Field) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic(c.name, r, ent)
+			err = fmt.Errorf("%s panicked: %v", c.name, r)
+		}
+	}()
+	return c.Core.Write(ent, fields)
+}
+
+func (c *isolatedCore) Sync() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic(c.name, r, zapcore.Entry{Message: "sync"})
+			err = fmt.Errorf("%s panicked on sync: %v", c.name, r)
+		}
+	}()
+	return c.Core.Sync()
+}
+
+// runHook calls h, leaving the entry untouched if it panics
+func runHook(h Hook, i int, ent zapcore.Entry, fields []zapcore.Field) (outEnt zapcore.Entry, outFields []zapcore.Field, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic(fmt.Sprintf("hook %d", i), r, ent)
+			outEnt, outFields, ok = ent, fields, true
+		}
+	}()
+	return h(ent, fields)
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Where Kubernetes mounts the service account and, by convention, a
+// downward API volume; KUBERNETES_PODINFO_DIR overrides the latter
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	defaultPodInfoDir = "/etc/podinfo"
+)
+
+// KubernetesFields returns the pod name, namespace, node and container as
+// key-value pairs named after the OpenTelemetry resource attributes
+// (k8s.pod.name, ...), or nil outside Kubernetes. Each value is read from an
+// environment variable set through the downward API, falling back to a file
+// of the same name in lower case in the downward API volume:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	- name: POD_NAMESPACE
+//	  valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+//	- name: NODE_NAME
+//	  valueFrom: {fieldRef: {fieldPath: spec.nodeName}}
+//	- name: CONTAINER_NAME
+//	  value: app
+//
+// Without them the pod name is taken from HOSTNAME, which Kubernetes sets to
+// it, and the namespace from the service account.
+func KubernetesFields() []interface{} {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		if _, err := os.Stat(serviceAccountDir); err != nil {
+			return nil
+		}
+	}
+	dir := os.Getenv("KUBERNETES_PODINFO_DIR")
+	if dir == "" {
+		dir = defaultPodInfoDir
+	}
+
+	pod := podInfo(dir, "POD_NAME")
+	if pod == "" {
+		pod = os.Getenv("HOSTNAME")
+	}
+	namespace := podInfo(dir, "POD_NAMESPACE")
+	if namespace == "" {
+		namespace = readTrimmed(filepath.Join(serviceAccountDir, "namespace"))
+	}
+
+	var kv []interface{}
+	for _, f := range []struct{ key, value string }{
+		{"k8s.pod.name", pod},
+		{"k8s.namespace.name", namespace},
+		{"k8s.node.name", podInfo(dir, "NODE_NAME")},
+		{"k8s.container.name", podInfo(dir, "CONTAINER_NAME")},
+	} {
+		if f.value != "" {
+			kv = append(kv, f.key, f.value)
+		}
+	}
+	return kv
+}
+
+// podInfo reads name from the environment or the downward API volume
+func podInfo(dir, name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return readTrimmed(filepath.Join(dir, strings.ToLower(name)))
+}
+
+func readTrimmed(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,37 @@
+// Package klogbridge routes logr and klog output, as used by Kubernetes
+// client-go and controller-runtime, through the package logger so their
+// lines come out as JSON entries with source=klog instead of unstructured
+// text on stderr.
+package klogbridge
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"k8s.io/klog/v2"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Logr returns a logr.Logger writing through l. logr verbosity V(n) maps to
+// zap level -n, so V(1) is debug and higher verbosities are never enabled;
+// the verbosity is recorded in the "v" field.
+func Logr(l logger.Logger) logr.Logger {
+	return zapr.NewLoggerWithOptions(l.Desugar(), zapr.LogInfoLevel("v"), zapr.ErrorKey("error"))
+}
+
+// RedirectKlog sends all klog output, including client-go's, to l
+func RedirectKlog(l logger.Logger) {
+	named := l.Named("klog").With("source", "klog")
+	klog.SetLogger(Logr(named))
+}
+
+// SetVerbosity sets klog's -v level; klog drops V(n) calls above it before
+// they reach the logger, so raise it together with the logger level
+func SetVerbosity(v int) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	_ = fs.Set("v", strconv.Itoa(v))
+}
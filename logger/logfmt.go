@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder writes each entry as one line of key=value pairs, the format
+// Heroku, Loki and most line-oriented tooling parse without configuration:
+//
+//	ts=2024-05-01T10:00:00.123Z level=info caller=kafka/kafka.go:42 msg="message consumed" offset=7 topic=orders
+//
+// The entry keys come first, then the fields sorted by key, nested objects
+// flattened with dots. Keys are reduced to the characters logfmt allows;
+// values with spaces, quotes, '=' or anything unprintable are quoted with Go
+// escapes, so an entry never spans lines whatever it holds.
+type logfmtEncoder struct {
+	*zapcore.MapObjectEncoder // Fields added through With
+	cfg                       zapcore.EncoderConfig
+}
+
+// NewLogfmtEncoder creates a logfmt encoder honoring the key names of cfg
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), cfg: cfg}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), cfg: e.cfg}
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	m := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		m.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+	flat := make(map[string]interface{}, len(m.Fields))
+	flatten(flat, "", m.Fields)
+
+	buf := logfmtPool.Get()
+	pair := func(k, v string) {
+		if buf.Len() > 0 {
+			buf.AppendByte(' ')
+		}
+		buf.AppendString(logfmtKey(k))
+		buf.AppendByte('=')
+		buf.AppendString(logfmtValue(v))
+	}
+	if e.cfg.TimeKey != "" {
+		pair(e.cfg.TimeKey, ent.Time.UTC().Format(time.RFC3339Nano))
+	}
+	if e.cfg.LevelKey != "" {
+		pair(e.cfg.LevelKey, ent.Level.String())
+	}
+	if e.cfg.NameKey != "" && ent.LoggerName != "" {
+		pair(e.cfg.NameKey, ent.LoggerName)
+	}
+	if e.cfg.CallerKey != "" && ent.Caller.Defined {
+		pair(e.cfg.CallerKey, ent.Caller.TrimmedPath())
+	}
+	if e.cfg.MessageKey != "" {
+		pair(e.cfg.MessageKey, ent.Message)
+	}
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pair(k, logfmtString(flat[k]))
+	}
+	if e.cfg.StacktraceKey != "" && ent.Stack != "" {
+		pair(e.cfg.StacktraceKey, ent.Stack)
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// logfmtString formats a field value as MapObjectEncoder stores it
+func logfmtString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		return x.String()
+	case []byte:
+		return base64.StdEncoding.EncodeToString(x)
+	case fmt.Stringer:
+		return x.String()
+	}
+	if data, err := json.Marshal(encodable(v, true)); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// logfmtKey replaces what a logfmt key can't hold: spaces, '=', quotes,
+// control characters and invalid UTF-8
+func logfmtKey(k string) string {
+	if k == "" {
+		return "_"
+	}
+	ok := true
+	for _, r := range k {
+		if !logfmtKeyRune(r) {
+			ok = false
+			break
+		}
+	}
+	if ok {
+		return k
+	}
+	var b strings.Builder
+	for _, r := range k {
+		if logfmtKeyRune(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func logfmtKeyRune(r rune) bool {
+	return r > ' ' && r != '=' && r != '"' && r != utf8.RuneError && unicode.IsPrint(r)
+}
+
+// logfmtValue quotes v unless it is a bare word
+func logfmtValue(v string) string {
+	if v == "" {
+		return `""`
+	}
+	for _, r := range v {
+		if !logfmtKeyRune(r) || r == '\\' {
+			return strconv.Quote(v)
+		}
+	}
+	return v
+}
@@ -0,0 +1,320 @@
+// Package logger is the structured, leveled logger shared by the services,
+// built on go.uber.org/zap.
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/buildinfo"
+)
+
+// Logger is a wrapper around zap.Logger
+type Logger struct {
+	*zap.SugaredLogger
+
+	typed     *zap.Logger        // Desugared once so the typed API never allocates a wrapper
+	typedUp   *zap.Logger        // typed reporting the caller of the wrapper's typed methods
+	sugaredUp *zap.SugaredLogger // The sugared logger reporting the caller of the wrapper's methods
+	span      trace.Span         // Active span captured by Ctx, used for error recording
+}
+
+// wrap builds a Logger around s, carrying over the captured span
+func wrap(s *zap.SugaredLogger, span trace.Span) Logger {
+	typed := s.Desugar()
+	typedUp := typed.WithOptions(zap.AddCallerSkip(1))
+	return Logger{SugaredLogger: s, typed: typed, typedUp: typedUp, sugaredUp: typedUp.Sugar(), span: span}
+}
+
+var (
+	logger Logger
+	level  = zap.NewAtomicLevel()
+	once   sync.Once
+
+	fileOutputs []io.Closer // File outputs opened by NewLogger, see Close
+)
+
+// Config holds the logger configuration
+type Config struct {
+	Level       string         `env:"LOG_LEVEL,default=info" flag:"log.level"` // Log level (e.g., "debug", "info", "warn", "error", "fatal")
+	Encoding    string         `env:"LOG_ENCODING" flag:"log.encoding"`        // Output encoding (e.g., "json", "console", "msgpack", "otel", "logfmt", "gelf")
+	OutputPaths []string       `env:"LOG_OUTPUT" flag:"log.output"`            // List of output paths (e.g., "stdout", "stderr", "/path/to/file.log", "file:///var/log/app.log")
+	Rotation    RotationConfig // Size-based rotation for file outputs
+	Sinks       []SinkConfig   // Extra outputs with their own encoding (e.g., console to stderr alongside JSON)
+	BaggageKeys []string       // OpenTelemetry baggage keys logged as fields by Ctx (e.g., "tenant")
+
+	// RecordSpanErrors makes Error calls on a Ctx logger also record the
+	// error on the active span and mark the span status as Error
+	RecordSpanErrors bool
+
+	// WriteTimeout bounds each write and sync of an output, so a hung mount
+	// or stalled pipe drops entries for a while instead of blocking callers;
+	// 0 waits forever. Sinks without their own WriteTimeout use it too.
+	WriteTimeout time.Duration `env:"LOG_WRITE_TIMEOUT" flag:"log.write-timeout"`
+
+	// SharedOutput locks stdout, stderr and files around every write with an
+	// advisory lock, for containers where several processes share one
+	// stdout or log file and long lines would otherwise interleave. Only
+	// processes that also lock are kept out; leave rotation of a shared file
+	// to one of them.
+	SharedOutput bool `env:"LOG_SHARED_OUTPUT" flag:"log.shared-output"`
+
+	// Fsync sets how often file outputs are fsynced; the zero value leaves
+	// flushing to the OS. Sinks without their own Fsync use it too.
+	Fsync FsyncPolicy
+
+	// REDMetrics derives request rate, error and duration metrics from the
+	// request entries of the server and grpcserver packages, see WithREDMetrics
+	REDMetrics bool `env:"LOG_RED_METRICS" flag:"log.red-metrics"`
+
+	// ErrorRateInterval is how often ErrorRateLimited logs each key (default 1m)
+	ErrorRateInterval time.Duration
+
+	// DisableKubernetesFields stops the pod, namespace, node and container
+	// fields from being added when running in Kubernetes
+	DisableKubernetesFields bool
+
+	Schema     *Schema           // Optional field schema enforced on every entry
+	Truncation *TruncationConfig // Optional per-field and per-entry size limits
+	Redaction  *RedactionConfig  // Optional masking of sensitive keys, nested ones included
+	Framing    string            // Output framing: "newline" (default), "strict" or "json-seq"
+	DevMode    bool              // Pretty multi-line console output, relative callers, panicking DPanic
+	Notify     *NotifyConfig     // Optional webhook for Fatal and Panic entries
+	Email      *EmailConfig      // Optional SMTP alerts and error digests
+	CrashDump  *CrashDumpConfig  // Optional bundle of recent entries and goroutines written on Fatal
+
+	// FlightRecorder keeps this many recent entries, debug ones included, in
+	// memory for FlightRecorderHandler; 0 disables it
+	FlightRecorder int
+}
+
+// RotationConfig controls log file rotation; rotation is off when MaxSizeMB is 0
+type RotationConfig struct {
+	MaxSizeMB  int  // Max size in MB before rotation
+	MaxBackups int  // Max old log files to keep
+	MaxAgeDays int  // Max days to retain old logs
+	Compress   bool // Gzip rotated logs
+}
+
+// NewLogger creates a new logger instance based on the provided configuration
+func NewLogger(config Config) Logger {
+	once.Do(func() { // Ensure logger is initialized only once
+		lvl, err := zapcore.ParseLevel(config.Level)
+		if err != nil {
+			lvl = zapcore.InfoLevel // Default to info level
+		}
+		level.SetLevel(lvl)
+		baggageKeys = config.BaggageKeys
+		recordSpanErrors = config.RecordSpanErrors
+		if config.ErrorRateInterval > 0 {
+			errorRateInterval = config.ErrorRateInterval
+		}
+
+		build := buildinfo.Get()
+		base := []interface{}{"version", build.Version, "commit", build.ShortCommit()} // Base fields on every entry
+		if !config.DisableKubernetesFields {
+			base = append(base, KubernetesFields()...)
+		}
+		opts := []Option{
+			WithAtomicLevel(level),
+			WithEncoding(config.Encoding),
+			WithFraming(config.Framing),
+			WithFields(base...),
+			WithCapture(captures),
+		}
+		if config.DevMode {
+			opts = append(opts, WithDevMode())
+		}
+		outputs := outputOptions{rotation: config.Rotation, timeout: config.WriteTimeout, fsync: config.Fsync, shared: config.SharedOutput}
+		// With only Sinks configured, OutputPaths no longer defaults to stdout
+		if len(config.OutputPaths) > 0 || len(config.Sinks) == 0 {
+			opts = append(opts, WithSink(getLogWriter(config.OutputPaths, outputs)))
+		}
+		opts = append(opts, sinkOptions(config.Sinks, outputs)...)
+		if config.Schema != nil {
+			opts = append(opts, WithSchema(*config.Schema))
+		}
+		if config.Truncation != nil {
+			opts = append(opts, WithTruncation(*config.Truncation))
+		}
+		if config.Redaction != nil {
+			opts = append(opts, WithRedaction(*config.Redaction))
+		}
+		if config.Notify != nil {
+			opts = append(opts, WithNotifier(*config.Notify))
+		}
+		if config.Email != nil {
+			opts = append(opts, WithEmail(*config.Email))
+		}
+		if config.CrashDump != nil {
+			opts = append(opts, WithCrashDump(*config.CrashDump))
+		}
+		if config.REDMetrics {
+			opts = append(opts, WithREDMetrics())
+		}
+		if config.FlightRecorder > 0 {
+			recorder = NewFlightRecorder(config.FlightRecorder)
+			opts = append(opts, WithFlightRecorder(recorder))
+		}
+		logger = New(opts...)
+	})
+
+	return logger
+}
+
+// Close closes the file outputs of the shared logger, fsyncing them first
+// where an FsyncPolicy applies and stopping their interval fsyncs. Call it
+// last when shutting down: entries logged afterwards only reach the outputs
+// that are not files.
+func Close() error {
+	var err error
+	for _, c := range fileOutputs {
+		err = errors.Join(err, c.Close())
+	}
+	fileOutputs = nil
+	return err
+}
+
+// newEncoder returns the JSON encoder, a colored console encoder for local
+// development, the compact MessagePack encoder for binary sinks, or the
+// OpenTelemetry, logfmt or GELF encoder for collectors expecting those
+func newEncoder(encoding string) zapcore.Encoder {
+	if encoding == "otel" {
+		return NewOTelEncoder()
+	}
+	if encoding == "gelf" {
+		return NewGELFEncoder()
+	}
+	if encoding == "logfmt" {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.TimeKey = "ts"
+		return NewLogfmtEncoder(encoderConfig)
+	}
+	if encoding == "msgpack" {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.TimeKey = "timestamp"
+		encoderConfig.EncodeCaller = shortCallerEncoder
+		return NewMsgpackEncoder(encoderConfig)
+	}
+	if encoding == "console" {
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder // Add color to console output
+		encoderConfig.TimeKey = "timestamp"
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewConsoleEncoder(encoderConfig)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeCaller = shortCallerEncoder
+	return zapcore.NewJSONEncoder(encoderConfig)
+}
+
+// callerNames caches the "dir/file.go:line" of each call site, which
+// zapcore.ShortCallerEncoder formats into a new string per entry. The line
+// is part of the key because inlined frames share their caller's PC.
+var callerNames = struct {
+	sync.RWMutex
+	m map[callerSite]string
+}{m: make(map[callerSite]string)}
+
+type callerSite struct {
+	pc   uintptr
+	line int
+}
+
+// shortCallerEncoder is zapcore.ShortCallerEncoder without the allocation
+func shortCallerEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+	if !caller.Defined || caller.PC == 0 {
+		zapcore.ShortCallerEncoder(caller, enc)
+		return
+	}
+	site := callerSite{caller.PC, caller.Line}
+	callerNames.RLock()
+	name, ok := callerNames.m[site]
+	callerNames.RUnlock()
+	if !ok {
+		name = caller.TrimmedPath()
+		callerNames.Lock()
+		callerNames.m[site] = name
+		callerNames.Unlock()
+	}
+	enc.AppendString(name)
+}
+
+// outputOptions apply to every output path of a sink
+type outputOptions struct {
+	rotation RotationConfig
+	timeout  time.Duration
+	fsync    FsyncPolicy
+	shared   bool
+}
+
+// getLogWriter retrieves the log writer based on the specified output paths;
+// each path gets its own write timeout so one stuck output doesn't hold up the others
+func getLogWriter(outputPaths []string, out outputOptions) zapcore.WriteSyncer {
+	std := func(f *os.File) zapcore.WriteSyncer {
+		if out.shared {
+			return NewLockedWriter(f)
+		}
+		return f
+	}
+	if len(outputPaths) == 0 {
+		return NewTimeoutWriter("stdout", std(os.Stdout), out.timeout) // Default to standard output
+	}
+
+	// For multiple output paths or file paths, create a multi-writer
+	var writers []zapcore.WriteSyncer
+	add := func(name string, ws zapcore.WriteSyncer) {
+		writers = append(writers, NewTimeoutWriter(name, ws, out.timeout))
+	}
+	for _, path := range outputPaths {
+		switch path {
+		case "", "stdout":
+			add("stdout", std(os.Stdout))
+		case "stderr":
+			add("stderr", std(os.Stderr))
+		default:
+			path = strings.TrimPrefix(path, "file://")
+			// Try to create a file writer, fallback to stdout on failure
+			file, err := newFileWriter(path, out.rotation, out.shared)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to open log file %s: %v\n", path, err)
+				add("stdout", std(os.Stdout)) // Fallback to stdout
+				continue
+			}
+			ws := newFsyncWriter(path, file, file.Sync, out.fsync)
+			fileOutputs = append(fileOutputs, ws.(io.Closer)) // Both file writers close the file
+			add(path, ws)
+		}
+	}
+
+	return zap.CombineWriteSyncers(writers...)
+}
+
+// Sugar method to get the sugared logger
+func Sugar() *zap.SugaredLogger {
+	return logger.SugaredLogger
+}
+
+// SetLevel changes the level of the shared logger at runtime
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+// LevelHandler serves the current level on GET and changes it on PUT,
+// e.g. curl -X PUT -d '{"level":"debug"}' localhost:6060/loglevel
+func LevelHandler() http.Handler {
+	return level
+}
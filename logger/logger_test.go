@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var discard = zapcore.AddSync(io.Discard)
+
+func TestTypedCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithSink(zapcore.AddSync(&buf)))
+	l.Info("typed", zap.String("k", "v"))
+	l.Error("failed", zap.Error(io.EOF))
+	l.Errorf("failed %s", "formatted")
+	l.Errorw("failed sugared", "err", io.EOF)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Panic did not panic")
+			}
+		}()
+		l.Panic("panicked", zap.Int("n", 1))
+	}()
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ent map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &ent); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		if caller, _ := ent["caller"].(string); !strings.HasPrefix(caller, "logger/logger_test.go:") {
+			t.Errorf("%s: caller = %q, want the call site in logger_test.go", ent["msg"], caller)
+		}
+	}
+}
+
+// TestTypedAllocs pins what BenchmarkInfo and BenchmarkDebugDisabled measure:
+// the caller annotation and the field slice, nothing from the wrapping cores
+func TestTypedAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	l := New(WithSink(discard))
+	if n := testing.AllocsPerRun(100, func() {
+		l.Info("message consumed", zap.String("topic", "orders"), zap.Int64("offset", 42))
+	}); n > 2 {
+		t.Errorf("Info allocates %v times, want at most 2", n)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		l.Debug("message consumed", zap.String("topic", "orders"), zap.Int64("offset", 42))
+	}); n > 1 {
+		t.Errorf("disabled Debug allocates %v times, want at most 1", n)
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	l := New(WithSink(discard))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("message consumed", zap.String("topic", "orders"), zap.Int("partition", 3), zap.Int64("offset", int64(i)))
+	}
+}
+
+func BenchmarkInfoWithContext(b *testing.B) {
+	l := New(WithSink(discard)).With("topic", "orders", "partition", 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("message consumed")
+	}
+}
+
+func BenchmarkDebugDisabled(b *testing.B) {
+	l := New(WithSink(discard))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Debug("message consumed", zap.String("topic", "orders"), zap.Int64("offset", int64(i)))
+	}
+}
+
+func TestAlerterClosed(t *testing.T) {
+	a := NewAlerter(AlertConfig{URL: "http://127.0.0.1:0"})
+	hook := a.Hook()
+	a.Close()
+	a.Close()
+	fields := []zapcore.Field{zap.String(AlertField, AlertPage)}
+	if _, _, keep := hook(zapcore.Entry{Message: "after close"}, fields); !keep {
+		t.Error("entry dropped after Close")
+	}
+}
+
+func TestMailSubject(t *testing.T) {
+	got := mailSubject("[api] FATAL: boom\r\nBcc: attacker@example.com")
+	if strings.ContainsAny(got, "\r\n") {
+		t.Errorf("subject %q spans lines", got)
+	}
+	if got := mailSubject("[api] FATAL: échec"); !strings.HasPrefix(got, "=?utf-8?q?") {
+		t.Errorf("non-ASCII subject %q not encoded", got)
+	}
+}
+
+func TestSendMailTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() { // Accepts and never greets
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	send := smtpSender(EmailConfig{Addr: ln.Addr().String(), From: "a@example.com", To: []string{"b@example.com"}, Timeout: 100 * time.Millisecond})
+	start := time.Now()
+	if err := send("subject", "body"); err == nil {
+		t.Error("send to a silent server succeeded")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("send took %s despite the 100ms timeout", d)
+	}
+}
@@ -0,0 +1,92 @@
+// Package logrusbridge routes logrus output from third-party libraries into
+// the package logger, so it is encoded and shipped like every other entry.
+package logrusbridge
+
+import (
+	"io"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Hook is a logrus hook writing every entry to the logger
+type Hook struct {
+	core zapcore.Core
+}
+
+// NewHook creates a hook writing to l with source=logrus
+func NewHook(l logger.Logger) *Hook {
+	return &Hook{core: l.Desugar().Core().With([]zapcore.Field{zap.String("source", "logrus")})}
+}
+
+// Levels reports that the hook fires on all levels
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes the entry. Fatal and panic entries are logged at their zap
+// level but logrus itself decides whether to exit or panic afterwards.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	ent := zapcore.Entry{Level: zapLevel(e.Level), Time: e.Time, Message: e.Message, LoggerName: "logrus"}
+	if e.Caller != nil {
+		ent.Caller = zapcore.NewEntryCaller(e.Caller.PC, e.Caller.File, e.Caller.Line, true)
+	}
+
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, k := range keys {
+		if err, ok := e.Data[k].(error); ok && k == logrus.ErrorKey {
+			fields = append(fields, zap.Error(err))
+			continue
+		}
+		fields = append(fields, zap.Any(k, e.Data[k]))
+	}
+
+	// Checking on the core, not a zap.Logger, leaves exiting to logrus
+	if ce := h.core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func zapLevel(l logrus.Level) zapcore.Level {
+	switch l {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return zapcore.DebugLevel
+	case logrus.InfoLevel:
+		return zapcore.InfoLevel
+	case logrus.WarnLevel:
+		return zapcore.WarnLevel
+	case logrus.ErrorLevel:
+		return zapcore.ErrorLevel
+	case logrus.FatalLevel:
+		return zapcore.FatalLevel
+	default:
+		return zapcore.PanicLevel
+	}
+}
+
+// Redirect makes lr write only through the hook, at the verbosity l has enabled
+func Redirect(lr *logrus.Logger, l logger.Logger) {
+	lr.SetOutput(io.Discard)
+	lr.ReplaceHooks(logrus.LevelHooks{})
+	lr.AddHook(NewHook(l))
+	if l.Desugar().Core().Enabled(zapcore.DebugLevel) {
+		lr.SetLevel(logrus.DebugLevel)
+	} else {
+		lr.SetLevel(logrus.InfoLevel)
+	}
+}
+
+// RedirectStandard redirects logrus.StandardLogger, which most libraries log to
+func RedirectStandard(l logger.Logger) {
+	Redirect(logrus.StandardLogger(), l)
+}
@@ -0,0 +1,22 @@
+// Package logtest provides an in-memory logger for asserting on log output in tests
+package logtest
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// New returns a logger that records every entry at or above level, and the
+// recorded entries for assertions, e.g.
+//
+//	log, logs := logtest.New(zapcore.DebugLevel)
+//	svc := NewService(log)
+//	...
+//	if logs.FilterMessage("order created").Len() != 1 { t.Fatal(...) }
+func New(level zapcore.Level) (logger.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	return logger.FromZap(zap.New(core)), logs
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var msgpackPool = buffer.NewPool()
+
+// msgpackEncoder writes each entry as one MessagePack map. It is typically
+// 30-50% smaller than JSON for the same entry, which matters for high-volume
+// binary sinks such as Kafka or Fluent Forward. MessagePack is self-delimiting,
+// so entries are concatenated without separators.
+type msgpackEncoder struct {
+	*zapcore.MapObjectEncoder // Fields added through With
+	cfg                       zapcore.EncoderConfig
+}
+
+// NewMsgpackEncoder creates a MessagePack encoder honoring the key names of cfg
+func NewMsgpackEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &msgpackEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), cfg: cfg}
+}
+
+func (e *msgpackEncoder) Clone() zapcore.Encoder {
+	clone := &msgpackEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), cfg: e.cfg}
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+func (e *msgpackEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	m := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		m.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+	for k, v := range m.Fields {
+		m.Fields[k] = encodable(v, false)
+	}
+
+	if e.cfg.TimeKey != "" {
+		m.Fields[e.cfg.TimeKey] = ent.Time.UTC()
+	}
+	if e.cfg.LevelKey != "" {
+		m.Fields[e.cfg.LevelKey] = ent.Level.String()
+	}
+	if e.cfg.NameKey != "" && ent.LoggerName != "" {
+		m.Fields[e.cfg.NameKey] = ent.LoggerName
+	}
+	if e.cfg.CallerKey != "" && ent.Caller.Defined {
+		m.Fields[e.cfg.CallerKey] = ent.Caller.TrimmedPath()
+	}
+	if e.cfg.MessageKey != "" {
+		m.Fields[e.cfg.MessageKey] = ent.Message
+	}
+	if e.cfg.StacktraceKey != "" && ent.Stack != "" {
+		m.Fields[e.cfg.StacktraceKey] = ent.Stack
+	}
+
+	buf := msgpackPool.Get()
+	enc := msgpack.NewEncoder(buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(m.Fields); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NotifyConfig configures a sink that posts crash-level entries to a Slack
+// incoming webhook or a generic HTTP endpoint
+type NotifyConfig struct {
+	URL       string        // Webhook endpoint
+	Format    string        // "slack" (default) or "generic" JSON
+	Service   string        // Prefixed to Slack messages and sent as "service"
+	Errors    bool          // Also post Error entries, not only DPanic, Panic and Fatal
+	RateLimit int           // Posts per minute at most (default 5); extras are counted and reported
+	Timeout   time.Duration // Per post (default 5s)
+}
+
+// Notification formats
+const (
+	NotifyFormatSlack   = "slack"
+	NotifyFormatGeneric = "generic"
+)
+
+// namedCore is an extra core added to the tee, named for panic reports
+type namedCore struct {
+	name string
+	core zapcore.Core
+}
+
+// WithNotifier adds a sink posting Fatal and Panic entries to a webhook.
+// Those are posted synchronously, since the process exits right after;
+// Error entries, when enabled, are posted in the background.
+func WithNotifier(cfg NotifyConfig) Option {
+	if cfg.Format == "" {
+		cfg.Format = NotifyFormatSlack
+	}
+	if cfg.RateLimit <= 0 {
+		cfg.RateLimit = 5
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	min := zapcore.DPanicLevel
+	if cfg.Errors {
+		min = zapcore.ErrorLevel
+	}
+	n := &notifier{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+	core := &notifyCore{LevelEnabler: min, n: n, enc: zapcore.NewMapObjectEncoder()}
+	return func(o *options) { o.extraCores = append(o.extraCores, namedCore{"notifier", core}) }
+}
+
+// notifier is shared by a notify core and its With children
+type notifier struct {
+	cfg     NotifyConfig
+	client  *http.Client
+	pending sync.WaitGroup
+
+	mu     sync.Mutex
+	errors throttle
+	crash  throttle // Separate budget so an error storm can't hide the crash
+}
+
+// throttle counts posts in the current one minute window
+type throttle struct {
+	windowStart time.Time
+	sent        int
+	suppressed  int
+}
+
+// allow applies the rate limit, returning how many posts were suppressed
+// since the last one that went out
+func (n *notifier) allow(ent zapcore.Entry) (bool, int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	t := &n.errors
+	if ent.Level >= zapcore.DPanicLevel {
+		t = &n.crash
+	}
+	if ent.Time.Sub(t.windowStart) >= time.Minute {
+		t.windowStart, t.sent = ent.Time, 0
+	}
+	if t.sent >= n.cfg.RateLimit {
+		t.suppressed++
+		return false, 0
+	}
+	t.sent++
+	suppressed := t.suppressed
+	t.suppressed = 0
+	return true, suppressed
+}
+
+type notifyCore struct {
+	zapcore.LevelEnabler
+	n   *notifier
+	enc *zapcore.MapObjectEncoder // Fields added with With
+}
+
+func (c *notifyCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.enc.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &notifyCore{LevelEnabler: c.LevelEnabler, n: c.n, enc: enc}
+}
+
+func (c *notifyCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *notifyCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ok, suppressed := c.n.allow(ent)
+	if !ok {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.enc.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	body := c.n.payload(ent, enc.Fields, suppressed)
+
+	if ent.Level >= zapcore.DPanicLevel {
+		return c.n.post(body) // The process may be about to exit
+	}
+	c.n.pending.Add(1)
+	go func() {
+		defer c.n.pending.Done()
+		if err := c.n.post(body); err != nil {
+			reportNotifyError(err, ent)
+		}
+	}()
+	return nil
+}
+
+// Sync waits for background posts
+func (c *notifyCore) Sync() error {
+	c.n.pending.Wait()
+	return nil
+}
+
+func (n *notifier) payload(ent zapcore.Entry, details map[string]interface{}, suppressed int) interface{} {
+	if n.cfg.Format == NotifyFormatSlack {
+		text := fmt.Sprintf(":rotating_light: *%s* %s: %s", ent.Level.CapitalString(), n.cfg.Service, ent.Message)
+		if ent.Caller.Defined {
+			text += "\n`" + ent.Caller.TrimmedPath() + "`"
+		}
+		if len(details) > 0 {
+			if b, err := json.Marshal(details); err == nil {
+				text += "\n```" + string(b) + "```"
+			}
+		}
+		if suppressed > 0 {
+			text += fmt.Sprintf("\n_%d earlier notifications suppressed by rate limit_", suppressed)
+		}
+		return map[string]interface{}{"text": text}
+	}
+	return map[string]interface{}{
+		"service":    n.cfg.Service,
+		"level":      ent.Level.String(),
+		"message":    ent.Message,
+		"timestamp":  ent.Time.UTC().Format(time.RFC3339Nano),
+		"logger":     ent.LoggerName,
+		"caller":     ent.Caller.TrimmedPath(),
+		"stacktrace": ent.Stack,
+		"fields":     details,
+		"suppressed": suppressed,
+	}
+}
+
+func (n *notifier) post(v interface{}) error {
+	return postJSON(n.client, n.cfg.URL, v)
+}
+
+// postJSON posts v as JSON, failing on non-2xx responses
+func postJSON(client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.Timeout+time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func reportNotifyError(err error, ent zapcore.Entry) {
+	_ = fallback.Write(zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Now(),
+		Message: "failed to post log notification",
+	}, []zapcore.Field{zap.Error(err), zap.String("entry_message", ent.Message)})
+}
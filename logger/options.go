@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures a logger built with New
+type Option func(*options)
+
+type options struct {
+	level      zap.AtomicLevel
+	encoding   string
+	sinks      []zapcore.WriteSyncer
+	fields     []interface{}
+	sampling   *samplingOptions
+	schema     *Schema
+	truncation *TruncationConfig
+	redaction  *RedactionConfig
+	framing    string
+
+	encodedSinks []encodedSink
+	devMode      bool
+	hooks        []Hook
+	flags        *flagOptions
+	extraCores   []namedCore
+	sampleExempt []SamplingExemption
+	red          bool
+}
+
+type samplingOptions struct {
+	tick       time.Duration
+	first      int
+	thereafter int
+}
+
+// New creates an independent logger from options. Unlike NewLogger it does
+// not touch the shared logger, which makes it the constructor for libraries
+// and tests. Without options it logs JSON at info level to stdout.
+func New(opts ...Option) Logger {
+	o := options{level: zap.NewAtomicLevelAt(zapcore.InfoLevel)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.build()
+}
+
+// WithLevel sets the minimum enabled level
+func WithLevel(l zapcore.Level) Option {
+	return func(o *options) { o.level = zap.NewAtomicLevelAt(l) }
+}
+
+// WithAtomicLevel shares a level that can be changed at runtime
+func WithAtomicLevel(l zap.AtomicLevel) Option {
+	return func(o *options) { o.level = l }
+}
+
+// WithEncoding selects "json" (default), "console", "msgpack", "otel", "logfmt" or "gelf"
+func WithEncoding(encoding string) Option {
+	return func(o *options) { o.encoding = encoding }
+}
+
+// WithSink adds an output; entries are written to every sink
+func WithSink(ws zapcore.WriteSyncer) Option {
+	return func(o *options) { o.sinks = append(o.sinks, ws) }
+}
+
+// WithFields adds key-value pairs to every entry
+func WithFields(keysAndValues ...interface{}) Option {
+	return func(o *options) { o.fields = append(o.fields, keysAndValues...) }
+}
+
+// WithSampling logs the first entries with the same level and message each
+// second, then every thereafter-th one; see WithSamplingExemptions for
+// entries that must always be kept
+func WithSampling(first, thereafter int) Option {
+	return func(o *options) {
+		o.sampling = &samplingOptions{tick: time.Second, first: first, thereafter: thereafter}
+	}
+}
+
+func (o *options) build() Logger {
+	// With flags the level is enforced by the flag core, which may lower it per tenant
+	var level zapcore.LevelEnabler = o.level
+	if o.flags != nil {
+		level = zapcore.DebugLevel
+	}
+
+	var cores []zapcore.Core
+	if len(o.sinks) > 0 || len(o.encodedSinks) == 0 {
+		var ws zapcore.WriteSyncer = os.Stdout
+		if len(o.sinks) > 0 {
+			ws = zap.CombineWriteSyncers(o.sinks...)
+		}
+		if o.devMode {
+			cores = append(cores, isolate(zapcore.NewCore(newDevEncoder(), ws, level), "sink"))
+		} else {
+			cores = append(cores, isolate(zapcore.NewCore(newEncoder(o.encoding), o.frame(o.encoding, ws), level), "sink"))
+		}
+	}
+	for i, s := range o.encodedSinks {
+		core := zapcore.NewCore(newEncoder(s.encoding), o.frame(s.encoding, s.ws), sinkLevel(level, s.min))
+		cores = append(cores, isolate(core, fmt.Sprintf("%s sink %d", s.encoding, i)))
+	}
+
+	for _, c := range o.extraCores {
+		cores = append(cores, isolate(c.core, c.name))
+	}
+
+	core := zapcore.NewTee(cores...)
+	core = newResourceCore(core) // Innermost, so the notifier and hooks see the fields too
+	if o.schema != nil {
+		core = newSchemaCore(core, o.schema)
+	}
+	if o.truncation != nil {
+		core = newTruncateCore(core, *o.truncation)
+	}
+	if o.redaction != nil {
+		core = newRedactCore(core, *o.redaction) // Inside the hooks, so the fields they add are masked too
+	}
+	if len(o.hooks) > 0 {
+		core = newHookCore(core, o.hooks)
+	}
+	if o.flags != nil {
+		core = newFlagCore(core, o.flags, o.level, o.sampleExempt)
+	}
+	if s := o.sampling; s != nil {
+		core = newSampleCore(core, zapcore.NewSamplerWithOptions(core, s.tick, s.first, s.thereafter), o.sampleExempt)
+	}
+	core = newErrorCodeCore(core) // Outside schema checks, hooks and sampling exemptions, so they see the codes
+	if o.red {
+		core = newREDCore(core) // Outermost, so request entries are counted before anything drops them
+	}
+
+	zapOpts := []zap.Option{zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)} // Add caller information
+	if o.devMode {
+		zapOpts = append(zapOpts, zap.Development())
+	}
+	l := zap.New(core, zapOpts...)
+	sugar := l.Sugar()
+	if len(o.fields) > 0 {
+		sugar = sugar.With(o.fields...)
+	}
+	return wrap(sugar, nil)
+}
+
+// frame applies the framing mode to JSON outputs; console, msgpack and logfmt output is left alone
+func (o *options) frame(encoding string, ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+	if encoding == "console" || encoding == "msgpack" || encoding == "logfmt" {
+		return ws
+	}
+	return newFramedWriter(ws, o.framing)
+}
+
+// writeThrough writes an entry modified by a wrapping core to the wrapped
+// core, going through Check so that level filtering of tee'd sinks still applies
+func writeThrough(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) error {
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var otelPool = buffer.NewPool()
+
+// otelEncoder writes one JSON object per entry in the shape the OpenTelemetry
+// Collector filelog receiver maps without extra operators: timestamp,
+// severity_text, severity_number, body, trace_id, span_id and a flat
+// attributes object whose nested keys are joined with dots.
+type otelEncoder struct {
+	*zapcore.MapObjectEncoder // Fields added through With
+}
+
+// NewOTelEncoder creates an encoder for the OpenTelemetry Collector file log format
+func NewOTelEncoder() zapcore.Encoder {
+	return &otelEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder()}
+}
+
+type otelRecord struct {
+	Timestamp      string                 `json:"timestamp"`
+	SeverityText   string                 `json:"severity_text"`
+	SeverityNumber int                    `json:"severity_number"`
+	Body           string                 `json:"body"`
+	TraceID        string                 `json:"trace_id,omitempty"`
+	SpanID         string                 `json:"span_id,omitempty"`
+	Scope          string                 `json:"scope,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func (e *otelEncoder) Clone() zapcore.Encoder {
+	clone := &otelEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder()}
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+func (e *otelEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	m := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		m.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+
+	rec := otelRecord{
+		Timestamp:      ent.Time.UTC().Format(time.RFC3339Nano),
+		SeverityText:   ent.Level.CapitalString(),
+		SeverityNumber: severityNumber(ent.Level),
+		Body:           ent.Message,
+		Scope:          ent.LoggerName,
+		Attributes:     make(map[string]interface{}, len(m.Fields)),
+	}
+	// Correlation fields added by Ctx become top-level record fields
+	if id, ok := m.Fields["trace_id"].(string); ok {
+		rec.TraceID = id
+		delete(m.Fields, "trace_id")
+	}
+	if id, ok := m.Fields["span_id"].(string); ok {
+		rec.SpanID = id
+		delete(m.Fields, "span_id")
+	}
+	flatten(rec.Attributes, "", m.Fields)
+	if ent.Caller.Defined {
+		rec.Attributes["code.filepath"] = ent.Caller.File
+		rec.Attributes["code.lineno"] = ent.Caller.Line
+		if ent.Caller.Function != "" {
+			rec.Attributes["code.function"] = ent.Caller.Function
+		}
+	}
+	if ent.Stack != "" {
+		rec.Attributes["exception.stacktrace"] = ent.Stack
+	}
+
+	buf := otelPool.Get()
+	if err := json.NewEncoder(buf).Encode(rec); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// flatten copies src into dst, joining nested map keys with dots
+func flatten(dst map[string]interface{}, prefix string, src map[string]interface{}) {
+	for k, v := range src {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			flatten(dst, k, nested)
+			continue
+		}
+		dst[k] = encodable(v, true)
+	}
+}
+
+// encodable replaces the values encoding/json or msgpack reject with strings
+// as zap's JSON encoder writes them: complex numbers and, when nonFinite is
+// set, NaN and infinite floats
+func encodable(v interface{}, nonFinite bool) interface{} {
+	switch x := v.(type) {
+	case float64:
+		if nonFinite && (math.IsNaN(x) || math.IsInf(x, 0)) {
+			return strconv.FormatFloat(x, 'f', -1, 64)
+		}
+	case float32:
+		if f := float64(x); nonFinite && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return strconv.FormatFloat(f, 'f', -1, 32)
+		}
+	case complex128, complex64:
+		return fmt.Sprint(x)
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = encodable(e, nonFinite)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			out[k] = encodable(e, nonFinite)
+		}
+		return out
+	}
+	return v
+}
+
+// severityNumber maps zap levels onto the OpenTelemetry severity number ranges
+func severityNumber(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 21
+	case zapcore.FatalLevel:
+		return 22
+	}
+	return 0
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package logger
+
+// raceEnabled is set when testing with -race, which adds allocations
+const raceEnabled = true
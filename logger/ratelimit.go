@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errorRateInterval is set from Config.ErrorRateInterval
+var errorRateInterval = time.Minute
+
+// errorLimits holds the state of every key passed to ErrorRateLimited
+var errorLimits sync.Map // key -> *errorLimit
+
+type errorLimit struct {
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int
+}
+
+// allowError reports whether an entry for key may be logged now and how many
+// were suppressed since the last one
+func allowError(key string) (int, bool) {
+	v, ok := errorLimits.Load(key)
+	if !ok {
+		v, _ = errorLimits.LoadOrStore(key, &errorLimit{})
+	}
+	l := v.(*errorLimit)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if !l.last.IsZero() && now.Sub(l.last) < errorRateInterval {
+		l.suppressed++
+		return 0, false
+	}
+	n := l.suppressed
+	l.last, l.suppressed = now, 0
+	return n, true
+}
+
+// ErrorRateLimited logs at error level, like Errorw, at most once per
+// Config.ErrorRateInterval (default one minute) for each key; the entries
+// dropped in between are counted and reported as suppressed_count on the
+// next one logged. Use it in tight loops, e.g. a consumer whose broker is
+// down, with a fixed key per call site: every key is remembered for the life
+// of the process.
+func (l Logger) ErrorRateLimited(key, msg string, keysAndValues ...interface{}) {
+	suppressed, ok := allowError(key)
+	if !ok {
+		return
+	}
+	if suppressed > 0 {
+		keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)], "suppressed_count", suppressed)
+	}
+	l.recordError(msg, keysAndValues)
+	l.skipped().Errorw(msg, keysAndValues...)
+}
+
+// ErrorRateLimited is Logger.ErrorRateLimited on the shared logger
+func ErrorRateLimited(key, msg string, keysAndValues ...interface{}) {
+	if logger.SugaredLogger == nil {
+		return
+	}
+	wrap(logger.SugaredLogger.WithOptions(zap.AddCallerSkip(1)), nil).ErrorRateLimited(key, msg, keysAndValues...)
+}
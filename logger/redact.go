@@ -0,0 +1,349 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactionConfig masks sensitive values wherever their key appears: as a
+// field, or as a key inside objects, maps, slices and structs passed as
+// fields, so a password inside a request DTO is caught too. Structs are
+// searched by their JSON names, the same names the encoder writes; values of
+// types that cannot hold a sensitive key are passed on untouched. Other
+// values nested deeper than MaxDepth are masked whole since they were not
+// searched.
+//
+// Keys in HashKeys are replaced by an HMAC-SHA256 of their value under Salt
+// instead, so entries about the same email or user ID can still be joined in
+// analytics without the raw value. Keep Salt secret and stable: anyone with
+// it can test guesses, and changing it breaks joins with older logs.
+type RedactionConfig struct {
+	Keys     []string // Keys to mask, matched case-insensitively
+	HashKeys []string // Keys to replace by a salted hash, matched the same way
+	Salt     string   // HMAC key for HashKeys; required with them
+	MaxDepth int      // Nesting levels searched below a field (default 8)
+	Mask     string   // Replacement value (default "[REDACTED]")
+}
+
+// WithRedaction masks the keys in cfg on every entry once hooks have run, so
+// fields the hooks add are masked too; hooks themselves see the raw values
+func WithRedaction(cfg RedactionConfig) Option {
+	return func(o *options) { o.redaction = &cfg }
+}
+
+// redactor rewrites fields holding sensitive keys, copying only what it changes
+type redactor struct {
+	keys     map[string]bool // Lowercased key to whether it is hashed rather than masked
+	salt     []byte
+	maxDepth int
+	mask     string
+
+	types sync.Map // reflect.Type to whether its values may hold a sensitive key
+}
+
+func newRedactor(cfg RedactionConfig) *redactor {
+	r := &redactor{keys: make(map[string]bool), salt: []byte(cfg.Salt), maxDepth: cfg.MaxDepth, mask: cfg.Mask}
+	for _, k := range cfg.Keys {
+		r.keys[strings.ToLower(k)] = false
+	}
+	for _, k := range cfg.HashKeys {
+		r.keys[strings.ToLower(k)] = true
+	}
+	if r.maxDepth <= 0 {
+		r.maxDepth = 8
+	}
+	if r.mask == "" {
+		r.mask = "[REDACTED]"
+	}
+	return r
+}
+
+func (r *redactor) sensitive(key string) bool {
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+// replace returns what is logged in place of the value of a sensitive key
+func (r *redactor) replace(key string, v interface{}) interface{} {
+	if !r.keys[strings.ToLower(key)] || v == nil {
+		return r.mask
+	}
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case []byte:
+		s = string(x)
+	case float64: // Numbers inside structs arrive this way from the JSON walk; format them like integers
+		s = strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		if !composite(v) {
+			s = fmt.Sprint(v)
+		} else if b, err := json.Marshal(v); err == nil {
+			s = string(b)
+		} else {
+			return r.mask
+		}
+	}
+	return HashValue(r.salt, s)
+}
+
+// HashValue returns the value logged in place of s for a HashKeys key, so
+// tools can find or produce the same hashes
+func HashValue(salt []byte, s string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	if len(r.keys) == 0 {
+		return fields
+	}
+	var out []zapcore.Field
+	for i, f := range fields {
+		rf, changed := r.field(f)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = rf
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	if r.sensitive(f.Key) && f.Type != zapcore.InlineMarshalerType {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		return zap.Any(f.Key, r.replace(f.Key, enc.Fields[f.Key])), true
+	}
+	switch f.Type {
+	case zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		if err := f.Interface.(zapcore.ObjectMarshaler).MarshalLogObject(enc); err != nil {
+			return f, false // Left to the encoder to report
+		}
+		v, changed := r.value(enc.Fields, 1)
+		if !changed {
+			return f, false
+		}
+		if f.Type == zapcore.InlineMarshalerType {
+			return zap.Inline(redactedObject(v.(map[string]interface{}))), true
+		}
+		return zap.Object(f.Key, redactedObject(v.(map[string]interface{}))), true
+	case zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		if err := enc.AddArray("v", f.Interface.(zapcore.ArrayMarshaler)); err != nil {
+			return f, false
+		}
+		if v, changed := r.value(enc.Fields["v"], 1); changed {
+			return zap.Any(f.Key, v), true
+		}
+	case zapcore.ReflectType:
+		if v, changed := r.value(f.Interface, 1); changed {
+			return zap.Any(f.Key, v), true
+		}
+	}
+	return f, false
+}
+
+// value returns v with sensitive keys masked at any depth; composite values
+// other than generic maps and slices are converted through their JSON form
+func (r *redactor) value(v interface{}, depth int) (interface{}, bool) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if depth > r.maxDepth {
+			return r.mask, true
+		}
+		var out map[string]interface{}
+		for k, e := range x {
+			var re interface{}
+			changed := true
+			if r.sensitive(k) {
+				re = r.replace(k, e)
+			} else {
+				re, changed = r.value(e, depth+1)
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]interface{}, len(x))
+				for k, e := range x {
+					out[k] = e
+				}
+			}
+			out[k] = re
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case []interface{}:
+		if depth > r.maxDepth {
+			return r.mask, true
+		}
+		var out []interface{}
+		for i, e := range x {
+			re, changed := r.value(e, depth+1)
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = append([]interface{}(nil), x...)
+			}
+			out[i] = re
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	}
+
+	if !composite(v) || !r.mayHold(reflect.TypeOf(v)) {
+		return v, false
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v, false
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return v, false
+	}
+	if rv, changed := r.value(generic, depth); changed {
+		return rv, true
+	}
+	return v, false // Keep the original so unchanged values encode as before
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// mayHold reports whether values of t may encode a sensitive key, so only
+// those take the JSON round trip. Interfaces, maps and custom marshalers
+// may hold any key, except those that are also text marshalers, such as
+// time.Time, which encode as strings; structs are judged by their field
+// names and types.
+func (r *redactor) mayHold(t reflect.Type) bool {
+	if held, ok := r.types.Load(t); ok {
+		return held.(bool)
+	}
+	held := r.typeHolds(t, make(map[reflect.Type]bool))
+	r.types.Store(t, held)
+	return held
+}
+
+// typeHolds is mayHold without the cache; seen breaks cycles of recursive types
+func (r *redactor) typeHolds(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false // Whatever it holds is found where it was first reached
+	}
+	seen[t] = true
+	if t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+		return false
+	}
+	if t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Interface, reflect.Map:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return r.typeHolds(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+			if name == "" && !f.Anonymous {
+				name = f.Name
+			}
+			if (name != "" && r.sensitive(name)) || r.typeHolds(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// composite reports whether v may hold keys: a struct, map or slice other than bytes
+func composite(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// redactedObject encodes a masked object with its keys in a stable order
+type redactedObject map[string]interface{}
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := enc.AddReflected(k, o[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+func newRedactCore(core zapcore.Core, cfg RedactionConfig) zapcore.Core {
+	return &redactCore{Core: core, r: newRedactor(cfg)}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeThrough(c.Core, ent, c.r.fields(fields))
+}
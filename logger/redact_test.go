@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type login struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+type order struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Lines   []struct {
+		SKU string `json:"sku"`
+	} `json:"lines"`
+}
+
+type tree struct {
+	Name     string  `json:"name"`
+	Children []*tree `json:"children"`
+}
+
+func TestRedaction(t *testing.T) {
+	cfg := RedactionConfig{Keys: []string{"password", "token"}, HashKeys: []string{"email"}, Salt: "s3cret", MaxDepth: 3}
+	deep := map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": map[string]interface{}{"d": 1}}}}
+	tests := []struct {
+		name  string
+		field zap.Field
+		want  interface{} // The field's value as decoded from the JSON output
+	}{
+		{"masked", zap.String("password", "hunter2"), "[REDACTED]"},
+		{"case-insensitive", zap.String("Token", "abc"), "[REDACTED]"},
+		{"hashed", zap.String("email", "a@example.com"), HashValue([]byte("s3cret"), "a@example.com")},
+		{"nested map", zap.Any("req", map[string]interface{}{"user": "ann", "password": "hunter2"}), map[string]interface{}{"user": "ann", "password": "[REDACTED]"}},
+		{"struct by JSON name", zap.Any("req", login{User: "ann", Password: "hunter2"}), map[string]interface{}{"user": "ann", "password": "[REDACTED]"}},
+		{"slice of maps", zap.Any("reqs", []interface{}{map[string]interface{}{"token": "t"}}), []interface{}{map[string]interface{}{"token": "[REDACTED]"}}},
+		{"nothing sensitive", zap.Any("order", order{ID: "o1"}), map[string]interface{}{"id": "o1", "created": "0001-01-01T00:00:00Z", "lines": nil}},
+		{"too deep", zap.Any("deep", deep), map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "[REDACTED]"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(WithSink(zapcore.AddSync(&buf)), WithRedaction(cfg)).Info("request", tt.field)
+			var ent map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &ent); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			if got := ent[tt.field.Key]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.field.Key, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRedactionAfterHooks checks that fields added by hooks and With are masked too
+func TestRedactionAfterHooks(t *testing.T) {
+	var buf bytes.Buffer
+	var seen string
+	hook := func(ent zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field, bool) {
+		for _, f := range fields {
+			if f.Key == "password" {
+				seen = f.String
+			}
+		}
+		return ent, append(fields, zap.String("token", "from-hook")), true
+	}
+	l := New(WithSink(zapcore.AddSync(&buf)), WithHooks(hook), WithRedaction(RedactionConfig{Keys: []string{"password", "token"}}))
+	l.With("password", "from-with").Info("login", zap.String("password", "from-call"))
+
+	if seen != "from-call" {
+		t.Errorf("hook saw password %q, want the raw value", seen)
+	}
+	for _, raw := range []string{"from-hook", "from-with", "from-call"} {
+		if bytes.Contains(buf.Bytes(), []byte(raw)) {
+			t.Errorf("%q in output: %s", raw, buf.String())
+		}
+	}
+}
+
+func TestRedactorMayHold(t *testing.T) {
+	r := newRedactor(RedactionConfig{Keys: []string{"password"}})
+	tests := []struct {
+		value interface{}
+		want  bool
+	}{
+		{login{}, true},
+		{&login{}, true},
+		{[]login{}, true},
+		{order{}, false},
+		{tree{}, false}, // Recursive
+		{time.Time{}, false},
+		{map[string]string{}, true}, // Any key
+		{struct{ Any interface{} }{}, true},
+		{struct {
+			Secret string `json:"-"`
+			hidden string
+		}{}, false},
+		{struct{ Password string }{}, true}, // Untagged fields go by their Go name
+		{struct{ login }{}, true},           // Embedded fields are promoted
+	}
+	for _, tt := range tests {
+		if got := r.mayHold(reflect.TypeOf(tt.value)); got != tt.want {
+			t.Errorf("mayHold(%T) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/metrics"
+)
+
+// RED metrics derived from request log lines, registered on first use
+var (
+	redMetricsOnce sync.Once
+
+	redRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "red",
+		Name:      "requests_total",
+		Help:      "Requests seen in request log lines, by kind (http or grpc), operation and status.",
+	}, []string{"kind", "operation", "status"})
+
+	redErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "red",
+		Name:      "errors_total",
+		Help:      "Requests whose log line was written at error level or above, by kind and operation.",
+	}, []string{"kind", "operation"})
+
+	redDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "red",
+		Name:      "request_duration_seconds",
+		Help:      "Request durations from request log lines, by kind and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"kind", "operation"})
+)
+
+// unmatchedOperation labels requests whose route is unknown, e.g. those no
+// ServeMux pattern matched; raw paths are never used as labels, since every
+// distinct URL would add series
+const unmatchedOperation = "unmatched"
+
+// redLine describes the fields of one kind of request log line
+type redLine struct {
+	kind      string
+	operation []string // Fields joined with a space, e.g. "GET /orders/{id}"
+	status    string
+	duration  string // Float milliseconds
+}
+
+// redLines are the request entries of server.AccessLog and the grpcserver
+// logging interceptors, keyed by message
+var redLines = map[string]redLine{
+	"http.request": {kind: "http", operation: []string{"http.method", "http.route"}, status: "http.status_code", duration: "http.duration_ms"},
+	"grpc request": {kind: "grpc", operation: []string{"grpc.method"}, status: "grpc.code", duration: "grpc.duration_ms"},
+}
+
+// WithREDMetrics derives rate, error and duration metrics per operation
+// from the request entries that the server and grpcserver packages write,
+// canonical log lines included, and exports them on the shared registry as
+// app_red_*. It gives services without a tracing backend the span metrics
+// one would compute. Request entries are counted whatever the level and
+// sampling, so info entries are checked even when the level is above info;
+// requests logged at error level count as errors.
+func WithREDMetrics() Option {
+	redMetricsOnce.Do(func() {
+		metrics.MustRegister(redRequestsTotal, redErrorsTotal, redDuration)
+	})
+	return func(o *options) { o.red = true }
+}
+
+// redCore observes request entries before the level, sampling and the
+// other wrappers get to drop them
+type redCore struct {
+	zapcore.Core
+}
+
+func newREDCore(core zapcore.Core) zapcore.Core {
+	return &redCore{Core: core}
+}
+
+func (c *redCore) Enabled(l zapcore.Level) bool {
+	return l >= zapcore.InfoLevel || c.Core.Enabled(l)
+}
+
+func (c *redCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redCore{Core: c.Core.With(fields)}
+}
+
+func (c *redCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if _, ok := redLines[ent.Message]; ok && ent.Level >= zapcore.InfoLevel {
+		return ce.AddCore(ent, c)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *redCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if line, ok := redLines[ent.Message]; ok {
+		line.observe(ent, fields)
+	}
+	return writeThrough(c.Core, ent, fields)
+}
+
+func (line redLine) observe(ent zapcore.Entry, fields []zapcore.Field) {
+	var op, status string
+	var duration time.Duration
+	for _, key := range line.operation {
+		for _, f := range fields {
+			if f.Key != key {
+				continue
+			}
+			v := fieldText(f)
+			if v == "" {
+				v = unmatchedOperation
+			}
+			// ServeMux patterns may carry the method already, as in "GET /orders/{id}"
+			if op == "" || strings.HasPrefix(v, op+" ") {
+				op = v
+			} else {
+				op += " " + v
+			}
+		}
+	}
+	for _, f := range fields {
+		switch f.Key {
+		case line.status:
+			status = fieldText(f)
+		case line.duration:
+			if f.Type == zapcore.Float64Type {
+				ms := math.Float64frombits(uint64(f.Integer))
+				duration = time.Duration(ms * float64(time.Millisecond))
+			}
+		}
+	}
+	if op == "" {
+		return // Not a request entry after all
+	}
+	redRequestsTotal.WithLabelValues(line.kind, op, status).Inc()
+	if ent.Level >= zapcore.ErrorLevel {
+		redErrorsTotal.WithLabelValues(line.kind, op).Inc()
+	}
+	redDuration.WithLabelValues(line.kind, op).Observe(duration.Seconds())
+}
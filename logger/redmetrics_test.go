@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestREDMetrics(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     zapcore.Level
+		log       func(l Logger)
+		kind      string
+		operation string
+		status    string
+		errors    float64
+		seconds   float64 // Sum of observed durations
+	}{
+		{
+			name: "http",
+			log: func(l Logger) {
+				l.Info("http.request", zap.String("http.method", "GET"), zap.String("http.route", "/orders/{id}"), zap.Int("http.status_code", 200), zap.Float64("http.duration_ms", 250))
+			},
+			kind: "http", operation: "GET /orders/{id}", status: "200", seconds: 0.25,
+		},
+		{
+			name: "pattern with method",
+			log: func(l Logger) {
+				l.Info("http.request", zap.String("http.method", "POST"), zap.String("http.route", "POST /carts/{id}"), zap.Int("http.status_code", 201), zap.Float64("http.duration_ms", 10))
+			},
+			kind: "http", operation: "POST /carts/{id}", status: "201", seconds: 0.01,
+		},
+		{
+			name: "unmatched route",
+			log: func(l Logger) {
+				l.Info("http.request", zap.String("http.method", "PUT"), zap.String("http.route", ""), zap.Int("http.status_code", 404), zap.Float64("http.duration_ms", 1))
+			},
+			kind: "http", operation: "PUT unmatched", status: "404", seconds: 0.001,
+		},
+		{
+			name: "error level",
+			log: func(l Logger) {
+				l.Error("http.request", zap.String("http.method", "DELETE"), zap.String("http.route", "/orders/{id}"), zap.Int("http.status_code", 500), zap.Float64("http.duration_ms", 2000))
+			},
+			kind: "http", operation: "DELETE /orders/{id}", status: "500", errors: 1, seconds: 2,
+		},
+		{
+			name: "grpc",
+			log: func(l Logger) {
+				l.Info("grpc request", zap.String("grpc.method", "/orders.v1.Orders/Get"), zap.String("grpc.code", "OK"), zap.Float64("grpc.duration_ms", 5))
+			},
+			kind: "grpc", operation: "/orders.v1.Orders/Get", status: "OK", seconds: 0.005,
+		},
+		{
+			name:  "counted above the level",
+			level: zapcore.ErrorLevel,
+			log: func(l Logger) {
+				l.Info("grpc request", zap.String("grpc.method", "/orders.v1.Orders/List"), zap.String("grpc.code", "OK"), zap.Float64("grpc.duration_ms", 5))
+			},
+			kind: "grpc", operation: "/orders.v1.Orders/List", status: "OK", seconds: 0.005,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := redRequestsTotal.WithLabelValues(tt.kind, tt.operation, tt.status)
+			errs := redErrorsTotal.WithLabelValues(tt.kind, tt.operation)
+			before := [...]float64{testutil.ToFloat64(requests), testutil.ToFloat64(errs), durationSum(t, tt.kind, tt.operation)}
+			l := New(WithSink(discard), WithLevel(tt.level), WithREDMetrics())
+			tt.log(l)
+
+			if got := testutil.ToFloat64(requests) - before[0]; got != 1 {
+				t.Errorf("requests_total grew by %v, want 1", got)
+			}
+			if got := testutil.ToFloat64(errs) - before[1]; got != tt.errors {
+				t.Errorf("errors_total grew by %v, want %v", got, tt.errors)
+			}
+			if got := durationSum(t, tt.kind, tt.operation) - before[2]; got < tt.seconds*0.999 || got > tt.seconds*1.001 {
+				t.Errorf("request_duration_seconds sum grew by %v, want %v", got, tt.seconds)
+			}
+		})
+	}
+}
+
+// TestREDMetricsOtherEntries checks that entries with other messages are left alone
+func TestREDMetricsOtherEntries(t *testing.T) {
+	before := testutil.CollectAndCount(redRequestsTotal)
+	l := New(WithSink(discard), WithREDMetrics())
+	l.Info("http request", zap.String("http.method", "GET"), zap.String("http.route", "/other"))
+	l.Info("http.request") // No operation fields
+	if got := testutil.CollectAndCount(redRequestsTotal); got != before {
+		t.Errorf("%d requests_total series, want %d", got, before)
+	}
+}
+
+// durationSum returns the sum of the request_duration_seconds series of one operation
+func durationSum(t *testing.T, kind, operation string) float64 {
+	t.Helper()
+	h, err := redDuration.GetMetricWithLabelValues(kind, operation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &dto.Metric{}
+	if err := h.(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleSum()
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/logger/fields"
+)
+
+// requestFields collects key-value pairs for the request completion entry
+type requestFields struct {
+	mu sync.Mutex
+	kv []interface{}
+
+	canonical  bool // See WithCanonicalLine
+	done       bool // RequestFields built the completion entry; nothing is folded anymore
+	suppressed int  // Entries folded into the completion entry
+	timings    []timing
+}
+
+type requestFieldsKey struct{}
+
+// WithRequestFields returns a context collecting the fields added with
+// AddRequestFields further down the call stack. The middleware that logs
+// the request's completion installs it and logs RequestFields once at the
+// end, so a user_id found deep in a handler goes on that one entry instead
+// of onto every line on the way.
+func WithRequestFields(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestFieldsKey{}).(*requestFields); ok {
+		return ctx // Keep collecting into the outer request's fields
+	}
+	return context.WithValue(ctx, requestFieldsKey{}, &requestFields{})
+}
+
+// AddRequestFields attaches key-value pairs to the completion entry of the
+// request handled under ctx, e.g. AddRequestFields(ctx, "order_id", id).
+// A later value replaces an earlier one with the same key. Without
+// WithRequestFields up the stack it does nothing.
+func AddRequestFields(ctx context.Context, keysAndValues ...interface{}) {
+	rf, ok := ctx.Value(requestFieldsKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.kv = append(rf.kv, keysAndValues...)
+}
+
+// RequestFields returns the key-value pairs added to ctx's request so far.
+// In canonical mode it completes the request: entries logged afterwards,
+// e.g. by goroutines outliving it, are written instead of folded.
+func RequestFields(ctx context.Context) []interface{} {
+	rf, ok := ctx.Value(requestFieldsKey{}).(*requestFields)
+	if !ok {
+		return nil
+	}
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	kv := lastByKey(rf.kv)
+	for _, t := range rf.timings {
+		kv = append(kv, fields.DurationMS("timing."+t.name, t.d))
+	}
+	if rf.canonical {
+		kv = append(kv, zap.Int("log.suppressed", rf.suppressed))
+		rf.done = true
+	}
+	return kv
+}
+
+// completed reports whether the completion entry was built
+func (rf *requestFields) completed() bool {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.done
+}
+
+// lastByKey drops all but the last value of each key, keeping the order in
+// which keys first appeared; zap.Field values stand for a pair of their own
+func lastByKey(kv []interface{}) []interface{} {
+	type pair struct {
+		key  string
+		args []interface{}
+	}
+	var pairs []pair
+	index := make(map[string]int)
+	for i := 0; i < len(kv); i++ {
+		var p pair
+		switch x := kv[i].(type) {
+		case zapcore.Field:
+			p = pair{x.Key, kv[i : i+1]}
+		case string:
+			if i+1 == len(kv) {
+				p = pair{x, kv[i:]} // Dangling key, reported by the sugared logger
+				break
+			}
+			p = pair{x, kv[i : i+2]}
+			i++
+		default:
+			p = pair{"", kv[i : i+1]} // Not a key; the sugared logger reports it
+		}
+		if j, ok := index[p.key]; ok && p.key != "" {
+			pairs[j].args = p.args
+			continue
+		}
+		index[p.key] = len(pairs)
+		pairs = append(pairs, p)
+	}
+	out := make([]interface{}, 0, len(kv))
+	for _, p := range pairs {
+		out = append(out, p.args...)
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/logger/fields"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// resourceCore adds the process's resource limits and usage to Panic and
+// Fatal entries, so a crash close to the container's memory limit or under
+// CPU throttling can be told apart from a plain bug. Limits come from the
+// cgroup (v2, or v1 as fallback); those it doesn't set are left out.
+type resourceCore struct {
+	zapcore.Core
+}
+
+func newResourceCore(core zapcore.Core) zapcore.Core {
+	return &resourceCore{Core: core}
+}
+
+func (c *resourceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &resourceCore{Core: c.Core.With(fields)}
+}
+
+func (c *resourceCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *resourceCore) Write(ent zapcore.Entry, fs []zapcore.Field) error {
+	if ent.Level >= zapcore.PanicLevel {
+		fs = append(fs[:len(fs):len(fs)], resourceFields()...)
+	}
+	return writeThrough(c.Core, ent, fs)
+}
+
+// resourceFields reads the current limits and usage
+func resourceFields() []zapcore.Field {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	out := []zapcore.Field{
+		zap.Int("gomaxprocs", runtime.GOMAXPROCS(0)),
+		zap.Int("num_cpu", runtime.NumCPU()),
+		fields.Bytes("heap_inuse", mem.HeapInuse),
+		fields.Bytes("go_sys", mem.Sys),
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		out = append(out, fields.Bytes("gomemlimit", limit))
+	}
+
+	limit, usage, quota := cgroupResources()
+	if limit > 0 {
+		out = append(out, fields.Bytes("cgroup_memory_limit", limit))
+	}
+	if usage > 0 {
+		out = append(out, fields.Bytes("cgroup_memory_usage", usage))
+		if limit > 0 {
+			out = append(out, fields.Percent("cgroup_memory_usage", float64(usage), float64(limit)))
+		}
+	}
+	if quota > 0 {
+		out = append(out, zap.Float64("cgroup_cpu_quota", quota))
+	}
+	return out
+}
+
+// cgroupResources returns the memory limit and usage in bytes and the CPU
+// quota in cores; each is 0 where unlimited or unknown
+func cgroupResources() (limit, usage int64, quota float64) {
+	if dir, ok := cgroupV2Dir(); ok {
+		limit = readInt(filepath.Join(dir, "memory.max"))
+		usage = readInt(filepath.Join(dir, "memory.current"))
+		// cpu.max holds "<quota> <period>", with "max" for no quota
+		if f := strings.Fields(readTrimmed(filepath.Join(dir, "cpu.max"))); len(f) == 2 {
+			q, qerr := strconv.ParseFloat(f[0], 64)
+			p, perr := strconv.ParseFloat(f[1], 64)
+			if qerr == nil && perr == nil && p > 0 {
+				quota = q / p
+			}
+		}
+		return limit, usage, quota
+	}
+
+	limit = readInt(filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes"))
+	if limit >= 1<<62 { // v1 reports no limit as a huge page-aligned number
+		limit = 0
+	}
+	usage = readInt(filepath.Join(cgroupRoot, "memory", "memory.usage_in_bytes"))
+	q := readInt(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us"))
+	p := readInt(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_period_us"))
+	if q > 0 && p > 0 {
+		quota = float64(q) / float64(p)
+	}
+	return limit, usage, quota
+}
+
+// cgroupV2Dir returns the unified hierarchy directory of this process
+func cgroupV2Dir() (string, bool) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(readTrimmed("/proc/self/cgroup"), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			dir := filepath.Join(cgroupRoot, path)
+			if _, err := os.Stat(filepath.Join(dir, "memory.max")); err == nil {
+				return dir, true
+			}
+		}
+	}
+	return cgroupRoot, true // With a private cgroup namespace the root is our own
+}
+
+// readInt reads a file holding one integer; "max" and errors read as 0
+func readInt(path string) int64 {
+	n, err := strconv.ParseInt(readTrimmed(path), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
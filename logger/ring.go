@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// entryRing holds the last encoded entries
+type entryRing struct {
+	mu    sync.Mutex
+	lines [][]byte
+	next  int
+	full  bool
+}
+
+func newEntryRing(n int) *entryRing {
+	return &entryRing{lines: make([][]byte, n)}
+}
+
+func (r *entryRing) add(line []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the entries oldest first
+func (r *entryRing) snapshot() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([][]byte(nil), r.lines[:r.next]...)
+	}
+	return append(append([][]byte(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// ringCore encodes entries as JSON lines into a ring, for the crash dump
+// and the flight recorder
+type ringCore struct {
+	zapcore.LevelEnabler
+	ring  *entryRing
+	enc   zapcore.Encoder // Carries the fields added with With
+	crash *crashDumper    // Dumps the ring on Panic and Fatal when set
+}
+
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &ringCore{LevelEnabler: c.LevelEnabler, ring: c.ring, enc: enc, crash: c.crash}
+}
+
+func (c *ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	line := make([]byte, len(buf.Bytes()))
+	copy(line, buf.Bytes())
+	buf.Free()
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	c.ring.add(line)
+
+	if c.crash != nil && ent.Level >= zapcore.PanicLevel {
+		c.crash.dump(ent)
+	}
+	return nil
+}
+
+func (c *ringCore) Sync() error { return nil }
@@ -0,0 +1,324 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// backupTimeFormat names rotated files like lumberjack did, app-2006-01-02T15-04-05.000.log,
+// so existing cleanup and shipping globs keep matching
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// fileWriter writes entries to a log file, rotating it by size. Readers
+// never see the active path missing or a half-written file in its place:
+// the old file is hard-linked to its backup name and a fresh file, created
+// under a temporary name, is renamed over the path. Compressed backups are
+// also written under a temporary name and renamed when complete.
+//
+// With shared set, writes hold an advisory lock on the file, as
+// lockedWriter does, and sizes are re-read so the writers agree on them.
+//
+// A write that fails part way is truncated off again, so the file only
+// holds whole entries, and on ENOSPC, EIO or ESTALE the file is reopened
+// and the write retried once, which recovers from a disk that was cleaned
+// up or an NFS server that came back.
+type fileWriter struct {
+	path     string
+	rotation RotationConfig
+	shared   bool // Lock the file around writes, see lockedWriter
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	mill   chan struct{} // Wakes the goroutine compressing and removing backups
+	stop   chan struct{} // Closed by Close to end that goroutine
+	closed bool
+}
+
+// newFileWriter opens path for appending; rotation is off when rotation.MaxSizeMB is 0
+func newFileWriter(path string, rotation RotationConfig, shared bool) (*fileWriter, error) {
+	w := &fileWriter{path: path, rotation: rotation, shared: shared}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if rotation.MaxSizeMB > 0 {
+		w.mill, w.stop = make(chan struct{}, 1), make(chan struct{})
+		go w.runMill()
+		w.wakeMill() // Apply the retention to backups left by earlier runs
+	}
+	return w, nil
+}
+
+func (w *fileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644) // read write for user, read only for group/others
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed // Not even by rotating to a new file
+	}
+	if max := int64(w.rotation.MaxSizeMB) * 1024 * 1024; max > 0 && w.size > 0 && w.size+int64(len(p)) > max {
+		if err := w.rotate(); err != nil {
+			reportSink("log file rotation failed", zap.String("sink", w.path), zap.Error(err))
+		}
+	}
+	n, err := w.write(p)
+	if err != nil && recoverable(err) {
+		reportSink("log file write failed, reopening", zap.String("sink", w.path), zap.Error(err))
+		if rerr := w.reopen(); rerr != nil {
+			return 0, fmt.Errorf("%w; reopening failed: %v", err, rerr)
+		}
+		n, err = w.write(p)
+	}
+	return n, err
+}
+
+// write appends p, cutting off whatever part of it made it to the file when it fails
+func (w *fileWriter) write(p []byte) (int, error) {
+	if w.shared {
+		defer lockFile(w.file)()
+		if info, err := w.file.Stat(); err == nil {
+			w.size = info.Size() // Other processes append too
+		}
+	}
+	n, err := w.file.Write(p)
+	if err == nil {
+		w.size += int64(n)
+		return n, nil
+	}
+	if n > 0 {
+		if terr := w.file.Truncate(w.size); terr != nil {
+			return 0, fmt.Errorf("%w; removing the partial entry failed: %v", err, terr)
+		}
+	}
+	return 0, err
+}
+
+// recoverable reports whether reopening the file may fix err
+func recoverable(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EIO) || errors.Is(err, syscall.ESTALE)
+}
+
+func (w *fileWriter) reopen() error {
+	_ = w.file.Close()
+	return w.open()
+}
+
+func (w *fileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Sync()
+}
+
+// Close stops the backup cleanup and closes the file; later writes fail.
+// A cleanup already running finishes in the background.
+func (w *fileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.stop != nil {
+		close(w.stop)
+	}
+	return w.file.Close()
+}
+
+// rotate moves the current file to its backup name and starts an empty one
+func (w *fileWriter) rotate() error {
+	// The new file must be on the same filesystem for the rename
+	tmp, err := os.CreateTemp(filepath.Dir(w.path), "."+filepath.Base(w.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+
+	t := time.Now()
+	backup := w.backupName(t)
+	for _, err := os.Lstat(backup); err == nil; _, err = os.Lstat(backup) {
+		t = t.Add(time.Millisecond) // Rotated twice within a millisecond
+		backup = w.backupName(t)
+	}
+	// Linking keeps the path in place until the rename replaces it; without
+	// hard link support there is a short window with no file at the path
+	if err := os.Link(w.path, backup); err != nil {
+		if err := os.Rename(w.path, backup); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), w.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := w.reopen(); err != nil {
+		return err
+	}
+	w.wakeMill()
+	return nil
+}
+
+func (w *fileWriter) backupName(t time.Time) string {
+	dir, name := filepath.Split(w.path)
+	ext := filepath.Ext(name)
+	return filepath.Join(dir, strings.TrimSuffix(name, ext)+"-"+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+func (w *fileWriter) wakeMill() {
+	select {
+	case w.mill <- struct{}{}:
+	default: // Already pending
+	}
+}
+
+func (w *fileWriter) runMill() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.mill:
+		}
+		if err := w.millBackups(); err != nil {
+			reportSink("log file cleanup failed", zap.String("sink", w.path), zap.Error(err))
+		}
+	}
+}
+
+// backupFile is a rotated file and when it was rotated
+type backupFile struct {
+	path string
+	at   time.Time
+}
+
+// millBackups compresses new backups and removes those beyond MaxBackups or MaxAgeDays
+func (w *fileWriter) millBackups() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	var remove []backupFile
+	if n := w.rotation.MaxBackups; n > 0 && len(backups) > n {
+		backups, remove = backups[:n], backups[n:]
+	}
+	if days := w.rotation.MaxAgeDays; days > 0 {
+		cutoff := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+		kept := backups[:0]
+		for _, b := range backups {
+			if b.at.Before(cutoff) {
+				remove = append(remove, b)
+				continue
+			}
+			kept = append(kept, b)
+		}
+		backups = kept
+	}
+
+	var errs []error
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	if w.rotation.Compress {
+		for _, b := range backups {
+			if !strings.HasSuffix(b.path, ".gz") {
+				if err := compressFile(b.path); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backups lists the rotated files, newest first
+func (w *fileWriter) backups() ([]backupFile, error) {
+	dir, name := filepath.Dir(w.path), filepath.Base(w.path)
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, e := range entries {
+		stamp := strings.TrimSuffix(e.Name(), ".gz")
+		if e.IsDir() || !strings.HasPrefix(stamp, prefix) || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		at, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(stamp, prefix), ext))
+		if err != nil {
+			continue // Another file sharing the prefix
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, e.Name()), at: at})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].at.After(backups[j].at) })
+	return backups, nil
+}
+
+// compressFile gzips path to path.gz, visible only once complete, and removes path
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".gz.*.tmp")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(tmp)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
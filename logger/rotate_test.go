@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWriterRotation(t *testing.T) {
+	tests := []struct {
+		name      string
+		rotation  RotationConfig
+		writes    int
+		wantPlain int // Plain backups left
+		wantGzip  int // Compressed backups left
+	}{
+		{name: "rotates by size", rotation: RotationConfig{MaxSizeMB: 1}, writes: 3, wantPlain: 2},
+		{name: "keeps MaxBackups", rotation: RotationConfig{MaxSizeMB: 1, MaxBackups: 1}, writes: 4, wantPlain: 1},
+		{name: "compresses backups", rotation: RotationConfig{MaxSizeMB: 1, Compress: true}, writes: 3, wantGzip: 2},
+	}
+	entry := bytes.Repeat([]byte("x"), 700*1024) // Two of these exceed 1 MB
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			w, err := newFileWriter(path, tt.rotation, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.writes; i++ {
+				if _, err := w.Write(entry); err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(2 * time.Millisecond) // Distinct backup names
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				plain, gz := countBackups(t, path)
+				if plain == tt.wantPlain && gz == tt.wantGzip {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("%d plain and %d compressed backups, want %d and %d", plain, gz, tt.wantPlain, tt.wantGzip)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if info, err := os.Stat(path); err != nil || info.Size() != int64(len(entry)) {
+				t.Errorf("active file: %v, want one entry", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestFileWriterCompressedBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := newFileWriter(path, RotationConfig{MaxSizeMB: 1, Compress: true}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	first := bytes.Repeat([]byte("a"), 700*1024)
+	w.Write(first)
+	w.Write(bytes.Repeat([]byte("b"), 700*1024))
+
+	deadline := time.Now().Add(5 * time.Second)
+	var backups []backupFile
+	for {
+		backups, _ = w.backups()
+		if len(backups) == 1 && strings.HasSuffix(backups[0].path, ".gz") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("backups = %v, want one compressed", backups)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	f, err := os.Open(backups[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(gz); !bytes.Equal(got, first) {
+		t.Errorf("backup holds %d bytes, want the %d of the rotated file", len(got), len(first))
+	}
+}
+
+func TestFileWriterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := newFileWriter(path, RotationConfig{MaxSizeMB: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("x"), 2<<20)); err == nil {
+		t.Error("write after Close succeeded")
+	}
+	if plain, gz := countBackups(t, path); plain+gz != 0 {
+		t.Error("write after Close rotated the file")
+	}
+}
+
+// countBackups counts the rotated files of path, skipping temporary ones
+func countBackups(t *testing.T, path string) (plain, gz int) {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := strings.TrimSuffix(filepath.Base(path), ".log") + "-"
+	for _, e := range entries {
+		switch name := e.Name(); {
+		case !strings.HasPrefix(name, prefix):
+		case strings.HasSuffix(name, ".log.gz"):
+			gz++
+		case strings.HasSuffix(name, ".log"):
+			plain++
+		}
+	}
+	return plain, gz
+}
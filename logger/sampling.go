@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingExemption matches entries the sampler must never drop, such as
+// audit records or errors about a specific customer. An entry matches when
+// it is at Level or above and carries Key, directly or via With, with Value
+// when Value is set:
+//
+//	logger.WithSamplingExemptions(
+//		logger.SamplingExemption{Key: "audit", Value: "true"},
+//		logger.SamplingExemption{Key: "customer_id", Level: "error"},
+//	)
+type SamplingExemption struct {
+	Key   string // Field name; empty matches every entry at Level
+	Value string // Optional value, compared with the field value as text
+	Level string // Optional minimum level, e.g. "error"
+}
+
+// WithSamplingExemptions keeps entries matching any of ex out of the
+// sampling set up with WithSampling or the SampleFlag of WithFlags
+func WithSamplingExemptions(ex ...SamplingExemption) Option {
+	return func(o *options) { o.sampleExempt = append(o.sampleExempt, ex...) }
+}
+
+func (e SamplingExemption) matches(ent zapcore.Entry, context, fields []zapcore.Field) bool {
+	if e.Level != "" {
+		min, err := zapcore.ParseLevel(e.Level)
+		if err == nil && ent.Level < min {
+			return false
+		}
+	}
+	if e.Key == "" {
+		return true
+	}
+	// Fields of the call override those added with With
+	for _, fs := range [][]zapcore.Field{fields, context} {
+		for i := len(fs) - 1; i >= 0; i-- {
+			if fs[i].Key == e.Key {
+				return e.Value == "" || fieldText(fs[i]) == e.Value
+			}
+		}
+	}
+	return false
+}
+
+// fieldText formats a field's value the way it would be written as text
+func fieldText(f zapcore.Field) string {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String
+	case zapcore.BoolType:
+		return fmt.Sprint(f.Integer == 1)
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return fmt.Sprint(enc.Fields[f.Key])
+}
+
+// sampleCore sends exempt entries past the sampler. The sampler decides on
+// level and message alone, before fields are known, so the choice is made
+// in Write instead and the sampled path checks the wrapped sampler there.
+type sampleCore struct {
+	zapcore.Core
+	sampled zapcore.Core // The sampler over Core
+	exempt  []SamplingExemption
+	context []zapcore.Field // Fields added through With
+}
+
+// newSampleCore passes the entries matching exempt to core and the others
+// to sampled, a sampler over core
+func newSampleCore(core, sampled zapcore.Core, exempt []SamplingExemption) zapcore.Core {
+	if len(exempt) == 0 {
+		return sampled
+	}
+	return &sampleCore{Core: core, sampled: sampled, exempt: exempt}
+}
+
+func (c *sampleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sampleCore{
+		Core:    c.Core.With(fields),
+		sampled: c.sampled.With(fields), // Shares the sampler's counters
+		exempt:  c.exempt,
+		context: append(c.context[:len(c.context):len(c.context)], fields...),
+	}
+}
+
+func (c *sampleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sampleCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	for _, e := range c.exempt {
+		if e.matches(ent, c.context, fields) {
+			return writeThrough(c.Core, ent, fields)
+		}
+	}
+	return writeThrough(c.sampled, ent, fields)
+}
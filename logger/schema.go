@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Schema modes
+const (
+	SchemaModeReport = "report" // Replace the entry with a schema violation report
+	SchemaModeDrop   = "drop"   // Silently drop nonconforming entries
+)
+
+// Schema constrains the fields of every entry so that strict downstream
+// parsers never see unexpected shapes
+type Schema struct {
+	Required       []string          // Fields every entry must carry (directly or via With)
+	Types          map[string]string // Field -> string, int, float, bool, time, duration, object or array
+	MaxFields      int               // Max fields per entry; 0 means unlimited
+	MaxValueLength int               // Max length of string values; 0 means unlimited
+	Mode           string            // SchemaModeReport (default) or SchemaModeDrop
+}
+
+// WithSchema enforces s on every entry
+func WithSchema(s Schema) Option {
+	return func(o *options) { o.schema = &s }
+}
+
+// schemaCore validates entries against a Schema before writing them
+type schemaCore struct {
+	zapcore.Core
+	schema  *Schema
+	context []zapcore.Field // Fields added through With, needed for Required checks
+}
+
+func newSchemaCore(core zapcore.Core, s *Schema) zapcore.Core {
+	return &schemaCore{Core: core, schema: s}
+}
+
+func (c *schemaCore) With(fields []zapcore.Field) zapcore.Core {
+	return &schemaCore{
+		Core:    c.Core.With(fields),
+		schema:  c.schema,
+		context: append(c.context[:len(c.context):len(c.context)], fields...),
+	}
+}
+
+func (c *schemaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *schemaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	violations := c.schema.violations(c.context, fields)
+	if len(violations) == 0 {
+		return writeThrough(c.Core, ent, fields)
+	}
+	if c.schema.Mode == SchemaModeDrop {
+		return nil
+	}
+	return writeThrough(c.Core, ent, []zapcore.Field{
+		zap.Bool("schema_violation", true),
+		zap.Strings("schema_violations", violations),
+	})
+}
+
+// violations lists every way the fields break the schema
+func (s *Schema) violations(context, fields []zapcore.Field) []string {
+	var out []string
+	all := make(map[string]zapcore.Field, len(context)+len(fields))
+	for _, f := range context {
+		all[f.Key] = f
+	}
+	for _, f := range fields {
+		all[f.Key] = f
+	}
+
+	if s.MaxFields > 0 && len(all) > s.MaxFields {
+		out = append(out, fmt.Sprintf("%d fields exceed the limit of %d", len(all), s.MaxFields))
+	}
+	for _, key := range s.Required {
+		if _, ok := all[key]; !ok {
+			out = append(out, fmt.Sprintf("missing required field %q", key))
+		}
+	}
+	for key, f := range all {
+		if want, ok := s.Types[key]; ok {
+			if got := fieldKind(f); got != want {
+				out = append(out, fmt.Sprintf("field %q is %s, want %s", key, got, want))
+			}
+		}
+		if s.MaxValueLength > 0 && f.Type == zapcore.StringType && len(f.String) > s.MaxValueLength {
+			out = append(out, fmt.Sprintf("field %q is %d bytes, limit %d", key, len(f.String), s.MaxValueLength))
+		}
+	}
+	return out
+}
+
+// fieldKind maps zap field types onto the schema type names
+func fieldKind(f zapcore.Field) string {
+	switch f.Type {
+	case zapcore.StringType, zapcore.StringerType, zapcore.ByteStringType, zapcore.BinaryType, zapcore.ErrorType:
+		return "string"
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type,
+		zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+		return "int"
+	case zapcore.Float64Type, zapcore.Float32Type:
+		return "float"
+	case zapcore.BoolType:
+		return "bool"
+	case zapcore.TimeType, zapcore.TimeFullType:
+		return "time"
+	case zapcore.DurationType:
+		return "duration"
+	case zapcore.ArrayMarshalerType:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"os"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// lockedWriter takes an exclusive advisory lock around every write, so each
+// entry lands whole even when several processes write to the same stdout
+// pipe or file. Writes above PIPE_BUF (4 KiB on Linux) to a pipe are
+// otherwise split by the kernel and can interleave with another process's;
+// the lock only orders writers that take it too, such as other services
+// built on this package.
+type lockedWriter struct {
+	mu   sync.Mutex // flock doesn't exclude goroutines sharing the descriptor
+	file *os.File
+	lock *os.File
+}
+
+// NewLockedWriter writes to f under an advisory lock shared with other
+// processes writing to f; on systems without flock it only serializes this
+// process
+func NewLockedWriter(f *os.File) zapcore.WriteSyncer {
+	return &lockedWriter{file: f, lock: outputLock(f)}
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer lockFile(w.lock)()
+	return w.file.Write(p)
+}
+
+func (w *lockedWriter) Sync() error {
+	return w.file.Sync()
+}
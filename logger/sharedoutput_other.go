@@ -0,0 +1,11 @@
+//go:build !unix
+
+package logger
+
+import "os"
+
+func outputLock(f *os.File) *os.File { return f }
+
+func lockFile(f *os.File) (unlock func()) {
+	return func() {}
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// outputLock opens the file to lock for writes to f. Processes that
+// inherited one stdout share its open file description, which flock can't
+// tell apart, so they each open a lock file in the temporary directory named
+// after the output's device and inode. When that fails f itself is locked.
+func outputLock(f *os.File) *os.File {
+	info, err := f.Stat()
+	if err != nil {
+		return f
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return f
+	}
+	name := filepath.Join(os.TempDir(), fmt.Sprintf("log-output-%d-%d.lock", st.Dev, st.Ino))
+	l, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return f
+	}
+	return l
+}
+
+// lockFile takes flock(LOCK_EX) on f and returns the unlock. A lock that
+// can't be taken is skipped, as an entry that may interleave beats a lost one.
+func lockFile(f *os.File) (unlock func()) {
+	fd := int(f.Fd())
+	if err := flock(fd, syscall.LOCK_EX); err != nil {
+		return func() {}
+	}
+	return func() { _ = flock(fd, syscall.LOCK_UN) }
+}
+
+func flock(fd, how int) error {
+	for {
+		if err := syscall.Flock(fd, how); err != syscall.EINTR {
+			return err
+		}
+	}
+}
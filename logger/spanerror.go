@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recordSpanErrors is set from Config.RecordSpanErrors
+var recordSpanErrors bool
+
+// Error logs msg and fields at error level and records the error of the
+// first zap.Error field, or msg, on the span captured by Ctx
+func (l Logger) Error(msg string, fields ...zap.Field) {
+	if ce := l.up().Check(zapcore.ErrorLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+	if recordSpanErrors && l.span != nil {
+		var args []interface{}
+		for _, f := range fields {
+			if f.Type == zapcore.ErrorType {
+				args = append(args, f.Interface)
+				break
+			}
+		}
+		l.recordError(msg, args)
+	}
+}
+
+// Errorf logs a formatted message at error level and records it on the span captured by Ctx
+func (l Logger) Errorf(template string, args ...interface{}) {
+	l.recordError(fmt.Sprintf(template, args...), args)
+	l.skipped().Errorf(template, args...)
+}
+
+// Errorw logs a message with key-value pairs at error level and records it on the span captured by Ctx
+func (l Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.recordError(msg, keysAndValues)
+	l.skipped().Errorw(msg, keysAndValues...)
+}
+
+// skipped reports the caller of the wrapper method rather than the wrapper itself
+func (l Logger) skipped() *zap.SugaredLogger {
+	if l.sugaredUp == nil { // Zero Logger or one built outside wrap
+		return l.SugaredLogger.WithOptions(zap.AddCallerSkip(1))
+	}
+	return l.sugaredUp
+}
+
+// recordError records the first error found in args (or msg) on the span
+func (l Logger) recordError(msg string, args []interface{}) {
+	if !recordSpanErrors || l.span == nil || !l.span.IsRecording() {
+		return
+	}
+	var err error
+	for _, a := range args {
+		if e, ok := a.(error); ok {
+			err = e
+			break
+		}
+	}
+	if err == nil {
+		err = errors.New(msg)
+	}
+	l.span.RecordError(err)
+	l.span.SetStatus(codes.Error, msg)
+}
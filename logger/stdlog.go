@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RedirectStdLog sends output of the standard library's global logger,
+// used by many dependencies, to l as info entries with source=stdlog and
+// the originating file:line as stdlog_caller. It returns a function that
+// restores the previous output.
+func RedirectStdLog(l Logger) func() {
+	flags, prefix, out := log.Flags(), log.Prefix(), log.Writer()
+	log.SetFlags(log.Lshortfile) // Timestamps come from the logger
+	log.SetPrefix("")
+	log.SetOutput(&stdLogWriter{log: l.With("source", "stdlog")})
+	return func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(out)
+	}
+}
+
+type stdLogWriter struct {
+	log FieldLogger
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	// The log package makes one Write per call, "file.go:12: message\n"
+	msg := strings.TrimSuffix(string(p), "\n")
+	if i := strings.Index(msg, ": "); i > 0 && strings.Contains(msg[:i], ".go:") {
+		w.log.Infow(msg[i+2:], "stdlog_caller", msg[:i])
+	} else {
+		w.log.Infow(msg)
+	}
+	return len(p), nil
+}
+
+// CaptureStderr replaces os.Stderr with a pipe whose lines are logged to l
+// as warn entries with source=stderr. Only writes through os.Stderr are
+// captured; the runtime and cgo code write to file descriptor 2 directly.
+// Build l before calling it, so l itself is not writing to the pipe. The
+// returned function restores os.Stderr and flushes a trailing partial line.
+func CaptureStderr(l Logger) (func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	orig := os.Stderr
+	os.Stderr = w
+
+	fl := l.With("source", "stderr")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for sc.Scan() {
+			if line := bytes.TrimRight(sc.Bytes(), "\r"); len(line) > 0 {
+				fl.Warnw(string(line))
+			}
+		}
+		r.Close()
+	}()
+
+	return func() {
+		os.Stderr = orig
+		w.Close()
+		wg.Wait()
+	}, nil
+}
@@ -0,0 +1,206 @@
+package logger_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/config"
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/logger/events"
+)
+
+// levels are swapped through; none of them drops error entries
+var levels = []string{"debug", "info", "warn", "error"}
+
+// levelSource is a RemoteSource whose log.level changes on every tick
+type levelSource struct {
+	every time.Duration
+}
+
+func (s levelSource) Load(ctx context.Context) (map[string]string, error) {
+	return map[string]string{"log.level": "info"}, nil
+}
+
+func (s levelSource) Watch(ctx context.Context, fn func(map[string]string)) error {
+	t := time.NewTicker(s.every)
+	defer t.Stop()
+	for i := 0; ; i++ {
+		select {
+		case <-t.C:
+			fn(map[string]string{"log.level": levels[i%len(levels)]})
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TestStress logs from hundreds of goroutines at once while the log file
+// rotates, the remote config changes the level, the level endpoint is
+// called and the flight recorder is dumped, and then checks that every entry
+// reached the files intact. It is meant for the race detector:
+//
+//	go test -race -run TestStress ./logger
+//
+// Info entries come and go with the level, so only error entries, which no
+// level used here drops, are counted: each must appear exactly once, and
+// every line of every file must be one valid JSON object. With -short it
+// runs briefly with fewer goroutines.
+func TestStress(t *testing.T) {
+	goroutines, duration := 200, 3*time.Second
+	if testing.Short() {
+		goroutines, duration = 50, 300*time.Millisecond
+	}
+	dir := t.TempDir()
+
+	l := logger.NewLogger(logger.Config{
+		Level:                   "info",
+		OutputPaths:             []string{filepath.Join(dir, "stress.log")},
+		Rotation:                logger.RotationConfig{MaxSizeMB: 1}, // Rotate often, keep every file
+		Redaction:               &logger.RedactionConfig{Keys: []string{"password"}},
+		ErrorRateInterval:       10 * time.Millisecond,
+		DisableKubernetesFields: true,
+		FlightRecorder:          500,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	background := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	// Config reloads, direct level changes and the level endpoint, all at once
+	rc := config.NewRemoteConfig(levelSource{every: time.Millisecond})
+	rc.BindLogLevel("log.level")
+	background(func() { rc.Run(ctx) })
+	background(func() {
+		for i := 0; ctx.Err() == nil; i++ {
+			lvl, _ := zapcore.ParseLevel(levels[i%len(levels)])
+			logger.SetLevel(lvl)
+			time.Sleep(time.Millisecond)
+		}
+	})
+	background(func() {
+		for i := 0; ctx.Err() == nil; i++ {
+			body := fmt.Sprintf(`{"level":%q}`, levels[i%len(levels)])
+			req := httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(body))
+			logger.LevelHandler().ServeHTTP(httptest.NewRecorder(), req)
+			time.Sleep(time.Millisecond)
+		}
+	})
+	background(func() {
+		for ctx.Err() == nil {
+			_ = logger.SharedFlightRecorder().Dump(io.Discard, 0)
+			logger.FlightRecorderHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/debug/logs?n=50", nil))
+			_ = l.Sync()
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	var logged atomic.Int64 // Error entries written
+	for w := 0; w < goroutines; w++ {
+		w := w
+		background(func() {
+			child := l.With("worker", w)
+			typed := l.Typed().With(zap.Int("worker", w))
+			for seq := 0; ctx.Err() == nil; seq++ {
+				switch seq % 6 {
+				case 0:
+					l.Infow("stress", "worker", w, "seq", seq, "password", "hunter2")
+				case 1:
+					typed.Debug("stress", zap.Int("seq", seq))
+				case 2:
+					child.Named("child").Warnw("stress", "seq", seq, "payload", map[string]interface{}{"password": "hunter2", "n": seq})
+				case 3:
+					logger.ErrorRateLimited("stress", "rate limited", "worker", w, "seq", seq)
+				case 4:
+					events.Log(child, events.DBQuery{System: "postgres", Operation: "SELECT", Rows: int64(seq), Duration: time.Millisecond})
+				case 5:
+					typed.Error("counted", zap.Int("seq", seq))
+					logged.Add(1)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	_ = l.Sync()
+
+	if err := verify(dir, logged.Load()); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d goroutines, %d counted entries", goroutines, logged.Load())
+}
+
+// verify reads every stress log file in dir and checks the lines and the counted entries
+func verify(dir string, want int64) error {
+	files, err := filepath.Glob(filepath.Join(dir, "stress*.log"))
+	if err != nil {
+		return err
+	}
+	seen := make(map[[2]int]bool, want)
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for line := 1; sc.Scan(); line++ {
+			var ent struct {
+				Msg    string `json:"msg"`
+				Worker *int   `json:"worker"`
+				Seq    *int   `json:"seq"`
+			}
+			if err := json.Unmarshal(sc.Bytes(), &ent); err != nil {
+				f.Close()
+				return fmt.Errorf("%s:%d: invalid JSON: %v: %q", name, line, err, sc.Bytes())
+			}
+			if strings.Contains(sc.Text(), "hunter2") {
+				f.Close()
+				return fmt.Errorf("%s:%d: redacted value in output", name, line)
+			}
+			if ent.Msg != "counted" {
+				continue
+			}
+			if ent.Worker == nil || ent.Seq == nil {
+				f.Close()
+				return fmt.Errorf("%s:%d: counted entry without worker or seq", name, line)
+			}
+			key := [2]int{*ent.Worker, *ent.Seq}
+			if seen[key] {
+				f.Close()
+				return fmt.Errorf("%s:%d: entry worker=%d seq=%d written twice", name, line, key[0], key[1])
+			}
+			seen[key] = true
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if int64(len(seen)) != want {
+		return fmt.Errorf("%d of %d counted entries in %d files", len(seen), want, len(files))
+	}
+	return nil
+}
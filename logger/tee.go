@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SinkConfig is one output with its own encoding, letting a single Config
+// write colorized console lines to stderr and JSON to a file at the same time
+type SinkConfig struct {
+	Encoding    string   // "json", "console", "msgpack", "otel", "logfmt" or "gelf"
+	OutputPaths []string // Same forms as Config.OutputPaths
+	Level       string   // Optional minimum level for this sink, on top of the logger level
+
+	// WriteTimeout bounds each write and sync of this sink, overriding
+	// Config.WriteTimeout
+	WriteTimeout time.Duration
+
+	Fsync *FsyncPolicy // Optional fsync policy for file outputs, overriding Config.Fsync
+}
+
+// encodedSink is an output with its own encoder
+type encodedSink struct {
+	encoding string
+	ws       zapcore.WriteSyncer
+	min      zapcore.Level
+}
+
+// WithEncodedSink adds an output encoded independently of WithEncoding,
+// e.g. WithEncodedSink("console", os.Stderr) next to a JSON file sink.
+// Entries below min are not written to this sink.
+func WithEncodedSink(encoding string, ws zapcore.WriteSyncer, min zapcore.Level) Option {
+	return func(o *options) { o.encodedSinks = append(o.encodedSinks, encodedSink{encoding, ws, min}) }
+}
+
+// sinkOptions turns Config.Sinks into options
+func sinkOptions(sinks []SinkConfig, outputs outputOptions) []Option {
+	var opts []Option
+	for _, s := range sinks {
+		min, err := zapcore.ParseLevel(s.Level)
+		if err != nil {
+			min = zapcore.DebugLevel // No extra filtering
+		}
+		out := outputs
+		if s.WriteTimeout > 0 {
+			out.timeout = s.WriteTimeout
+		}
+		if s.Fsync != nil {
+			out.fsync = *s.Fsync
+		}
+		opts = append(opts, WithEncodedSink(s.Encoding, getLogWriter(s.OutputPaths, out), min))
+	}
+	return opts
+}
+
+// sinkLevel enables levels that pass both the shared level and the sink minimum
+func sinkLevel(shared zapcore.LevelEnabler, min zapcore.Level) zapcore.LevelEnabler {
+	return zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= min && shared.Enabled(l)
+	})
+}
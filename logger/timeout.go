@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrSinkTimeout is returned by a write or sync that exceeded the sink's deadline
+var ErrSinkTimeout = errors.New("log sink timed out")
+
+// sinkRetryInterval is how long a sink stays skipped after a timeout
+const sinkRetryInterval = 5 * time.Second
+
+// timeoutWriter bounds every Write and Sync of an output. When one times out
+// the circuit opens: entries for that output are dropped without waiting
+// until the stuck call has returned and sinkRetryInterval has passed, then
+// the next write tries the output again. A hung NFS mount or a stalled TCP
+// peer costs one timeout instead of blocking every log call, Sync and
+// shutdown.
+type timeoutWriter struct {
+	name    string
+	ws      zapcore.WriteSyncer
+	timeout time.Duration
+
+	mu        sync.Mutex
+	pending   chan error // Result of the call that timed out, until it returns
+	openUntil time.Time
+	dropped   int64
+}
+
+// NewTimeoutWriter wraps ws so that a Write or Sync taking longer than
+// timeout fails with ErrSinkTimeout and later writes skip ws for a while;
+// name identifies the output in the logger's own error reports. A timeout
+// of zero returns ws unchanged.
+func NewTimeoutWriter(name string, ws zapcore.WriteSyncer, timeout time.Duration) zapcore.WriteSyncer {
+	if timeout <= 0 {
+		return ws
+	}
+	return &timeoutWriter{name: name, ws: ws, timeout: timeout}
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	// The encoder reuses p once Write returns, and a call that timed out is still running
+	buf := append([]byte(nil), p...)
+	if err := w.call("write", func() error { _, err := w.ws.Write(buf); return err }); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *timeoutWriter) Sync() error {
+	return w.call("sync", w.ws.Sync)
+}
+
+// call runs fn with the deadline, or drops it while the circuit is open
+func (w *timeoutWriter) call(op string, fn func() error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending != nil {
+		select {
+		case <-w.pending:
+			w.pending = nil
+		default:
+			w.dropped++
+			return nil
+		}
+	}
+	if time.Now().Before(w.openUntil) {
+		w.dropped++
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err == nil && w.dropped > 0 {
+			reportSink("log sink recovered", zap.String("sink", w.name), zap.Int64("dropped", w.dropped))
+			w.dropped = 0
+		}
+		return err
+	case <-timer.C:
+		w.pending = done
+		w.openUntil = time.Now().Add(sinkRetryInterval)
+		reportSink("log sink "+op+" timed out, dropping its entries",
+			zap.String("sink", w.name), zap.Duration("timeout", w.timeout), zap.Duration("retry_in", sinkRetryInterval))
+		return fmt.Errorf("%s %s: %w after %s", w.name, op, ErrSinkTimeout, w.timeout)
+	}
+}
+
+func reportSink(msg string, fields ...zapcore.Field) {
+	_ = fallback.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: msg}, fields)
+}
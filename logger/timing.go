@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/upendravikram5/upendra/logger/fields"
+)
+
+// Timed starts timing an operation and returns the function that logs msg
+// at info level with the start, end and duration_ms fields followed by
+// keysAndValues:
+//
+//	done := log.Timed("import finished")
+//	defer done("rows", n)
+func (l Logger) Timed(msg string) func(keysAndValues ...interface{}) {
+	sw := StartStopwatch()
+	l = l.CallerSkip(1) // Report the caller of done
+	return func(keysAndValues ...interface{}) {
+		l.Infow(msg, append(sw.Fields(), keysAndValues...)...)
+	}
+}
+
+// Timed is Logger.Timed on the shared logger
+func Timed(msg string) func(keysAndValues ...interface{}) {
+	if logger.SugaredLogger == nil {
+		return func(...interface{}) {}
+	}
+	return logger.Timed(msg)
+}
+
+// Stopwatch times an operation and its phases on the monotonic clock, so a
+// wall clock step during the operation doesn't skew the durations. It is
+// not safe for concurrent use.
+type Stopwatch struct {
+	start time.Time
+	last  time.Duration // Elapsed at the last Lap
+	laps  []timing
+}
+
+// StartStopwatch returns a running stopwatch
+func StartStopwatch() *Stopwatch {
+	return &Stopwatch{start: time.Now()}
+}
+
+// Lap ends the current phase, naming it, and returns its duration; a name
+// used again adds to that phase
+func (s *Stopwatch) Lap(name string) time.Duration {
+	now := s.Elapsed()
+	d := now - s.last
+	s.last = now
+	for i := range s.laps {
+		if s.laps[i].name == name {
+			s.laps[i].d += d
+			return d
+		}
+	}
+	s.laps = append(s.laps, timing{name, d})
+	return d
+}
+
+// Elapsed returns the time since the stopwatch started
+func (s *Stopwatch) Elapsed() time.Duration {
+	return time.Since(s.start)
+}
+
+// Fields returns start, end and duration_ms, plus timing.<phase>_ms for
+// every lap, as key-value pairs for the sugared API. End is start plus the
+// monotonic duration, so the three always agree.
+func (s *Stopwatch) Fields() []interface{} {
+	elapsed := s.Elapsed()
+	kv := make([]interface{}, 0, 3+len(s.laps))
+	kv = append(kv,
+		zap.Time("start", s.start),
+		zap.Time("end", s.start.Add(elapsed)),
+		fields.DurationMS("duration", elapsed),
+	)
+	for _, l := range s.laps {
+		kv = append(kv, fields.DurationMS("timing."+l.name, l.d))
+	}
+	return kv
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TruncationConfig caps string values so an accidental huge payload can't
+// blow up sinks or ingestion quotas. Only string and byte fields (and the
+// message) are measured; objects and arrays are left to the encoder.
+type TruncationConfig struct {
+	MaxFieldBytes int    // Per value limit; 0 means unlimited
+	MaxEntryBytes int    // Budget shared by the message and all values; 0 means unlimited
+	Marker        string // Appended to truncated values (default "...[truncated]")
+	FlagKey       string // Boolean field added when anything was cut (default "value_truncated")
+}
+
+// WithTruncation caps field sizes as described by cfg
+func WithTruncation(cfg TruncationConfig) Option {
+	return func(o *options) { o.truncation = &cfg }
+}
+
+type truncateCore struct {
+	zapcore.Core
+	cfg TruncationConfig
+}
+
+func newTruncateCore(core zapcore.Core, cfg TruncationConfig) zapcore.Core {
+	if cfg.Marker == "" {
+		cfg.Marker = "...[truncated]"
+	}
+	if cfg.FlagKey == "" {
+		cfg.FlagKey = "value_truncated"
+	}
+	return &truncateCore{Core: core, cfg: cfg}
+}
+
+func (c *truncateCore) With(fields []zapcore.Field) zapcore.Core {
+	fields, _ = c.truncate(fields, -1)
+	return &truncateCore{Core: c.Core.With(fields), cfg: c.cfg}
+}
+
+func (c *truncateCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *truncateCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	budget := -1 // Unlimited
+	if c.cfg.MaxEntryBytes > 0 {
+		budget = c.cfg.MaxEntryBytes
+	}
+
+	var cut bool
+	ent.Message, budget, cut = c.cap(ent.Message, budget)
+	fields, fieldsCut := c.truncate(fields, budget)
+	if cut || fieldsCut {
+		fields = append(fields, zap.Bool(c.cfg.FlagKey, true))
+	}
+	return writeThrough(c.Core, ent, fields)
+}
+
+// truncate returns fields with oversized values cut, copying only when needed
+func (c *truncateCore) truncate(fields []zapcore.Field, budget int) ([]zapcore.Field, bool) {
+	var out []zapcore.Field
+	for i, f := range fields {
+		var (
+			s   string
+			cut bool
+		)
+		switch f.Type {
+		case zapcore.StringType:
+			s, budget, cut = c.cap(f.String, budget)
+			f.String = s
+		case zapcore.ByteStringType, zapcore.BinaryType:
+			b, _ := f.Interface.([]byte)
+			s, budget, cut = c.cap(string(b), budget)
+			if cut {
+				f = zap.String(f.Key, s)
+			}
+		default:
+			continue
+		}
+		if cut {
+			if out == nil {
+				out = append(make([]zapcore.Field, 0, len(fields)+1), fields...)
+			}
+			out[i] = f
+		}
+	}
+	if out == nil {
+		return fields, false
+	}
+	return out, true
+}
+
+// cap shortens s to the per-field limit and the remaining entry budget
+func (c *truncateCore) cap(s string, budget int) (string, int, bool) {
+	limit := len(s)
+	if c.cfg.MaxFieldBytes > 0 && limit > c.cfg.MaxFieldBytes {
+		limit = c.cfg.MaxFieldBytes
+	}
+	if budget >= 0 && limit > budget {
+		limit = budget
+	}
+	if budget >= 0 {
+		budget -= limit
+	}
+	if limit == len(s) {
+		return s, budget, false
+	}
+	// Back off to a rune boundary so the result stays valid UTF-8
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit] + c.cfg.Marker, budget, true
+}
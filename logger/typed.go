@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Debug, Info, Warn, Error, DPanic, Panic and Fatal take typed fields, as
+// zap.Logger's do, in place of the sugared variadic methods; the sugared
+// forms remain as Debugw, Debugf and so on. This breaks calls such as
+// log.Info("started", "port", port), which must become Infow, or
+// l.SugaredLogger.Info for the fmt.Sprint form. Typed fields skip the
+// interface{} boxing and reflection of key-value pairs, so they are the API
+// for hot paths:
+//
+//	log.Info("message consumed",
+//		zap.String("topic", msg.Topic),
+//		zap.Int64("offset", msg.Offset),
+//	)
+//
+// An enabled entry still allocates for the caller annotation and, when
+// fields are passed, for their slice; a disabled one only for the slice.
+// BenchmarkTyped and its neighbours in logger_test.go keep count.
+
+// Debug logs msg and fields at debug level
+func (l Logger) Debug(msg string, fields ...zap.Field) {
+	if ce := l.up().Check(zapcore.DebugLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// Info logs msg and fields at info level
+func (l Logger) Info(msg string, fields ...zap.Field) {
+	if ce := l.up().Check(zapcore.InfoLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// Warn logs msg and fields at warn level
+func (l Logger) Warn(msg string, fields ...zap.Field) {
+	if ce := l.up().Check(zapcore.WarnLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// DPanic logs msg and fields at dpanic level, panicking in development mode
+func (l Logger) DPanic(msg string, fields ...zap.Field) {
+	if ce := l.up().Check(zapcore.DPanicLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// Panic logs msg and fields at panic level, then panics
+func (l Logger) Panic(msg string, fields ...zap.Field) {
+	if ce := l.up().Check(zapcore.PanicLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// Fatal logs msg and fields at fatal level, then exits
+func (l Logger) Fatal(msg string, fields ...zap.Field) {
+	if ce := l.up().Check(zapcore.FatalLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// Typed returns the strongly-typed zap logger behind l, e.g. for libraries
+// that take a *zap.Logger
+func (l Logger) Typed() *zap.Logger {
+	if l.typed == nil { // Zero Logger or one built outside wrap
+		return l.SugaredLogger.Desugar()
+	}
+	return l.typed
+}
+
+// up returns the typed logger that skips the wrapper method's frame
+func (l Logger) up() *zap.Logger {
+	if l.typedUp == nil {
+		return l.SugaredLogger.Desugar().WithOptions(zap.AddCallerSkip(1))
+	}
+	return l.typedUp
+}
@@ -0,0 +1,116 @@
+// Package zerologbridge routes zerolog output from third-party libraries
+// into the package logger, so it is encoded and shipped like every other
+// entry.
+package zerologbridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Writer decodes zerolog's JSON lines and writes them to the logger
+type Writer struct {
+	core zapcore.Core
+}
+
+// NewWriter creates a writer logging to l with source=zerolog
+func NewWriter(l logger.Logger) *Writer {
+	return &Writer{core: l.Desugar().Core().With([]zapcore.Field{zap.String("source", "zerolog")})}
+}
+
+// Write handles one zerolog event; lines that are not JSON objects are
+// logged as the message
+func (w *Writer) Write(p []byte) (int, error) {
+	var event map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&event); err != nil {
+		w.write(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: string(bytes.TrimSpace(p))}, nil)
+		return len(p), nil
+	}
+
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), LoggerName: "zerolog"}
+	if s, ok := event[zerolog.LevelFieldName].(string); ok {
+		if lvl, err := zerolog.ParseLevel(s); err == nil {
+			ent.Level = zapLevel(lvl)
+		}
+	}
+	if s, ok := event[zerolog.MessageFieldName].(string); ok {
+		ent.Message = s
+	}
+	// Events are written synchronously, so the current time is more precise
+	// than zerolog's second resolution timestamp
+	delete(event, zerolog.LevelFieldName)
+	delete(event, zerolog.MessageFieldName)
+	delete(event, zerolog.TimestampFieldName)
+
+	keys := make([]string, 0, len(event))
+	for k := range event {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, field(k, event[k]))
+	}
+	w.write(ent, fields)
+	return len(p), nil
+}
+
+// field keeps zerolog's numbers numeric
+func field(key string, v interface{}) zapcore.Field {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return zap.Int64(key, i)
+		}
+		if f, err := n.Float64(); err == nil {
+			return zap.Float64(key, f)
+		}
+	}
+	return zap.Any(key, v)
+}
+
+// write checks on the core, not a zap.Logger, which leaves exiting to zerolog
+func (w *Writer) write(ent zapcore.Entry, fields []zapcore.Field) {
+	if ce := w.core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+func zapLevel(l zerolog.Level) zapcore.Level {
+	switch l {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return zapcore.DebugLevel
+	case zerolog.WarnLevel:
+		return zapcore.WarnLevel
+	case zerolog.ErrorLevel:
+		return zapcore.ErrorLevel
+	case zerolog.FatalLevel:
+		return zapcore.FatalLevel
+	case zerolog.PanicLevel:
+		return zapcore.PanicLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// New returns a zerolog logger writing to l, for handing to libraries
+// that accept one
+func New(l logger.Logger) zerolog.Logger {
+	return zerolog.New(NewWriter(l)).With().Timestamp().Logger()
+}
+
+// RedirectGlobal replaces zerolog's global log.Logger, which most libraries
+// log to, with one writing to l
+func RedirectGlobal(l logger.Logger) {
+	zlog.Logger = New(l)
+}
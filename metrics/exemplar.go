@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExemplarTraceIDLabel is the exemplar label holding the trace ID, the name
+// Grafana looks for by default when linking an exemplar to its trace
+const ExemplarTraceIDLabel = "trace_id"
+
+// ObserveWithExemplar records v in o and, when ctx carries a sampled span,
+// attaches its trace ID as an exemplar so a latency spike in Grafana links
+// straight to an example trace. Unsampled traces get no exemplar, as there
+// would be no trace to jump to.
+//
+//	metrics.ObserveWithExemplar(ctx, latency.WithLabelValues(route), time.Since(start).Seconds())
+func ObserveWithExemplar(ctx context.Context, o prometheus.Observer, v float64) {
+	sc := trace.SpanContextFromContext(ctx)
+	eo, ok := o.(prometheus.ExemplarObserver)
+	if !ok || !sc.IsSampled() {
+		o.Observe(v)
+		return
+	}
+	eo.ObserveWithExemplar(v, prometheus.Labels{ExemplarTraceIDLabel: sc.TraceID().String()})
+}
@@ -0,0 +1,41 @@
+// Package metrics holds the shared Prometheus registry that the other
+// packages register their collectors with, and the handler that exposes it.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric registered through this package
+const Namespace = "app"
+
+var registry = newRegistry()
+
+func newRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// Registry returns the shared registry
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// MustRegister registers collectors with the shared registry, panicking on conflicts
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+// Handler serves the shared registry in the Prometheus exposition format,
+// or OpenMetrics with exemplars when the scraper asks for it
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry, EnableOpenMetrics: true})
+}
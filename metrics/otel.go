@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// OTLPConfig configures pushing metrics to an OpenTelemetry Collector
+type OTLPConfig struct {
+	ServiceName string
+	Endpoint    string        // Collector gRPC endpoint (e.g., "localhost:4317")
+	Insecure    bool          // Disable TLS to the collector
+	Interval    time.Duration // Export interval (default 30s)
+}
+
+// InitOTLP exports the shared registry, plus any instruments created through
+// the global OpenTelemetry meter provider, to an OTLP collector. Prometheus
+// scraping via Handler keeps working alongside it. Call Shutdown on the
+// returned provider to flush the final export.
+func InitOTLP(ctx context.Context, cfg OTLPConfig) (*sdkmetric.MeterProvider, error) {
+	opts := []otlpmetricgrpc.Option{}
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(interval),
+		sdkmetric.WithProducer(newRegistryProducer(registry)),
+	)
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+	)
+	otel.SetMeterProvider(mp)
+	return mp, nil
+}
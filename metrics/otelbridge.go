@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// registryProducer converts what a Prometheus gatherer collects into
+// OpenTelemetry metric data on every export, so the collectors registered
+// with the shared registry reach the collector without being rewritten.
+// Counters become cumulative monotonic sums, gauges and untyped metrics
+// gauges, histograms explicit-bucket histograms and summaries summaries.
+type registryProducer struct {
+	gatherer prometheus.Gatherer
+	start    time.Time // Start time of every cumulative series
+}
+
+func newRegistryProducer(g prometheus.Gatherer) *registryProducer {
+	return &registryProducer{gatherer: g, start: time.Now()}
+}
+
+// Produce gathers the registry; metrics gathered before an error are kept
+func (p *registryProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.gatherer.Gather()
+	if len(families) == 0 {
+		return nil, err
+	}
+	now := time.Now()
+	sm := metricdata.ScopeMetrics{Scope: instrumentation.Scope{Name: "github.com/upendravikram5/upendra/metrics"}}
+	for _, mf := range families {
+		if m, ok := p.convert(mf, now); ok {
+			sm.Metrics = append(sm.Metrics, m)
+		}
+	}
+	return []metricdata.ScopeMetrics{sm}, err
+}
+
+func (p *registryProducer) convert(mf *dto.MetricFamily, now time.Time) (metricdata.Metrics, bool) {
+	m := metricdata.Metrics{Name: mf.GetName(), Description: mf.GetHelp()}
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+		for _, pm := range mf.GetMetric() {
+			sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: labelSet(pm), StartTime: p.start, Time: now, Value: pm.GetCounter().GetValue(),
+			})
+		}
+		m.Data = sum
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		var gauge metricdata.Gauge[float64]
+		for _, pm := range mf.GetMetric() {
+			v := pm.GetGauge().GetValue()
+			if mf.GetType() == dto.MetricType_UNTYPED {
+				v = pm.GetUntyped().GetValue()
+			}
+			gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: labelSet(pm), Time: now, Value: v,
+			})
+		}
+		m.Data = gauge
+	case dto.MetricType_HISTOGRAM:
+		hist := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, pm := range mf.GetMetric() {
+			h := pm.GetHistogram()
+			dp := metricdata.HistogramDataPoint[float64]{
+				Attributes: labelSet(pm), StartTime: p.start, Time: now,
+				Count: h.GetSampleCount(), Sum: h.GetSampleSum(),
+			}
+			var below uint64 // Prometheus buckets are cumulative, OpenTelemetry ones are not
+			for _, b := range h.GetBucket() {
+				dp.Bounds = append(dp.Bounds, b.GetUpperBound())
+				dp.BucketCounts = append(dp.BucketCounts, b.GetCumulativeCount()-below)
+				below = b.GetCumulativeCount()
+			}
+			dp.BucketCounts = append(dp.BucketCounts, h.GetSampleCount()-below) // +Inf
+			hist.DataPoints = append(hist.DataPoints, dp)
+		}
+		m.Data = hist
+	case dto.MetricType_SUMMARY:
+		var summary metricdata.Summary
+		for _, pm := range mf.GetMetric() {
+			s := pm.GetSummary()
+			dp := metricdata.SummaryDataPoint{
+				Attributes: labelSet(pm), StartTime: p.start, Time: now,
+				Count: s.GetSampleCount(), Sum: s.GetSampleSum(),
+			}
+			for _, q := range s.GetQuantile() {
+				dp.QuantileValues = append(dp.QuantileValues, metricdata.QuantileValue{Quantile: q.GetQuantile(), Value: q.GetValue()})
+			}
+			summary.DataPoints = append(summary.DataPoints, dp)
+		}
+		m.Data = summary
+	default:
+		return m, false // Native-only and gauge histograms have no plain equivalent
+	}
+	return m, true
+}
+
+func labelSet(pm *dto.Metric) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(pm.GetLabel()))
+	for _, l := range pm.GetLabel() {
+		kvs = append(kvs, attribute.String(l.GetName(), l.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}
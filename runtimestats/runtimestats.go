@@ -0,0 +1,100 @@
+// Package runtimestats periodically logs goroutine, heap, GC pause and file
+// descriptor figures so leaks show up in the logs before they become outages.
+// The same figures are exported as metrics by the Go and process collectors
+// of the shared metrics registry.
+package runtimestats
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/logger/fields"
+)
+
+// Reporter logs runtime stats on an interval; it implements lifecycle.Component
+type Reporter struct {
+	log      logger.Logger
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewReporter creates a reporter logging through log every interval (default 1m)
+func NewReporter(log logger.Logger, interval time.Duration) *Reporter {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Reporter{log: log, interval: interval}
+}
+
+// Start begins reporting in the background
+func (r *Reporter) Start(ctx context.Context) error {
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.done = make(chan struct{})
+	go r.run(ctx)
+	return nil
+}
+
+// Stop ends reporting
+func (r *Reporter) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Reporter) run(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Report()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Report logs one snapshot of the runtime stats
+func (r *Reporter) Report() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	// PauseQuantiles[i] is the i-th percentile of recent GC pauses
+	gc := debug.GCStats{PauseQuantiles: make([]time.Duration, 101)}
+	debug.ReadGCStats(&gc)
+
+	r.log.Infow("runtime stats",
+		"goroutines", runtime.NumGoroutine(),
+		fields.Bytes("heap_alloc", mem.HeapAlloc),
+		fields.Bytes("heap_inuse", mem.HeapInuse),
+		"heap_objects", mem.HeapObjects,
+		fields.Bytes("sys", mem.Sys),
+		"gc_count", gc.NumGC,
+		fields.DurationMS("gc_pause_p50", gc.PauseQuantiles[50]),
+		fields.DurationMS("gc_pause_p95", gc.PauseQuantiles[95]),
+		fields.DurationMS("gc_pause_p99", gc.PauseQuantiles[99]),
+		"open_fds", openFDs(),
+	)
+}
+
+// openFDs counts the process file descriptors, or returns -1 where /proc is unavailable
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,287 @@
+// Package server builds the public HTTP server with production timeouts,
+// request IDs, an access log, panic recovery and JSON error responses, and
+// runs it as a lifecycle component. http.ListenAndServe sets no timeouts at
+// all, so one slow or idle client can hold a connection and its goroutine
+// forever.
+//
+//	srv := server.New(server.Config{Addr: cfg.HTTPAddress}, mux, log)
+//	app.Add("http", srv)
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/upendravikram5/upendra/ids"
+	"github.com/upendravikram5/upendra/logger"
+	"github.com/upendravikram5/upendra/logger/events"
+)
+
+// Config holds the HTTP server configuration; zero values take the defaults
+type Config struct {
+	Addr              string        // Listen address (default ":8080")
+	ReadHeaderTimeout time.Duration // Time to read the request headers (default 5s)
+	ReadTimeout       time.Duration // Time to read the whole request, body included (default 30s)
+	WriteTimeout      time.Duration // Time from the end of the headers to the end of the response (default 30s)
+	IdleTimeout       time.Duration // Keep-alive time between requests (default 2m)
+	MaxHeaderBytes    int           // Request header size limit (default 1 MiB)
+
+	// CanonicalLog logs each request as one wide event, see CanonicalLog
+	CanonicalLog bool
+}
+
+func (c *Config) setDefaults() {
+	if c.Addr == "" {
+		c.Addr = ":8080"
+	}
+	if c.ReadHeaderTimeout <= 0 {
+		c.ReadHeaderTimeout = 5 * time.Second
+	}
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = 30 * time.Second
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 30 * time.Second
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 2 * time.Minute
+	}
+	if c.MaxHeaderBytes <= 0 {
+		c.MaxHeaderBytes = 1 << 20
+	}
+}
+
+// Server is the HTTP server; it implements lifecycle.Component
+type Server struct {
+	server *http.Server
+	log    logger.FieldLogger
+}
+
+// New creates a server for h, wrapped with AccessLog, or CanonicalLog, and
+// Recover. The access log sees the route matched by h when h is the
+// ServeMux itself; a router or middleware in between reports it with SetRoute.
+func New(cfg Config, h http.Handler, log logger.FieldLogger) *Server {
+	cfg.setDefaults()
+	access := AccessLog(log)
+	if cfg.CanonicalLog {
+		access = CanonicalLog(log)
+	}
+	return &Server{
+		log: log,
+		server: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           access(Recover(log)(h)),
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		},
+	}
+}
+
+// Start listens on the configured address and serves in the background;
+// a port that is taken fails Start rather than the background goroutine
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		s.log.Infow("Starting HTTP server", "addr", ln.Addr().String())
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Errorw("HTTP server error", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops accepting connections and waits for in-flight requests until
+// ctx is done, then closes the remaining connections
+func (s *Server) Stop(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		_ = s.server.Close()
+	}
+	return err
+}
+
+// Error is the JSON body of every error response
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"` // Stable machine-readable code, e.g. "not_found"
+	Message string `json:"message"`
+	TraceID string `json:"trace_id,omitempty"` // For support requests, when the request is traced
+}
+
+// WriteError writes a JSON error response, e.g.
+// server.WriteError(w, r, http.StatusNotFound, "not_found", "order not found")
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	body := Error{Status: status, Code: code, Message: message}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+		body.TraceID = sc.TraceID().String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Recover turns a panicking handler into a logged error with its stack and
+// a 500 response, instead of net/http's bare connection reset. Panics with
+// http.ErrAbortHandler are passed on, as they ask for exactly that.
+func Recover(log logger.FieldLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				l := log
+				if zl, ok := log.(logger.Logger); ok {
+					l = zl.Ctx(r.Context())
+				}
+				l.Errorw("HTTP handler panicked",
+					"panic", fmt.Sprint(p),
+					"stack", string(debug.Stack()),
+					"http.method", r.Method,
+					"http.path", r.URL.Path,
+					"request_id", RequestID(r.Context()),
+				)
+				if !rw.wroteHeader {
+					WriteError(rw, r, http.StatusInternalServerError, "internal", "internal server error")
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// RequestIDHeader is the header carrying the request ID in both directions
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the request handled under ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// AccessLog logs one http.request event per request, carrying its request
+// ID and the fields handlers added with logger.AddRequestFields. The request
+// ID is the caller's RequestIDHeader or a new one, sent back in the response
+// and available to handlers with RequestID. The route is the pattern
+// passed to SetRoute, or else the one a ServeMux matched for the request
+// AccessLog handed on; middleware between them that copies the request, e.g.
+// with r.WithContext, hides the latter, so the route would be "unmatched".
+func AccessLog(log logger.FieldLogger) func(http.Handler) http.Handler {
+	return accessLog(log, logger.WithRequestFields)
+}
+
+// CanonicalLog is AccessLog in canonical log line mode: the info and debug
+// entries of loggers from Ctx(r.Context()) are folded into the http.request
+// event, leaving one line per request plus any warnings and errors (see
+// logger.WithCanonicalLine)
+func CanonicalLog(log logger.FieldLogger) func(http.Handler) http.Handler {
+	return accessLog(log, logger.WithCanonicalLine)
+}
+
+func accessLog(log logger.FieldLogger, collect func(context.Context) context.Context) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w}
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = ids.NewID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			parent := context.WithValue(r.Context(), requestIDKey{}, id)
+			route := new(string)
+			ctx := context.WithValue(collect(parent), routeKey{}, route)
+			r = r.WithContext(ctx)
+			next.ServeHTTP(rw, r)
+
+			if *route == "" {
+				*route = r.Pattern // Set by ServeMux after routing
+			}
+			if *route == "" {
+				*route = "unmatched" // Never the raw path, which would add series per URL
+			}
+			l := log
+			if zl, ok := log.(logger.Logger); ok {
+				l = zl.Ctx(parent) // Not folded into itself in canonical mode
+			}
+			l = logger.AddFields(l, append([]interface{}{"request_id", id}, logger.RequestFields(ctx)...)...)
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			events.Log(l, events.HTTPRequest{
+				Method:        r.Method,
+				Route:         *route,
+				Status:        status,
+				Duration:      time.Since(start),
+				RequestBytes:  max(r.ContentLength, 0),
+				ResponseBytes: rw.bytes,
+				ClientIP:      host,
+			})
+		})
+	}
+}
+
+type routeKey struct{}
+
+// SetRoute reports the route pattern that matched r, e.g. "GET /orders/{id}",
+// to the enclosing AccessLog or CanonicalLog. Routers other than ServeMux, and
+// handlers behind middleware that copies the request, call it so the
+// http.request event isn't logged as "unmatched".
+func SetRoute(r *http.Request, pattern string) {
+	if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+		*route = pattern
+	}
+}
+
+// responseWriter records the status and size of the response
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	status      int
+	bytes       int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader, w.status = true, status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.wroteHeader, w.status = true, http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
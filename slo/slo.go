@@ -0,0 +1,121 @@
+// Package slo emits SLI events in the fixed schema the SLO pipeline consumes.
+// Each handled request or message produces one "sli" entry:
+//
+//	slo.service, slo.kind (http or kafka), slo.operation,
+//	slo.available, slo.latency_ms, slo.latency_bucket, slo.latency_good
+//
+// Availability and latency are judged here so every team reports them the
+// same way; the pipeline only counts good and total events.
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/upendravikram5/upendra/kafka"
+	"github.com/upendravikram5/upendra/logger"
+)
+
+// Event kinds
+const (
+	KindHTTP  = "http"
+	KindKafka = "kafka"
+)
+
+// DefaultBuckets are the latency bucket bounds used when Config.Buckets is empty
+var DefaultBuckets = []time.Duration{
+	10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond,
+	250 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+// Config configures a Recorder
+type Config struct {
+	Service          string
+	LatencyThreshold time.Duration   // Events at or under it count as fast (default 500ms)
+	Buckets          []time.Duration // Ascending bucket bounds (default DefaultBuckets)
+}
+
+// Recorder emits SLI events
+type Recorder struct {
+	log logger.FieldLogger
+	cfg Config
+}
+
+// NewRecorder creates a recorder logging through log
+func NewRecorder(log logger.FieldLogger, cfg Config) *Recorder {
+	if cfg.LatencyThreshold <= 0 {
+		cfg.LatencyThreshold = 500 * time.Millisecond
+	}
+	if len(cfg.Buckets) == 0 {
+		cfg.Buckets = DefaultBuckets
+	}
+	return &Recorder{log: log, cfg: cfg}
+}
+
+// Record emits one SLI event
+func (r *Recorder) Record(kind, operation string, latency time.Duration, available bool) {
+	r.log.Infow("sli",
+		"slo.service", r.cfg.Service,
+		"slo.kind", kind,
+		"slo.operation", operation,
+		"slo.available", available,
+		"slo.latency_ms", latency.Milliseconds(),
+		"slo.latency_bucket", r.bucket(latency),
+		"slo.latency_good", latency <= r.cfg.LatencyThreshold,
+	)
+}
+
+// bucket names the smallest bucket holding d, e.g. "le_250ms", or "inf"
+func (r *Recorder) bucket(d time.Duration) string {
+	for _, b := range r.cfg.Buckets {
+		if d <= b {
+			return fmt.Sprintf("le_%dms", b.Milliseconds())
+		}
+	}
+	return "inf"
+}
+
+// HTTP wraps next, recording one event per request under operation
+// "METHOD pattern". Responses with 5xx status count as unavailable.
+func (r *Recorder) HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+
+		operation := req.Pattern // Set by ServeMux after routing
+		if operation == "" {
+			operation = req.Method + " " + req.URL.Path
+		}
+		r.Record(KindHTTP, operation, time.Since(start), sw.status < 500)
+	})
+}
+
+// Kafka wraps next, recording one event per message under the topic name.
+// Handler errors count as unavailable, except cancellation at shutdown.
+func (r *Recorder) Kafka(next kafka.Handler) kafka.Handler {
+	return func(ctx context.Context, msg *kafka.Message) error {
+		start := time.Now()
+		err := next(ctx, msg)
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		r.Record(KindKafka, msg.Topic, time.Since(start), err == nil)
+		return err
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// Well-known baggage keys propagated between services
+const (
+	BaggageTenant       = "tenant"
+	BaggageFeatureFlags = "feature_flags"
+)
+
+// SetBaggage returns a copy of ctx whose baggage carries key=value
+func SetBaggage(ctx context.Context, key, value string) (context.Context, error) {
+	m, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		return ctx, fmt.Errorf("invalid baggage member %q: %w", key, err)
+	}
+	b, err := baggage.FromContext(ctx).SetMember(m)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to set baggage %q: %w", key, err)
+	}
+	return baggage.ContextWithBaggage(ctx, b), nil
+}
+
+// GetBaggage returns the baggage value for key, or "" when absent
+func GetBaggage(ctx context.Context, key string) string {
+	return baggage.FromContext(ctx).Member(key).Value()
+}
+
+// Tenant returns the tenant carried in the baggage
+func Tenant(ctx context.Context) string {
+	return GetBaggage(ctx, BaggageTenant)
+}
+
+// WithTenant returns a copy of ctx carrying tenant in its baggage
+func WithTenant(ctx context.Context, tenant string) (context.Context, error) {
+	return SetBaggage(ctx, BaggageTenant, tenant)
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// defaultPropagators matches the OpenTelemetry SDK default
+var defaultPropagators = []string{"tracecontext", "baggage"}
+
+// NewPropagator builds a composite propagator from names, using the same
+// names as the OTEL_PROPAGATORS variable:
+//
+//	tracecontext  W3C traceparent/tracestate
+//	baggage       W3C baggage
+//	b3            B3 single header
+//	b3multi       B3 multiple X-B3-* headers
+//	jaeger        uber-trace-id
+//	none          no propagation
+//
+// Injection writes every configured format; extraction accepts any of them,
+// which lets services migrate off B3 without breaking older peers.
+func NewPropagator(names []string) (propagation.TextMapPropagator, error) {
+	if len(names) == 0 {
+		if env := os.Getenv("OTEL_PROPAGATORS"); env != "" {
+			names = strings.Split(env, ",")
+		} else {
+			names = defaultPropagators
+		}
+	}
+
+	var props []propagation.TextMapPropagator
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "tracecontext":
+			props = append(props, propagation.TraceContext{})
+		case "baggage":
+			props = append(props, propagation.Baggage{})
+		case "b3":
+			props = append(props, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			props = append(props, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			props = append(props, jaeger.Jaeger{})
+		case "none":
+			return propagation.NewCompositeTextMapPropagator(), nil
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown propagator %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(props...), nil
+}
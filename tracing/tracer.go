@@ -0,0 +1,67 @@
+// Package tracing initializes OpenTelemetry tracing for the services
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/upendravikram5/upendra/buildinfo"
+)
+
+// Tracer is the service tracer, set by InitTracer
+var Tracer trace.Tracer = otel.Tracer("")
+
+// Config holds the tracing configuration
+type Config struct {
+	ServiceName string
+	Endpoint    string   `env:"OTEL_EXPORTER_OTLP_ENDPOINT" flag:"tracing.endpoint"` // Collector gRPC endpoint (default "localhost:4317")
+	Insecure    bool     `env:"OTEL_EXPORTER_OTLP_INSECURE" flag:"tracing.insecure"` // Disable TLS to the collector
+	Propagators []string // See NewPropagator; empty falls back to OTEL_PROPAGATORS, then tracecontext,baggage
+}
+
+// InitTracer initializes OpenTelemetry tracing and installs the global
+// tracer provider and propagators. Call Shutdown on the returned provider
+// to flush pending spans.
+func InitTracer(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	propagator, err := NewPropagator(cfg.Propagators)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "localhost:4317"
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	build := buildinfo.Get()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", cfg.ServiceName),
+			attribute.String("service.version", build.Version),
+			attribute.String("vcs.revision", build.Commit),
+		)),
+	)
+
+	// Set global tracer & propagator
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+
+	Tracer = tp.Tracer(cfg.ServiceName)
+	return tp, nil
+}